@@ -328,7 +328,7 @@ export default function ContestsPage() {
             <Button variant="outline" onClick={startContest} disabled={busy || !contest || Boolean(startedAt) || finished}>
               Start
             </Button>
-            <Button onClick={finishContest} disabled={busy || !contest}>
+            <Button onClick={finishContest} disabled={busy || !contest || !startedAt || finished}>
               Finish contest
             </Button>
           </div>
@@ -477,7 +477,7 @@ export default function ContestsPage() {
                           <Button
                             size="sm"
                             variant="primary"
-                            disabled={busy || busyID === it.problem.id}
+                            disabled={busy || busyID === it.problem.id || !startedAt || finished}
                             onClick={() => confirmOne(it.problem.id)}
                             title="Confirm this problem's result (updates schedule)"
                           >
@@ -17,10 +17,14 @@ import (
 	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/contests"
 	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/db"
 	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/docs"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/interviews"
 	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/lists"
 	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/notes"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/plans"
 	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/problems"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/recommendations"
 	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/reviews"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/solutions"
 	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/stats"
 	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/users"
 )
@@ -38,6 +42,8 @@ func main() {
 	refreshHours := config.Int("REFRESH_TTL_HOURS", 24*30)
 	icsBaseURL := config.String("ICS_BASE_URL", "")
 	openAPISpecPath := config.String("OPENAPI_SPEC_PATH", "")
+	contestExpireHours := config.Int("CONTEST_EXPIRE_AFTER_HOURS", 24)
+	notesKeepRevisions := config.Int("NOTES_REVISION_RETENTION", 50)
 
 	pool, err := db.Open(ctx, dbURL)
 	if err != nil {
@@ -55,19 +61,26 @@ func main() {
 	reviewsHandler := reviews.NewHandler(pool, userRepo, problemsRepo)
 	usersHandler := users.NewHandler(userRepo)
 
-	notesRepo := notes.NewRepository(pool)
+	notesRepo := notes.NewRepository(pool, notesKeepRevisions)
 	notesHandler := notes.NewHandler(notesRepo)
+	solutionsHandler := solutions.NewHandler(solutions.NewRepository(pool), userRepo)
 
 	listsRepo := lists.NewRepository(pool)
 	listsHandler := lists.NewHandler(pool, listsRepo, problemsRepo, userRepo)
 
+	plansRepo := plans.NewRepository(pool)
+	plansHandler := plans.NewHandler(pool, plansRepo, listsRepo, problemsRepo, userRepo)
+	interviewsHandler := interviews.NewHandler(pool, interviews.NewRepository(pool), problemsRepo, userRepo)
+
 	contestsRepo := contests.NewRepository(pool)
-	contestsHandler := contests.NewHandler(pool, contestsRepo, problemsRepo, userRepo)
+	contestsHandler := contests.NewHandler(pool, contestsRepo, problemsRepo, listsRepo, userRepo)
+	go contests.RunExpiry(ctx, contestsRepo, time.Duration(contestExpireHours)*time.Hour, 10*time.Minute)
 
 	statsHandler := stats.NewHandler(pool, userRepo)
+	recommendationsHandler := recommendations.NewHandler(pool)
 
 	tokenRepo := calendar.NewTokenRepo(pool)
-	calendarHandler := calendar.NewHandler(tokenRepo, userRepo, icsBaseURL)
+	calendarHandler := calendar.NewHandler(tokenRepo, userRepo, listsRepo, icsBaseURL)
 	docsHandler := docs.NewHandler(openAPISpecPath)
 
 	r := chi.NewRouter()
@@ -111,9 +124,17 @@ func main() {
 				r.Patch("/{id}", problemsHandler.Patch)
 				r.Get("/{id}/notes", notesHandler.Get)
 				r.Put("/{id}/notes", notesHandler.Put)
+				r.Get("/{id}/notes/revisions", notesHandler.Revisions)
+				r.Get("/{id}/notes/revisions/diff", notesHandler.Diff)
+				r.Post("/{id}/notes/restore/{rev}", notesHandler.Restore)
+				r.Get("/{id}/solutions", solutionsHandler.List)
+				r.Post("/{id}/solutions", solutionsHandler.Create)
+				r.Patch("/{id}/solutions/{solutionID}", solutionsHandler.Update)
+				r.Delete("/{id}/solutions/{solutionID}", solutionsHandler.Delete)
 			})
 			r.Route("/reviews", func(r chi.Router) {
 				r.Get("/due", reviewsHandler.Due)
+				r.Get("/history.csv", statsHandler.ReviewHistoryCSV)
 				r.Post("/", reviewsHandler.Post)
 			})
 			r.Route("/lists", func(r chi.Router) {
@@ -121,22 +142,60 @@ func main() {
 				r.Get("/", listsHandler.List)
 				r.Post("/import", listsHandler.Import)
 				r.Get("/{id}", listsHandler.Get)
+				r.Patch("/{id}", listsHandler.Patch)
 				r.Post("/{id}/items", listsHandler.AddItem)
 				r.Patch("/{id}/items/reorder", listsHandler.Reorder)
 			})
+			r.Route("/interviews", func(r chi.Router) {
+				r.Post("/", interviewsHandler.Create)
+				r.Get("/", interviewsHandler.List)
+				r.Get("/rubrics", interviewsHandler.RubricHistory)
+				r.Get("/{id}", interviewsHandler.Get)
+				r.Post("/{id}/start", interviewsHandler.Start)
+				r.Post("/{id}/cancel", interviewsHandler.Cancel)
+				r.Post("/{id}/rubric", interviewsHandler.SubmitRubric)
+			})
+			r.Route("/plans", func(r chi.Router) {
+				r.Post("/", plansHandler.Create)
+				r.Get("/", plansHandler.List)
+				r.Get("/{id}", plansHandler.Get)
+				r.Post("/{id}/rebalance", plansHandler.Rebalance)
+				r.Delete("/{id}", plansHandler.Delete)
+			})
 			r.Route("/contests", func(r chi.Router) {
+				r.Get("/", contestsHandler.List)
 				r.Post("/generate", contestsHandler.Generate)
+				r.Post("/join", contestsHandler.Join)
+				r.Get("/presets", contestsHandler.ListPresets)
+				r.Post("/presets", contestsHandler.CreatePreset)
+				r.Delete("/presets/{id}", contestsHandler.DeletePreset)
 				r.Get("/{id}", contestsHandler.Get)
+				r.Delete("/{id}", contestsHandler.Delete)
 				r.Post("/{id}/start", contestsHandler.Start)
 				r.Post("/{id}/complete", contestsHandler.Complete)
 				r.Post("/{id}/results", contestsHandler.SubmitResults)
+				r.Post("/{id}/events", contestsHandler.PostEvent)
+				r.Get("/{id}/timeline", contestsHandler.Timeline)
+				r.Post("/{id}/invite", contestsHandler.Invite)
+				r.Get("/{id}/leaderboard", contestsHandler.Leaderboard)
 			})
 			r.Route("/stats", func(r chi.Router) {
 				r.Get("/overview", statsHandler.Overview)
 				r.Get("/topics", statsHandler.Topics)
+				r.Get("/mastery", statsHandler.Mastery)
 				r.Get("/streaks", statsHandler.Streaks)
+				r.Post("/streaks/freeze", statsHandler.Freeze)
+				r.Get("/goals", statsHandler.Goals)
+				r.Get("/retention", statsHandler.Retention)
+				r.Get("/grades", statsHandler.Grades)
+				r.Get("/time-spent", statsHandler.TimeSpent)
+				r.Get("/heatmap", statsHandler.Heatmap)
+				r.Get("/daily.csv", statsHandler.DailyCSV)
+				r.Get("/report", statsHandler.Report)
 				r.Get("/contests", statsHandler.Contests)
 			})
+			r.Get("/notes/search", notesHandler.Search)
+			r.Get("/recommendations", recommendationsHandler.Get)
 		})
 	})
 
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/activity"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/db"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/notes"
+)
+
+// backfill rebuilds the daily_user_activity rollup (and the recent grades
+// mastery reads) from review and contest history, and re-renders notes markdown
+// from the editor JSON, for one user or everyone.
+func main() {
+	var dbURL string
+	var userID string
+
+	flag.StringVar(&dbURL, "database", "", "DATABASE_URL")
+	flag.StringVar(&userID, "user", "", "only rebuild this user id (default: all users)")
+	flag.Parse()
+
+	if dbURL == "" {
+		log.Fatal("missing -database")
+	}
+
+	ctx := context.Background()
+	pool, err := db.Open(ctx, dbURL)
+	if err != nil {
+		log.Fatalf("db open: %v", err)
+	}
+	defer pool.Close()
+
+	userIDs := []string{userID}
+	if userID == "" {
+		rows, err := pool.Query(ctx, `SELECT id::text FROM users ORDER BY created_at`)
+		if err != nil {
+			log.Fatal(err)
+		}
+		userIDs = userIDs[:0]
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				log.Fatal(err)
+			}
+			userIDs = append(userIDs, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	repo := activity.NewRepository(pool)
+	notesRepo := notes.NewRepository(pool, 1)
+	var rendered, skipped int
+	for _, id := range userIDs {
+		if err := repo.Rebuild(ctx, id); err != nil {
+			log.Fatalf("rebuild user_id=%s: %v", id, err)
+		}
+		u, s, err := notesRepo.RenderMarkdown(ctx, id)
+		if err != nil {
+			log.Fatalf("render notes user_id=%s: %v", id, err)
+		}
+		rendered += u
+		skipped += s
+	}
+	fmt.Printf("daily activity rebuilt: %d user(s)\n", len(userIDs))
+	fmt.Printf("notes markdown re-rendered: %d note(s), %d skipped (invalid content_json)\n", rendered, skipped)
+}
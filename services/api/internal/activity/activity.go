@@ -0,0 +1,326 @@
+// Package activity maintains daily_user_activity, the per-user, per-local-day
+// rollup of reviews and contests that stats, streaks and daily goals read
+// instead of review_logs and contest_results.
+package activity
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const dateLayout = "2006-01-02"
+
+var (
+	ErrDayNotFreezable = errors.New("only a missed day in the last week can be frozen")
+	ErrNoFreezes       = errors.New("no streak freezes available")
+)
+
+// Day is one local date of activity. Date is a UTC midnight standing for the
+// user's calendar day.
+type Day struct {
+	Date         time.Time
+	Reviews      int
+	TimeSpentSec int
+	GoalMet      bool
+	Frozen       bool
+
+	ContestsFinished int
+	ContestProblems  int
+	ContestSolved    int
+	ContestGraded    int
+	ContestGradeSum  int
+	ContestTimeSec   int
+}
+
+// Goal is the user's daily target; a day meets it when both parts are reached.
+type Goal struct {
+	Reviews int `json:"daily_reviews"`
+	Minutes int `json:"daily_minutes"`
+}
+
+// Met reports whether the totals reach the goal.
+func (g Goal) Met(reviews int, timeSpentSec int) bool {
+	return reviews >= g.Reviews && timeSpentSec >= g.Minutes*60
+}
+
+// LocalDate is the calendar day of t in loc, as a UTC midnight.
+func LocalDate(t time.Time, loc *time.Location) time.Time {
+	l := t.In(loc)
+	return time.Date(l.Year(), l.Month(), l.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// LocationTx loads the user's time zone, falling back to UTC like the handlers do.
+func LocationTx(ctx context.Context, tx pgx.Tx, userID string) (*time.Location, error) {
+	var tz string
+	if err := tx.QueryRow(ctx, `SELECT timezone FROM user_settings WHERE user_id = $1`, userID).Scan(&tz); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return time.UTC, nil
+		}
+		return nil, err
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return time.UTC, nil
+	}
+	return loc, nil
+}
+
+// Review is one review_logs row as seen by the rollup.
+type Review struct {
+	ProblemID     string
+	Grade         int
+	ReviewedAtUTC time.Time
+	TimeSpentSec  *int
+}
+
+// RecordReviewTx adds one review to the user's local day, stamps the day as
+// goal-met once it reaches the user's current goal and records the grade for
+// mastery. Every writer of review_logs calls it in the same transaction as the
+// insert.
+func RecordReviewTx(ctx context.Context, tx pgx.Tx, userID string, loc *time.Location, rv Review) error {
+	if err := recordGradeTx(ctx, tx, userID, rv.ProblemID, rv.Grade); err != nil {
+		return err
+	}
+	day := LocalDate(rv.ReviewedAtUTC, loc)
+	spent := 0
+	if rv.TimeSpentSec != nil {
+		spent = *rv.TimeSpentSec
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO daily_user_activity (user_id, local_date, reviews, time_spent_sec)
+		VALUES ($1, $2, 1, $3)
+		ON CONFLICT (user_id, local_date) DO UPDATE
+		SET reviews = daily_user_activity.reviews + 1,
+		    time_spent_sec = daily_user_activity.time_spent_sec + EXCLUDED.time_spent_sec,
+		    updated_at = now()
+	`, userID, day, spent); err != nil {
+		return err
+	}
+	return markGoalTx(ctx, tx, userID, day)
+}
+
+func markGoalTx(ctx context.Context, tx pgx.Tx, userID string, day time.Time) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE daily_user_activity d
+		SET goal_met_at = now()
+		FROM user_settings us
+		WHERE d.user_id = $1 AND d.local_date = $2 AND us.user_id = d.user_id
+		  AND d.goal_met_at IS NULL
+		  AND d.reviews >= us.daily_goal_reviews
+		  AND d.time_spent_sec >= us.daily_goal_minutes * 60
+	`, userID, day)
+	return err
+}
+
+// recentGradesLen matches mastery.HistoryLen, the grades an estimate reads.
+const recentGradesLen = 10
+
+// recordGradeTx prepends a grade to the problem's recent grades (newest first,
+// in write order).
+func recordGradeTx(ctx context.Context, tx pgx.Tx, userID string, problemID string, grade int) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE user_problem_state
+		SET recent_grades = (ARRAY[$3::int] || recent_grades)[1:$4]
+		WHERE user_id = $1 AND problem_id = $2
+	`, userID, problemID, grade, recentGradesLen)
+	return err
+}
+
+// ContestResult is one contest_results row as seen by the rollup.
+type ContestResult struct {
+	RecordedAtUTC time.Time
+	Grade         *int
+	TimeSpentSec  *int
+	Solved        bool
+}
+
+// RecordContestResultTx moves a participant's result into the rollup. prev is
+// the result it replaces (nil for a first submission); it is subtracted from its
+// own day so resubmissions do not double count.
+func RecordContestResultTx(ctx context.Context, tx pgx.Tx, userID string, loc *time.Location, prev *ContestResult, next ContestResult) error {
+	if prev != nil {
+		if err := addContestResultTx(ctx, tx, userID, loc, *prev, -1); err != nil {
+			return err
+		}
+	}
+	return addContestResultTx(ctx, tx, userID, loc, next, 1)
+}
+
+func addContestResultTx(ctx context.Context, tx pgx.Tx, userID string, loc *time.Location, cr ContestResult, sign int) error {
+	graded, gradeSum, spent, solved := 0, 0, 0, 0
+	if cr.Grade != nil {
+		graded, gradeSum = sign, sign**cr.Grade
+	}
+	if cr.TimeSpentSec != nil {
+		spent = sign * *cr.TimeSpentSec
+	}
+	if cr.Solved {
+		solved = sign
+	}
+	_, err := tx.Exec(ctx, `
+		INSERT INTO daily_user_activity (user_id, local_date, contest_problems, contest_solved, contest_graded, contest_grade_sum, contest_time_sec)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (user_id, local_date) DO UPDATE
+		SET contest_problems = daily_user_activity.contest_problems + EXCLUDED.contest_problems,
+		    contest_solved = daily_user_activity.contest_solved + EXCLUDED.contest_solved,
+		    contest_graded = daily_user_activity.contest_graded + EXCLUDED.contest_graded,
+		    contest_grade_sum = daily_user_activity.contest_grade_sum + EXCLUDED.contest_grade_sum,
+		    contest_time_sec = daily_user_activity.contest_time_sec + EXCLUDED.contest_time_sec,
+		    updated_at = now()
+	`, userID, LocalDate(cr.RecordedAtUTC, loc), sign, solved, graded, gradeSum, spent)
+	return err
+}
+
+// RecordContestFinishedTx counts a completed contest on the participant's local day.
+func RecordContestFinishedTx(ctx context.Context, tx pgx.Tx, userID string, loc *time.Location, completedAtUTC time.Time) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO daily_user_activity (user_id, local_date, contests_finished)
+		VALUES ($1, $2, 1)
+		ON CONFLICT (user_id, local_date) DO UPDATE
+		SET contests_finished = daily_user_activity.contests_finished + 1,
+		    updated_at = now()
+	`, userID, LocalDate(completedAtUTC, loc))
+	return err
+}
+
+type Repository struct {
+	pool *pgxpool.Pool
+}
+
+func NewRepository(pool *pgxpool.Pool) *Repository { return &Repository{pool: pool} }
+
+const dayColumns = `local_date, reviews, time_spent_sec, goal_met_at IS NOT NULL, frozen_at IS NOT NULL,
+	contests_finished, contest_problems, contest_solved, contest_graded, contest_grade_sum, contest_time_sec`
+
+func scanDays(rows pgx.Rows) ([]Day, error) {
+	defer rows.Close()
+	out := make([]Day, 0)
+	for rows.Next() {
+		var d Day
+		if err := rows.Scan(&d.Date, &d.Reviews, &d.TimeSpentSec, &d.GoalMet, &d.Frozen,
+			&d.ContestsFinished, &d.ContestProblems, &d.ContestSolved, &d.ContestGraded, &d.ContestGradeSum, &d.ContestTimeSec); err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+// Range returns the recorded days in [from, to], oldest first. Days without
+// activity have no row.
+func (r *Repository) Range(ctx context.Context, userID string, from time.Time, to time.Time) ([]Day, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+dayColumns+`
+		FROM daily_user_activity
+		WHERE user_id = $1 AND local_date BETWEEN $2 AND $3
+		ORDER BY local_date
+	`, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return scanDays(rows)
+}
+
+// EachDay calls fn for every local date in [from, to], oldest first, including
+// days without activity, as rows arrive from the database. It stops at the
+// first error from fn.
+func (r *Repository) EachDay(ctx context.Context, userID string, from time.Time, to time.Time, fn func(Day) error) error {
+	rows, err := r.pool.Query(ctx, `
+		SELECT d::date, COALESCE(a.reviews, 0), COALESCE(a.time_spent_sec, 0),
+		       a.goal_met_at IS NOT NULL, a.frozen_at IS NOT NULL,
+		       COALESCE(a.contests_finished, 0), COALESCE(a.contest_problems, 0), COALESCE(a.contest_solved, 0),
+		       COALESCE(a.contest_graded, 0), COALESCE(a.contest_grade_sum, 0), COALESCE(a.contest_time_sec, 0)
+		FROM generate_series($2::date, $3::date, interval '1 day') AS d
+		LEFT JOIN daily_user_activity a ON a.user_id = $1 AND a.local_date = d::date
+		ORDER BY d
+	`, userID, from, to)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var d Day
+		if err := rows.Scan(&d.Date, &d.Reviews, &d.TimeSpentSec, &d.GoalMet, &d.Frozen,
+			&d.ContestsFinished, &d.ContestProblems, &d.ContestSolved, &d.ContestGraded, &d.ContestGradeSum, &d.ContestTimeSec); err != nil {
+			return err
+		}
+		if err := fn(d); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+const streakDaysQuery = `
+	SELECT ` + dayColumns + `
+	FROM daily_user_activity
+	WHERE user_id = $1 AND (goal_met_at IS NOT NULL OR frozen_at IS NOT NULL)
+	ORDER BY local_date
+`
+
+// Streaks computes the user's streaks from the days that met the goal or were frozen.
+func (r *Repository) Streaks(ctx context.Context, userID string, today time.Time) (Streaks, error) {
+	rows, err := r.pool.Query(ctx, streakDaysQuery, userID)
+	if err != nil {
+		return Streaks{}, err
+	}
+	days, err := scanDays(rows)
+	if err != nil {
+		return Streaks{}, err
+	}
+	return ComputeStreaks(days, today), nil
+}
+
+// Freeze spends a streak freeze on a missed day within the last FreezeWindowDays.
+// The settings row is locked so concurrent freezes cannot overspend the balance.
+func (r *Repository) Freeze(ctx context.Context, userID string, day time.Time, today time.Time) (Streaks, error) {
+	if !day.Before(today) || day.Before(today.AddDate(0, 0, -FreezeWindowDays)) {
+		return Streaks{}, ErrDayNotFreezable
+	}
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return Streaks{}, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM user_settings WHERE user_id = $1 FOR UPDATE`, userID); err != nil {
+		return Streaks{}, err
+	}
+	rows, err := tx.Query(ctx, streakDaysQuery, userID)
+	if err != nil {
+		return Streaks{}, err
+	}
+	days, err := scanDays(rows)
+	if err != nil {
+		return Streaks{}, err
+	}
+	for _, d := range days {
+		if d.Date.Equal(day) {
+			return Streaks{}, ErrDayNotFreezable
+		}
+	}
+	// The freeze must be affordable at that point in time, without starving a
+	// later freeze of its token.
+	before := ComputeStreaks(days, today)
+	days = append(days, Day{Date: day, Frozen: true})
+	after := ComputeStreaks(days, today)
+	if after.FreezesUsed != before.FreezesUsed+1 {
+		return Streaks{}, ErrNoFreezes
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO daily_user_activity (user_id, local_date, frozen_at)
+		VALUES ($1, $2, now())
+		ON CONFLICT (user_id, local_date) DO UPDATE
+		SET frozen_at = now(), updated_at = now()
+	`, userID, day); err != nil {
+		return Streaks{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return Streaks{}, err
+	}
+	return after, nil
+}
@@ -0,0 +1,95 @@
+package activity
+
+import (
+	"context"
+)
+
+// Rebuild recomputes a user's rollup from review_logs, contest_results and
+// contests in their current time zone, along with the recent grades mastery
+// reads. Freezes are kept, and so are goal verdicts already recorded; days that
+// now reach the current goal are stamped. It is what the backfill command runs,
+// and is safe to repeat (for example after a time zone change).
+func (r *Repository) Rebuild(ctx context.Context, userID string) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// Serialize with freezes, which read the same rows to check the balance.
+	// Reviews written while a rebuild runs are caught by the next rebuild.
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM user_settings WHERE user_id = $1 FOR UPDATE`, userID); err != nil {
+		return err
+	}
+	loc, err := LocationTx(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+	tz := loc.String()
+
+	steps := []struct {
+		sql  string
+		args []any
+	}{
+		{`DELETE FROM daily_user_activity
+		 WHERE user_id = $1 AND goal_met_at IS NULL AND frozen_at IS NULL`, []any{userID}},
+		{`UPDATE daily_user_activity
+		 SET reviews = 0, time_spent_sec = 0, contests_finished = 0, contest_problems = 0,
+		     contest_solved = 0, contest_graded = 0, contest_grade_sum = 0, contest_time_sec = 0,
+		     updated_at = now()
+		 WHERE user_id = $1`, []any{userID}},
+		{`INSERT INTO daily_user_activity (user_id, local_date, reviews, time_spent_sec)
+		 SELECT user_id, (reviewed_at AT TIME ZONE $2)::date AS d, COUNT(*), COALESCE(SUM(time_spent_sec), 0)
+		 FROM review_logs
+		 WHERE user_id = $1
+		 GROUP BY user_id, d
+		 ON CONFLICT (user_id, local_date) DO UPDATE
+		 SET reviews = EXCLUDED.reviews, time_spent_sec = EXCLUDED.time_spent_sec`, []any{userID, tz}},
+		{`INSERT INTO daily_user_activity (user_id, local_date, contest_problems, contest_solved, contest_graded, contest_grade_sum, contest_time_sec)
+		 SELECT user_id, (recorded_at AT TIME ZONE $2)::date AS d,
+		        COUNT(*), COUNT(*) FILTER (WHERE solved_flag IS TRUE), COUNT(grade),
+		        COALESCE(SUM(grade), 0), COALESCE(SUM(time_spent_sec), 0)
+		 FROM contest_results
+		 WHERE user_id = $1
+		 GROUP BY user_id, d
+		 ON CONFLICT (user_id, local_date) DO UPDATE
+		 SET contest_problems = EXCLUDED.contest_problems,
+		     contest_solved = EXCLUDED.contest_solved,
+		     contest_graded = EXCLUDED.contest_graded,
+		     contest_grade_sum = EXCLUDED.contest_grade_sum,
+		     contest_time_sec = EXCLUDED.contest_time_sec`, []any{userID, tz}},
+		{`INSERT INTO daily_user_activity (user_id, local_date, contests_finished)
+		 SELECT cp.user_id, (c.completed_at AT TIME ZONE $2)::date AS d, COUNT(*)
+		 FROM contests c
+		 JOIN contest_participants cp ON cp.contest_id = c.id
+		 WHERE cp.user_id = $1 AND c.completed_at IS NOT NULL
+		 GROUP BY cp.user_id, d
+		 ON CONFLICT (user_id, local_date) DO UPDATE
+		 SET contests_finished = EXCLUDED.contests_finished`, []any{userID, tz}},
+		{`UPDATE daily_user_activity d
+		 SET goal_met_at = now()
+		 FROM user_settings us
+		 WHERE d.user_id = $1 AND us.user_id = d.user_id AND d.goal_met_at IS NULL
+		   AND d.reviews >= us.daily_goal_reviews
+		   AND d.time_spent_sec >= us.daily_goal_minutes * 60
+		   AND d.reviews > 0`, []any{userID}},
+		{`UPDATE user_problem_state s
+		 SET recent_grades = COALESCE((
+		     SELECT array_agg(g.grade ORDER BY g.reviewed_at DESC, g.created_at DESC)
+		     FROM (
+		         SELECT grade, reviewed_at, created_at
+		         FROM review_logs rl
+		         WHERE rl.user_id = s.user_id AND rl.problem_id = s.problem_id
+		         ORDER BY reviewed_at DESC, created_at DESC
+		         LIMIT $2
+		     ) g
+		 ), '{}')
+		 WHERE s.user_id = $1`, []any{userID, recentGradesLen}},
+	}
+	for _, st := range steps {
+		if _, err := tx.Exec(ctx, st.sql, st.args...); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
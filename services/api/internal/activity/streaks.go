@@ -0,0 +1,103 @@
+package activity
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	// FreezeEvery is the run length (in goal days) that earns a streak freeze.
+	FreezeEvery = 7
+	// FreezeCap is the most freezes a user can hold at once.
+	FreezeCap = 2
+	// FreezeWindowDays is how far back a missed day can still be frozen.
+	FreezeWindowDays = 7
+	// streakHistoryLen bounds the runs returned in Streaks.History.
+	streakHistoryLen = 20
+)
+
+// Run is one unbroken streak. Days counts goal days only; frozen days inside
+// the run keep it alive without lengthening it.
+type Run struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+	Days  int    `json:"days"`
+}
+
+type Streaks struct {
+	CurrentDays      int   `json:"current_streak_days"`
+	LongestDays      int   `json:"longest_streak_days"`
+	History          []Run `json:"history"`
+	FreezesAvailable int   `json:"freezes_available"`
+	FreezesUsed      int   `json:"freezes_used"`
+}
+
+// ComputeStreaks derives streaks and the freeze balance from the days that met
+// the goal or were frozen, in any order. today is the user's local date.
+//
+// A run is a sequence of consecutive such days; runs made only of frozen days
+// are ignored. Every FreezeEvery goal days in a run earn a freeze (up to
+// FreezeCap held) and each frozen day spends one; a frozen day with no freeze
+// to spend breaks the run like a missed day. The current streak is the run
+// ending today or yesterday, so it is not broken before today is over.
+func ComputeStreaks(days []Day, today time.Time) Streaks {
+	sorted := make([]Day, 0, len(days))
+	for _, d := range days {
+		if d.GoalMet || d.Frozen {
+			sorted = append(sorted, d)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Date.Before(sorted[j].Date) })
+
+	var out Streaks
+	runs := make([]Run, 0)
+	var cur *Run
+	var prev time.Time
+	for _, d := range sorted {
+		if !d.GoalMet && out.FreezesAvailable == 0 {
+			if cur != nil && cur.Days > 0 {
+				runs = append(runs, *cur)
+			}
+			cur = nil
+			continue
+		}
+		if cur == nil || !d.Date.Equal(prev.AddDate(0, 0, 1)) {
+			if cur != nil && cur.Days > 0 {
+				runs = append(runs, *cur)
+			}
+			cur = &Run{Start: d.Date.Format(dateLayout)}
+		}
+		prev = d.Date
+		cur.End = d.Date.Format(dateLayout)
+		if d.GoalMet {
+			cur.Days++
+			if cur.Days%FreezeEvery == 0 && out.FreezesAvailable < FreezeCap {
+				out.FreezesAvailable++
+			}
+			continue
+		}
+		out.FreezesUsed++
+		out.FreezesAvailable--
+	}
+	if cur != nil && cur.Days > 0 {
+		runs = append(runs, *cur)
+	}
+
+	for _, r := range runs {
+		if r.Days > out.LongestDays {
+			out.LongestDays = r.Days
+		}
+	}
+	if n := len(runs); n > 0 {
+		last := runs[n-1]
+		yesterday := today.AddDate(0, 0, -1).Format(dateLayout)
+		if last.End >= yesterday {
+			out.CurrentDays = last.Days
+		}
+	}
+	out.History = make([]Run, 0, min(len(runs), streakHistoryLen))
+	for i := len(runs) - 1; i >= 0 && len(out.History) < streakHistoryLen; i-- {
+		out.History = append(out.History, runs[i])
+	}
+	return out
+}
@@ -0,0 +1,75 @@
+package activity
+
+import (
+	"testing"
+	"time"
+)
+
+func day(s string) time.Time {
+	t, _ := time.Parse(dateLayout, s)
+	return t
+}
+
+// goalRun returns n consecutive goal-met days starting at start.
+func goalRun(start string, n int) []Day {
+	out := make([]Day, 0, n)
+	for i := 0; i < n; i++ {
+		out = append(out, Day{Date: day(start).AddDate(0, 0, i), Reviews: 1, GoalMet: true})
+	}
+	return out
+}
+
+func TestComputeStreaksCurrentLongestAndHistory(t *testing.T) {
+	days := append(goalRun("2026-01-01", 5), goalRun("2026-01-10", 3)...)
+	days = append(days, Day{Date: day("2026-01-20"), Reviews: 1}) // below goal: ignored
+
+	st := ComputeStreaks(days, day("2026-01-13"))
+	if st.CurrentDays != 3 || st.LongestDays != 5 {
+		t.Fatalf("expected current 3 and longest 5, got %+v", st)
+	}
+	if len(st.History) != 2 || st.History[0].Start != "2026-01-10" || st.History[1].Days != 5 {
+		t.Fatalf("expected newest-first history, got %+v", st.History)
+	}
+	if st := ComputeStreaks(days, day("2026-01-14")); st.CurrentDays != 0 {
+		t.Fatalf("expected the streak to lapse after a missed day, got %d", st.CurrentDays)
+	}
+}
+
+func TestComputeStreaksFreezesBridgeMissedDays(t *testing.T) {
+	days := goalRun("2026-02-01", 7) // earns one freeze on day 7
+	days = append(days, Day{Date: day("2026-02-08"), Frozen: true})
+	days = append(days, goalRun("2026-02-09", 2)...)
+
+	st := ComputeStreaks(days, day("2026-02-10"))
+	if st.CurrentDays != 9 || st.FreezesUsed != 1 || st.FreezesAvailable != 0 {
+		t.Fatalf("expected a 9-day streak across the frozen day, got %+v", st)
+	}
+	if len(st.History) != 1 || st.History[0].Start != "2026-02-01" || st.History[0].End != "2026-02-10" {
+		t.Fatalf("expected one run spanning the freeze, got %+v", st.History)
+	}
+}
+
+func TestComputeStreaksUnaffordableFreezeBreaksRun(t *testing.T) {
+	days := goalRun("2026-03-01", 3)
+	days = append(days, Day{Date: day("2026-03-04"), Frozen: true})
+	days = append(days, goalRun("2026-03-05", 2)...)
+
+	st := ComputeStreaks(days, day("2026-03-06"))
+	if st.CurrentDays != 2 || st.LongestDays != 3 || st.FreezesUsed != 0 {
+		t.Fatalf("expected the freeze without a token to break the run, got %+v", st)
+	}
+}
+
+func TestComputeStreaksFreezeCap(t *testing.T) {
+	st := ComputeStreaks(goalRun("2026-01-01", 35), day("2026-02-04"))
+	if st.FreezesAvailable != FreezeCap {
+		t.Fatalf("expected freezes capped at %d, got %d", FreezeCap, st.FreezesAvailable)
+	}
+}
+
+func TestGoalMet(t *testing.T) {
+	g := Goal{Reviews: 3, Minutes: 30}
+	if g.Met(3, 1799) || !g.Met(3, 1800) || g.Met(2, 4000) {
+		t.Fatalf("expected both parts of the goal to be required")
+	}
+}
@@ -217,6 +217,12 @@ func (h *Handler) Me(w http.ResponseWriter, r *http.Request) {
 		"min_interval_days": settings.MinIntervalDays,
 		"due_hour_local":    settings.DueHourLocal,
 		"due_minute_local":  settings.DueMinuteLocal,
+
+		"contest_cooldown_contests": settings.ContestCooldownContests,
+		"contest_cooldown_hours":    settings.ContestCooldownHours,
+
+		"daily_goal_reviews": settings.DailyGoalReviews,
+		"daily_goal_minutes": settings.DailyGoalMinutes,
 	})
 }
 
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/httpx"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/lists"
 	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/reqctx"
 	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/users"
 )
@@ -14,14 +15,16 @@ import (
 type Handler struct {
 	tokens      *TokenRepo
 	users       *users.Repository
+	lists       *lists.Repository
 	icsBaseURL  string
 	defaultDays int
 }
 
-func NewHandler(tokens *TokenRepo, usersRepo *users.Repository, icsBaseURL string) *Handler {
+func NewHandler(tokens *TokenRepo, usersRepo *users.Repository, listsRepo *lists.Repository, icsBaseURL string) *Handler {
 	return &Handler{
 		tokens:      tokens,
 		users:       usersRepo,
+		lists:       listsRepo,
 		icsBaseURL:  strings.TrimRight(strings.TrimSpace(icsBaseURL), "/"),
 		defaultDays: 30,
 	}
@@ -92,8 +95,24 @@ func (h *Handler) ICS(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "failed to load calendar", http.StatusInternalServerError)
 		return
 	}
+	// Optional: restrict the feed to one list (custom, template or smart).
+	var inList map[string]bool
+	if listID := strings.TrimSpace(r.URL.Query().Get("list_id")); listID != "" {
+		ids, err := h.lists.ProblemIDs(r.Context(), userID, listID)
+		if err != nil {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		inList = make(map[string]bool, len(ids))
+		for _, id := range ids {
+			inList[id] = true
+		}
+	}
 	events := make([]Event, 0, len(due))
 	for _, d := range due {
+		if inList != nil && !inList[d.ProblemID] {
+			continue
+		}
 		local := d.DueAt.In(loc)
 		start := time.Date(local.Year(), local.Month(), local.Day(), settings.DueHourLocal, settings.DueMinuteLocal, 0, 0, loc)
 		end := start.Add(30 * time.Minute)
@@ -0,0 +1,170 @@
+package contests
+
+import (
+	"sort"
+	"time"
+)
+
+// Attempt events reported while a contest is running. solved and gave_up are
+// terminal: they end the problem's attempt and derive its contest result.
+const (
+	EventOpened      = "opened"
+	EventWrongAnswer = "wrong_answer"
+	EventHint        = "hint"
+	EventSolved      = "solved"
+	EventGaveUp      = "gave_up"
+)
+
+func validEventType(t string) bool {
+	switch t {
+	case EventOpened, EventWrongAnswer, EventHint, EventSolved, EventGaveUp:
+		return true
+	}
+	return false
+}
+
+func isTerminalEvent(t string) bool { return t == EventSolved || t == EventGaveUp }
+
+type Event struct {
+	ID         int64     `json:"id"`
+	ProblemID  string    `json:"problem_id"`
+	Type       string    `json:"type"`
+	OccurredAt time.Time `json:"occurred_at"`
+	OffsetSec  int       `json:"offset_sec"` // seconds since started_at
+	Note       string    `json:"note,omitempty"`
+}
+
+// Problem outcomes on the timeline.
+const (
+	OutcomeSolved    = "solved"
+	OutcomeGaveUp    = "gave_up"
+	OutcomeOpen      = "open"
+	OutcomeUntouched = "untouched"
+)
+
+type ProblemTimeline struct {
+	ProblemID        string `json:"problem_id"`
+	Title            string `json:"title"`
+	Outcome          string `json:"outcome"`
+	TimeSpentSec     int    `json:"time_spent_sec"`
+	WrongAnswers     int    `json:"wrong_answers"`
+	Hints            int    `json:"hints"`
+	FirstEventOffset *int   `json:"first_event_offset_sec,omitempty"`
+	FinishedOffset   *int   `json:"finished_offset_sec,omitempty"`
+	TargetMinutes    int    `json:"target_minutes"`
+	OverTargetSec    int    `json:"over_target_sec"`
+}
+
+type Timeline struct {
+	Contest  Contest           `json:"contest"`
+	Events   []Event           `json:"events"`
+	Problems []ProblemTimeline `json:"problems"`
+	IdleSec  int               `json:"idle_sec"`
+}
+
+// buildTimeline attributes the contest's elapsed time to its problems. Walking the
+// events in order, the span before each event goes to the problem in focus, or to
+// the event's own problem when nothing is in focus (it was being worked on before
+// anything was reported). A problem stays in focus until its terminal event. The
+// tail up to end goes to the focused problem or, with nothing in focus, counts as idle.
+// items fixes the output order and includes problems that never saw an event.
+func buildTimeline(startedAt time.Time, end time.Time, items []ContestItem, events []Event) ([]ProblemTimeline, int) {
+	byID := make(map[string]*ProblemTimeline, len(items))
+	out := make([]ProblemTimeline, len(items))
+	for i, it := range items {
+		out[i] = ProblemTimeline{
+			ProblemID:     it.Problem.ID,
+			Title:         it.Problem.Title,
+			Outcome:       OutcomeUntouched,
+			TargetMinutes: it.TargetMinutes,
+		}
+		byID[it.Problem.ID] = &out[i]
+	}
+
+	sorted := append([]Event(nil), events...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].OccurredAt.Before(sorted[j].OccurredAt) })
+
+	span := func(from, to time.Time) int {
+		if !to.After(from) {
+			return 0
+		}
+		return int(to.Sub(from).Seconds())
+	}
+
+	cursor := startedAt
+	focus := ""
+	for _, e := range sorted {
+		p := byID[e.ProblemID]
+		if p == nil {
+			continue
+		}
+		at := e.OccurredAt
+		if at.Before(cursor) {
+			at = cursor
+		}
+		owner := focus
+		if owner == "" {
+			owner = e.ProblemID
+		}
+		byID[owner].TimeSpentSec += span(cursor, at)
+		cursor = at
+
+		if p.FirstEventOffset == nil {
+			p.FirstEventOffset = ptrInt(e.OffsetSec)
+		}
+		if p.Outcome == OutcomeUntouched {
+			p.Outcome = OutcomeOpen
+		}
+		switch e.Type {
+		case EventWrongAnswer:
+			p.WrongAnswers++
+		case EventHint:
+			p.Hints++
+		case EventSolved:
+			p.Outcome = OutcomeSolved
+		case EventGaveUp:
+			p.Outcome = OutcomeGaveUp
+		}
+		if isTerminalEvent(e.Type) {
+			p.FinishedOffset = ptrInt(e.OffsetSec)
+			if focus == e.ProblemID {
+				focus = ""
+			}
+		} else {
+			focus = e.ProblemID
+		}
+	}
+
+	idle := 0
+	if focus != "" {
+		byID[focus].TimeSpentSec += span(cursor, end)
+	} else {
+		idle = span(cursor, end)
+	}
+	for i := range out {
+		if over := out[i].TimeSpentSec - out[i].TargetMinutes*60; over > 0 {
+			out[i].OverTargetSec = over
+		}
+	}
+	return out, idle
+}
+
+// derivedResult turns a finished problem's timeline into a contest result: a clean
+// solve grades 4, each of "used hints" and "had wrong answers" costs one point, and
+// giving up grades 1. ok is false while the problem has no terminal event.
+func derivedResult(p ProblemTimeline) (grade int, solved bool, ok bool) {
+	switch p.Outcome {
+	case OutcomeSolved:
+		grade = 4
+		if p.Hints > 0 {
+			grade--
+		}
+		if p.WrongAnswers > 0 {
+			grade--
+		}
+		return grade, true, true
+	case OutcomeGaveUp:
+		return 1, false, true
+	}
+	return 0, false, false
+}
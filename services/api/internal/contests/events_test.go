@@ -0,0 +1,54 @@
+package contests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/problems"
+)
+
+func TestBuildTimelineAttributesTimeToFocusedProblem(t *testing.T) {
+	start := time.Date(2026, 2, 10, 12, 0, 0, 0, time.UTC)
+	at := func(min int) time.Time { return start.Add(time.Duration(min) * time.Minute) }
+	items := []ContestItem{
+		{Problem: problems.Problem{ID: "a"}, TargetMinutes: 10},
+		{Problem: problems.Problem{ID: "b"}, TargetMinutes: 20},
+		{Problem: problems.Problem{ID: "c"}, TargetMinutes: 20},
+	}
+	events := []Event{
+		{ProblemID: "a", Type: EventOpened, OccurredAt: at(1)},
+		{ProblemID: "a", Type: EventWrongAnswer, OccurredAt: at(8)},
+		{ProblemID: "a", Type: EventSolved, OccurredAt: at(15)},
+		{ProblemID: "b", Type: EventHint, OccurredAt: at(20)},
+	}
+	rows, idle := buildTimeline(start, at(30), items, events)
+	// a: 0..15 (the first minute goes to the first reported problem); b: 15..30.
+	if rows[0].TimeSpentSec != 15*60 || rows[0].Outcome != OutcomeSolved || rows[0].OverTargetSec != 5*60 {
+		t.Fatalf("unexpected a: %+v", rows[0])
+	}
+	if rows[1].TimeSpentSec != 15*60 || rows[1].Outcome != OutcomeOpen || rows[1].Hints != 1 {
+		t.Fatalf("unexpected b: %+v", rows[1])
+	}
+	if rows[2].Outcome != OutcomeUntouched || rows[2].TimeSpentSec != 0 || idle != 0 {
+		t.Fatalf("unexpected c/idle: %+v idle=%d", rows[2], idle)
+	}
+	if grade, solved, ok := derivedResult(rows[0]); !ok || !solved || grade != 3 {
+		t.Fatalf("expected solved grade 3 for a, got %d %v %v", grade, solved, ok)
+	}
+	if _, _, ok := derivedResult(rows[1]); ok {
+		t.Fatalf("expected no derived result for an open problem")
+	}
+}
+
+func TestBuildTimelineCountsIdleAfterLastFinish(t *testing.T) {
+	start := time.Date(2026, 2, 10, 12, 0, 0, 0, time.UTC)
+	items := []ContestItem{{Problem: problems.Problem{ID: "a"}}}
+	events := []Event{{ProblemID: "a", Type: EventGaveUp, OccurredAt: start.Add(10 * time.Minute)}}
+	rows, idle := buildTimeline(start, start.Add(25*time.Minute), items, events)
+	if rows[0].TimeSpentSec != 600 || idle != 900 {
+		t.Fatalf("expected 600s on a and 900s idle, got %d and %d", rows[0].TimeSpentSec, idle)
+	}
+	if grade, solved, ok := derivedResult(rows[0]); !ok || solved || grade != 1 {
+		t.Fatalf("expected gave-up grade 1, got %d %v %v", grade, solved, ok)
+	}
+}
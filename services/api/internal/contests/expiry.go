@@ -0,0 +1,38 @@
+package contests
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// RunExpiry periodically expires contests that were started but never completed
+// within `after`. It blocks until ctx is cancelled, so run it in a goroutine.
+func RunExpiry(ctx context.Context, repo *Repository, after time.Duration, every time.Duration) {
+	if after <= 0 || every <= 0 {
+		return
+	}
+	sweep := func() {
+		n, err := repo.ExpireStale(ctx, after)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("contest expiry sweep: %v", err)
+			}
+			return
+		}
+		if n > 0 {
+			log.Printf("contest expiry sweep: expired %d contest(s)", n)
+		}
+	}
+	sweep()
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			sweep()
+		}
+	}
+}
@@ -1,15 +1,21 @@
 package contests
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/activity"
 	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/httpx"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/lists"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/mastery"
 	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/problems"
 	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/reqctx"
 	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/scheduler"
@@ -20,11 +26,13 @@ type Handler struct {
 	pool     *pgxpool.Pool
 	repo     *Repository
 	problems *problems.Repository
+	lists    *lists.Repository
 	users    *users.Repository
+	mastery  *mastery.Repository
 }
 
-func NewHandler(pool *pgxpool.Pool, repo *Repository, problemsRepo *problems.Repository, usersRepo *users.Repository) *Handler {
-	return &Handler{pool: pool, repo: repo, problems: problemsRepo, users: usersRepo}
+func NewHandler(pool *pgxpool.Pool, repo *Repository, problemsRepo *problems.Repository, listsRepo *lists.Repository, usersRepo *users.Repository) *Handler {
+	return &Handler{pool: pool, repo: repo, problems: problemsRepo, lists: listsRepo, users: usersRepo, mastery: mastery.NewRepository(pool)}
 }
 
 func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
@@ -50,6 +58,127 @@ func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 	httpx.WriteJSON(w, http.StatusOK, out)
 }
 
+type listResponse struct {
+	Items      []ContestWithSummary `json:"items"`
+	NextOffset *int                 `json:"next_offset,omitempty"`
+}
+
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httpx.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID, ok := reqctx.UserIDFromContext(r.Context())
+	if !ok {
+		httpx.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	q := r.URL.Query()
+	f := ListFilter{
+		Status:   strings.TrimSpace(strings.ToLower(q.Get("status"))),
+		Strategy: strings.TrimSpace(strings.ToLower(q.Get("strategy"))),
+		Limit:    20,
+	}
+	switch f.Status {
+	case "", StatusGenerated, StatusInProgress, StatusCompleted, StatusExpired:
+	default:
+		httpx.WriteError(w, http.StatusBadRequest, "status must be generated|in_progress|completed|expired")
+		return
+	}
+	if v := strings.TrimSpace(q.Get("limit")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 100 {
+			httpx.WriteError(w, http.StatusBadRequest, "limit must be 1..100")
+			return
+		}
+		f.Limit = n
+	}
+	if v := strings.TrimSpace(q.Get("offset")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			httpx.WriteError(w, http.StatusBadRequest, "offset must be >= 0")
+			return
+		}
+		f.Offset = n
+	}
+
+	from := strings.TrimSpace(q.Get("from"))
+	to := strings.TrimSpace(q.Get("to"))
+	if from != "" || to != "" {
+		settings, err := h.users.GetSettings(r.Context(), userID)
+		if err != nil {
+			httpx.WriteError(w, http.StatusInternalServerError, "failed to load user settings")
+			return
+		}
+		loc, err := time.LoadLocation(settings.Timezone)
+		if err != nil {
+			loc = time.UTC
+		}
+		if from != "" {
+			d, err := time.ParseInLocation("2006-01-02", from, loc)
+			if err != nil {
+				httpx.WriteError(w, http.StatusBadRequest, "from must be YYYY-MM-DD")
+				return
+			}
+			f.CreatedFrom = ptrTime(d.UTC())
+		}
+		if to != "" {
+			d, err := time.ParseInLocation("2006-01-02", to, loc)
+			if err != nil {
+				httpx.WriteError(w, http.StatusBadRequest, "to must be YYYY-MM-DD")
+				return
+			}
+			// Inclusive: everything before the start of the next local day.
+			f.CreatedBefore = ptrTime(d.AddDate(0, 0, 1).UTC())
+		}
+	}
+
+	items, hasMore, err := h.repo.List(r.Context(), userID, f)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to list contests")
+		return
+	}
+	out := listResponse{Items: items}
+	if hasMore {
+		out.NextOffset = ptrInt(f.Offset + len(items))
+	}
+	httpx.WriteJSON(w, http.StatusOK, out)
+}
+
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		httpx.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID, ok := reqctx.UserIDFromContext(r.Context())
+	if !ok {
+		httpx.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	id := strings.TrimSpace(chi.URLParam(r, "id"))
+	if id == "" {
+		httpx.WriteError(w, http.StatusBadRequest, "id required")
+		return
+	}
+	if err := h.repo.DeleteUnstarted(r.Context(), id, userID); err != nil {
+		if errors.Is(err, ErrContestStarted) {
+			httpx.WriteError(w, http.StatusConflict, err.Error())
+			return
+		}
+		if errors.Is(err, ErrNotHost) {
+			httpx.WriteError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		if h.repo.IsNotFound(err) {
+			httpx.WriteError(w, http.StatusNotFound, "not found")
+			return
+		}
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to delete contest")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (h *Handler) Generate(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		httpx.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
@@ -65,7 +194,30 @@ func (h *Handler) Generate(w http.ResponseWriter, r *http.Request) {
 		httpx.WriteError(w, http.StatusBadRequest, "invalid json body")
 		return
 	}
-	if req.DurationMinutes <= 0 {
+	req.Preset = strings.TrimSpace(req.Preset)
+	if req.Preset != "" {
+		preset, err := h.repo.GetPreset(r.Context(), userID, req.Preset)
+		if err != nil {
+			if h.repo.IsNotFound(err) {
+				httpx.WriteError(w, http.StatusNotFound, "preset not found")
+				return
+			}
+			httpx.WriteError(w, http.StatusInternalServerError, "failed to load preset")
+			return
+		}
+		req = req.applyPreset(preset)
+	}
+	if t := req.TargetMinutes; t != nil {
+		for _, m := range []int{t.Easy, t.Medium, t.Hard} {
+			if m < 0 || m > 240 {
+				httpx.WriteError(w, http.StatusBadRequest, "target_minutes must be 0..240")
+				return
+			}
+		}
+	}
+	// With per-difficulty targets and no explicit duration, the duration is the sum
+	// of the chosen items' targets (set after selection).
+	if req.DurationMinutes <= 0 && req.TargetMinutes == nil {
 		req.DurationMinutes = 60
 	}
 	if req.DifficultyMix.Easy < 0 || req.DifficultyMix.Medium < 0 || req.DifficultyMix.Hard < 0 {
@@ -76,6 +228,29 @@ func (h *Handler) Generate(w http.ResponseWriter, r *http.Request) {
 		// Reasonable default: 2 easy, 2 medium, 1 hard.
 		req.DifficultyMix = DifficultyMix{Easy: 2, Medium: 2, Hard: 1}
 	}
+	settings, err := h.users.GetSettings(r.Context(), userID)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load user settings")
+		return
+	}
+	req.reviewCooldown = time.Duration(settings.ContestCooldownHours) * time.Hour
+	req.recentContestProblemIDs, err = h.repo.RecentContestProblemIDs(r.Context(), userID, settings.ContestCooldownContests)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load recent contests")
+		return
+	}
+	req.ListID = strings.TrimSpace(req.ListID)
+	if req.ListID != "" {
+		ids, err := h.lists.ProblemIDs(r.Context(), userID, req.ListID)
+		if err != nil {
+			httpx.WriteError(w, http.StatusNotFound, "list not found")
+			return
+		}
+		req.listProblemIDs = make(map[string]bool, len(ids))
+		for _, id := range ids {
+			req.listProblemIDs[id] = true
+		}
+	}
 
 	// Load candidates from the library.
 	rows, err := h.pool.Query(r.Context(), `
@@ -106,17 +281,33 @@ func (h *Handler) Generate(w http.ResponseWriter, r *http.Request) {
 		p.State.LastGrade = lastGrade
 		all = append(all, p)
 	}
+	req.recentGrades, err = h.mastery.RecentGrades(r.Context(), userID, nil)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load review history")
+		return
+	}
 
 	now := time.Now().UTC()
 	chosen := pickContestProblems(now, req, all)
 	if len(chosen) == 0 {
+		if req.scoped() {
+			httpx.WriteError(w, http.StatusBadRequest, "no eligible problems match the contest scope")
+			return
+		}
 		httpx.WriteError(w, http.StatusBadRequest, "no eligible problems found (add problems first)")
 		return
 	}
 
-	targetMinutes := 0
-	if len(chosen) > 0 {
-		targetMinutes = req.DurationMinutes / len(chosen)
+	difficulties := make([]string, len(chosen))
+	for i, p := range chosen {
+		difficulties[i] = p.Difficulty
+	}
+	targets := itemTargets(req, difficulties)
+	if req.DurationMinutes <= 0 {
+		req.DurationMinutes = 0
+		for _, m := range targets {
+			req.DurationMinutes += m
+		}
 	}
 
 	ctx := r.Context()
@@ -127,13 +318,13 @@ func (h *Handler) Generate(w http.ResponseWriter, r *http.Request) {
 	}
 	defer func() { _ = tx.Rollback(ctx) }()
 
-	c, err := h.repo.CreateTx(ctx, tx, userID, req.DurationMinutes, strings.TrimSpace(strings.ToLower(req.Strategy)))
+	c, err := h.repo.CreateTx(ctx, tx, userID, req.DurationMinutes, strings.TrimSpace(strings.ToLower(req.Strategy)), req.Seed)
 	if err != nil {
 		httpx.WriteError(w, http.StatusInternalServerError, "failed to create contest")
 		return
 	}
 	for idx, p := range chosen {
-		if err := h.repo.AddItemTx(ctx, tx, c.ID, p.ID, idx, targetMinutes); err != nil {
+		if err := h.repo.AddItemTx(ctx, tx, c.ID, p.ID, idx, targets[idx], &p.Breakdown); err != nil {
 			httpx.WriteError(w, http.StatusInternalServerError, "failed to add contest item")
 			return
 		}
@@ -148,6 +339,86 @@ func (h *Handler) Generate(w http.ResponseWriter, r *http.Request) {
 	httpx.WriteJSON(w, http.StatusCreated, out)
 }
 
+func (h *Handler) ListPresets(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httpx.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID, ok := reqctx.UserIDFromContext(r.Context())
+	if !ok {
+		httpx.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	out, err := h.repo.ListPresets(r.Context(), userID)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to list presets")
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, out)
+}
+
+func (h *Handler) CreatePreset(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httpx.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID, ok := reqctx.UserIDFromContext(r.Context())
+	if !ok {
+		httpx.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	var req Preset
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpx.WriteError(w, http.StatusBadRequest, "invalid json body")
+		return
+	}
+	p, err := req.Normalize()
+	if err != nil {
+		httpx.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	out, err := h.repo.CreatePreset(r.Context(), userID, p)
+	if err != nil {
+		if errors.Is(err, ErrPresetNameTaken) {
+			httpx.WriteError(w, http.StatusConflict, err.Error())
+			return
+		}
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to create preset")
+		return
+	}
+	httpx.WriteJSON(w, http.StatusCreated, out)
+}
+
+func (h *Handler) DeletePreset(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		httpx.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID, ok := reqctx.UserIDFromContext(r.Context())
+	if !ok {
+		httpx.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	id := strings.TrimSpace(chi.URLParam(r, "id"))
+	if id == "" {
+		httpx.WriteError(w, http.StatusBadRequest, "id required")
+		return
+	}
+	if _, builtin := builtinPreset(id); builtin {
+		httpx.WriteError(w, http.StatusBadRequest, "built-in presets cannot be deleted")
+		return
+	}
+	if err := h.repo.DeletePreset(r.Context(), userID, id); err != nil {
+		if h.repo.IsNotFound(err) {
+			httpx.WriteError(w, http.StatusNotFound, "not found")
+			return
+		}
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to delete preset")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (h *Handler) Start(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		httpx.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
@@ -165,7 +436,7 @@ func (h *Handler) Start(w http.ResponseWriter, r *http.Request) {
 	}
 	out, err := h.repo.Start(r.Context(), id, userID)
 	if err != nil {
-		httpx.WriteError(w, http.StatusNotFound, "not found")
+		writeTransitionError(w, h.repo, err)
 		return
 	}
 	httpx.WriteJSON(w, http.StatusOK, out)
@@ -188,7 +459,7 @@ func (h *Handler) Complete(w http.ResponseWriter, r *http.Request) {
 	}
 	out, err := h.repo.Complete(r.Context(), id, userID)
 	if err != nil {
-		httpx.WriteError(w, http.StatusNotFound, "not found")
+		writeTransitionError(w, h.repo, err)
 		return
 	}
 	httpx.WriteJSON(w, http.StatusOK, out)
@@ -198,9 +469,11 @@ type submitResultsRequest struct {
 	Results []submitResult `json:"results"`
 }
 
+// submitResult is one problem's result. Grade may only be left out for a problem
+// with a solved or gave_up event, whose derived grade is then used.
 type submitResult struct {
 	ProblemID    string `json:"problem_id"`
-	Grade        int    `json:"grade"`
+	Grade        *int   `json:"grade"`
 	TimeSpentSec *int   `json:"time_spent_sec"`
 	SolvedFlag   *bool  `json:"solved_flag"`
 }
@@ -235,7 +508,7 @@ func (h *Handler) SubmitResults(w http.ResponseWriter, r *http.Request) {
 			httpx.WriteError(w, http.StatusBadRequest, "problem_id required")
 			return
 		}
-		if req.Results[i].Grade < 0 || req.Results[i].Grade > 4 {
+		if g := req.Results[i].Grade; g != nil && (*g < 0 || *g > 4) {
 			httpx.WriteError(w, http.StatusBadRequest, "grade must be 0..4")
 			return
 		}
@@ -264,70 +537,480 @@ func (h *Handler) SubmitResults(w http.ResponseWriter, r *http.Request) {
 	}
 	defer func() { _ = tx.Rollback(ctx) }()
 
-	// Ownership check inside the tx so the whole operation is consistent.
-	var one int
-	if err := tx.QueryRow(ctx, `SELECT 1 FROM contests WHERE id = $1 AND user_id = $2`, contestID, userID).Scan(&one); err != nil {
-		httpx.WriteError(w, http.StatusNotFound, "not found")
+	// Lock the contest inside the tx so state checks and writes are consistent.
+	contest, err := h.repo.LockRunningTx(ctx, tx, contestID, userID)
+	if err != nil {
+		writeTransitionError(w, h.repo, err)
+		return
+	}
+	items, err := h.repo.ItemProblemIDsTx(ctx, tx, contestID)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load contest items")
+		return
+	}
+	// Problems with attempt events take their grade, time and solved flag from the
+	// event timeline when the client omits them.
+	events, err := h.repo.ListEventsTx(ctx, tx, contestID, userID)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load contest events")
 		return
 	}
+	timeline := timelineByProblem(contest, items, events, now)
+
+	batch := make([]string, 0, len(req.Results))
+	omitted := 0
+	for _, res := range req.Results {
+		if !items[res.ProblemID] {
+			httpx.WriteError(w, http.StatusBadRequest, "problem "+res.ProblemID+" is not part of this contest")
+			return
+		}
+		if _, _, ok := derivedResult(timeline[res.ProblemID]); res.Grade == nil && !ok {
+			httpx.WriteError(w, http.StatusBadRequest, "grade required for problem "+res.ProblemID+" (no solved or gave_up event)")
+			return
+		}
+		batch = append(batch, res.ProblemID)
+		if res.TimeSpentSec == nil && timeline[res.ProblemID].Outcome == OutcomeUntouched {
+			omitted++
+		}
+	}
+
+	// Remaining results without a client-reported time get the span since the
+	// previous recorded result (or the start), split across this batch.
+	var computedSec int
+	if omitted > 0 {
+		lapStart, err := h.repo.LapStartTx(ctx, tx, contestID, userID, *contest.StartedAt, batch)
+		if err != nil {
+			httpx.WriteError(w, http.StatusInternalServerError, "failed to compute time spent")
+			return
+		}
+		computedSec = splitElapsed(lapStart, now, omitted)
+	}
+	isLate := contest.DeadlineAt != nil && now.After(*contest.DeadlineAt)
 
 	for _, res := range req.Results {
-		if err := h.repo.UpsertResultTx(ctx, tx, ResultInput{
+		pt := timeline[res.ProblemID]
+		timeComputed := res.TimeSpentSec == nil
+		if timeComputed {
+			if pt.Outcome != OutcomeUntouched {
+				res.TimeSpentSec = ptrInt(pt.TimeSpentSec)
+			} else {
+				res.TimeSpentSec = ptrInt(computedSec)
+			}
+		}
+		if grade, solved, ok := derivedResult(pt); ok {
+			if res.Grade == nil {
+				res.Grade = &grade
+			}
+			if res.SolvedFlag == nil {
+				res.SolvedFlag = &solved
+			}
+		}
+		in := ResultInput{
 			ContestID:     contestID,
+			UserID:        userID,
 			ProblemID:     res.ProblemID,
-			Grade:         &res.Grade,
+			Grade:         res.Grade,
 			TimeSpentSec:  res.TimeSpentSec,
 			SolvedFlag:    res.SolvedFlag,
 			RecordedAtUTC: now,
-		}); err != nil {
-			httpx.WriteError(w, http.StatusInternalServerError, "failed to save contest result")
+			IsLate:        isLate,
+			TimeComputed:  timeComputed,
+		}
+		if err := h.recordResultTx(ctx, tx, userID, in, settings, loc); err != nil {
+			httpx.WriteError(w, http.StatusInternalServerError, "failed to record contest result")
 			return
 		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to commit")
+		return
+	}
+
+	out, _ := h.repo.GetWithItems(r.Context(), contestID, userID)
+	httpx.WriteJSON(w, http.StatusOK, out)
+}
+
+// timelineByProblem builds the contest timeline up to now, keyed by problem id.
+func timelineByProblem(c Contest, items map[string]bool, events []Event, now time.Time) map[string]ProblemTimeline {
+	its := make([]ContestItem, 0, len(items))
+	for id := range items {
+		its = append(its, ContestItem{Problem: problems.Problem{ID: id}})
+	}
+	rows, _ := buildTimeline(*c.StartedAt, now, its, events)
+	out := make(map[string]ProblemTimeline, len(rows))
+	for _, row := range rows {
+		out[row.ProblemID] = row
+	}
+	return out
+}
 
-		if err := h.repo.InsertReviewLogTx(ctx, tx, userID, contestID, res.ProblemID, now, res.Grade, res.TimeSpentSec); err != nil {
-			httpx.WriteError(w, http.StatusInternalServerError, "failed to write review log")
+type postEventRequest struct {
+	ProblemID string `json:"problem_id"`
+	Type      string `json:"type"`
+	Note      string `json:"note"`
+}
+
+type postEventResponse struct {
+	Event  Event          `json:"event"`
+	Result *ContestResult `json:"result,omitempty"`
+}
+
+// PostEvent records an attempt event on a running contest. A terminal event
+// (solved, gave_up) also records the problem's contest result, derived from its
+// events, unless a result was already submitted for it.
+func (h *Handler) PostEvent(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httpx.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID, ok := reqctx.UserIDFromContext(r.Context())
+	if !ok {
+		httpx.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	contestID := strings.TrimSpace(chi.URLParam(r, "id"))
+	if contestID == "" {
+		httpx.WriteError(w, http.StatusBadRequest, "id required")
+		return
+	}
+	var req postEventRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpx.WriteError(w, http.StatusBadRequest, "invalid json body")
+		return
+	}
+	req.ProblemID = strings.TrimSpace(req.ProblemID)
+	req.Type = strings.TrimSpace(strings.ToLower(req.Type))
+	req.Note = strings.TrimSpace(req.Note)
+	if req.ProblemID == "" {
+		httpx.WriteError(w, http.StatusBadRequest, "problem_id required")
+		return
+	}
+	if !validEventType(req.Type) {
+		httpx.WriteError(w, http.StatusBadRequest, "type must be opened|wrong_answer|hint|solved|gave_up")
+		return
+	}
+	if len(req.Note) > 500 {
+		httpx.WriteError(w, http.StatusBadRequest, "note must be at most 500 characters")
+		return
+	}
+
+	settings, err := h.users.GetSettings(r.Context(), userID)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load user settings")
+		return
+	}
+	loc, err := time.LoadLocation(settings.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	now := time.Now().UTC()
+
+	ctx := r.Context()
+	tx, err := h.pool.Begin(ctx)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to start transaction")
+		return
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	contest, err := h.repo.LockRunningTx(ctx, tx, contestID, userID)
+	if err != nil {
+		writeTransitionError(w, h.repo, err)
+		return
+	}
+	items, err := h.repo.ItemProblemIDsTx(ctx, tx, contestID)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load contest items")
+		return
+	}
+	if !items[req.ProblemID] {
+		httpx.WriteError(w, http.StatusBadRequest, "problem "+req.ProblemID+" is not part of this contest")
+		return
+	}
+	events, err := h.repo.ListEventsTx(ctx, tx, contestID, userID)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load contest events")
+		return
+	}
+	for _, e := range events {
+		if e.ProblemID == req.ProblemID && isTerminalEvent(e.Type) {
+			httpx.WriteError(w, http.StatusConflict, "problem already finished ("+e.Type+")")
 			return
 		}
+	}
 
-		state, err := h.problems.GetStateForUpdate(ctx, tx, userID, res.ProblemID)
+	ev, err := h.repo.InsertEventTx(ctx, tx, contestID, userID, Event{
+		ProblemID:  req.ProblemID,
+		Type:       req.Type,
+		OccurredAt: now,
+		OffsetSec:  int(now.Sub(*contest.StartedAt).Seconds()),
+		Note:       req.Note,
+	})
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to record event")
+		return
+	}
+
+	resp := postEventResponse{Event: ev}
+	if isTerminalEvent(ev.Type) {
+		recorded, err := h.repo.HasResultTx(ctx, tx, contestID, userID, req.ProblemID)
 		if err != nil {
-			// Safety: if state doesn't exist, initialize and retry.
-			_ = h.problems.EnsureUserStateTx(ctx, tx, userID, res.ProblemID, now)
-			state, err = h.problems.GetStateForUpdate(ctx, tx, userID, res.ProblemID)
-			if err != nil {
-				httpx.WriteError(w, http.StatusNotFound, "problem state not found")
+			httpx.WriteError(w, http.StatusInternalServerError, "failed to load contest result")
+			return
+		}
+		if !recorded {
+			pt := timelineByProblem(contest, items, append(events, ev), now)[req.ProblemID]
+			grade, solved, _ := derivedResult(pt)
+			in := ResultInput{
+				ContestID:     contestID,
+				UserID:        userID,
+				ProblemID:     req.ProblemID,
+				Grade:         ptrInt(grade),
+				TimeSpentSec:  ptrInt(pt.TimeSpentSec),
+				SolvedFlag:    &solved,
+				RecordedAtUTC: now,
+				IsLate:        contest.DeadlineAt != nil && now.After(*contest.DeadlineAt),
+				TimeComputed:  true,
+			}
+			if err := h.recordResultTx(ctx, tx, userID, in, settings, loc); err != nil {
+				httpx.WriteError(w, http.StatusInternalServerError, "failed to record contest result")
 				return
 			}
+			resp.Result = &ContestResult{
+				Grade: in.Grade, TimeSpentSec: in.TimeSpentSec, SolvedFlag: in.SolvedFlag, RecordedAt: ptrTime(now),
+				IsLate: in.IsLate, TimeComputed: true,
+			}
 		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to commit")
+		return
+	}
+	httpx.WriteJSON(w, http.StatusCreated, resp)
+}
 
-		out := scheduler.Update(scheduler.State{
-			Reps:         state.Reps,
-			IntervalDays: state.IntervalDays,
-			Ease:         state.Ease,
-		}, res.Grade, now, loc, settings.MinIntervalDays, settings.DueHourLocal, settings.DueMinuteLocal)
+// Timeline returns the contest's attempt events and where its time went per problem.
+func (h *Handler) Timeline(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httpx.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID, ok := reqctx.UserIDFromContext(r.Context())
+	if !ok {
+		httpx.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	contestID := strings.TrimSpace(chi.URLParam(r, "id"))
+	if contestID == "" {
+		httpx.WriteError(w, http.StatusBadRequest, "id required")
+		return
+	}
+	c, err := h.repo.GetWithItems(r.Context(), contestID, userID)
+	if err != nil {
+		httpx.WriteError(w, http.StatusNotFound, "not found")
+		return
+	}
+	events, err := h.repo.ListEvents(r.Context(), contestID, userID)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load contest events")
+		return
+	}
+	out := Timeline{Contest: c.Contest, Events: events, Problems: make([]ProblemTimeline, 0)}
+	if c.StartedAt != nil {
+		end := time.Now().UTC()
+		switch {
+		case c.CompletedAt != nil:
+			end = *c.CompletedAt
+		case c.ExpiredAt != nil:
+			end = *c.ExpiredAt
+		}
+		out.Problems, out.IdleSec = buildTimeline(*c.StartedAt, end, c.Items, events)
+	}
+	httpx.WriteJSON(w, http.StatusOK, out)
+}
 
-		state.Reps = out.State.Reps
-		state.IntervalDays = out.State.IntervalDays
-		state.Ease = out.State.Ease
-		state.DueAt = out.DueAt
-		state.LastReviewAt = ptrTime(now)
-		state.LastGrade = ptrInt(res.Grade)
+type inviteResponse struct {
+	ContestID  string `json:"contest_id"`
+	InviteCode string `json:"invite_code"`
+}
 
-		if err := h.problems.UpdateState(ctx, tx, userID, res.ProblemID, state); err != nil {
-			httpx.WriteError(w, http.StatusInternalServerError, "failed to update scheduling state")
-			return
+// Invite returns the contest's invite code, creating it on first use. Host only.
+func (h *Handler) Invite(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httpx.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID, ok := reqctx.UserIDFromContext(r.Context())
+	if !ok {
+		httpx.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	contestID := strings.TrimSpace(chi.URLParam(r, "id"))
+	if contestID == "" {
+		httpx.WriteError(w, http.StatusBadRequest, "id required")
+		return
+	}
+	code, err := h.repo.EnsureInviteCode(r.Context(), contestID, userID)
+	if err != nil {
+		switch {
+		case h.repo.IsNotFound(err):
+			httpx.WriteError(w, http.StatusNotFound, "not found")
+		case errors.Is(err, ErrNotHost):
+			httpx.WriteError(w, http.StatusForbidden, err.Error())
+		default:
+			httpx.WriteError(w, http.StatusInternalServerError, "failed to create invite code")
 		}
+		return
 	}
+	httpx.WriteJSON(w, http.StatusOK, inviteResponse{ContestID: contestID, InviteCode: code})
+}
 
-	if err := tx.Commit(ctx); err != nil {
-		httpx.WriteError(w, http.StatusInternalServerError, "failed to commit")
+type joinRequest struct {
+	InviteCode string `json:"invite_code"`
+}
+
+// Join adds the caller to a shared contest by invite code and returns it with the
+// caller's own (initially empty) results.
+func (h *Handler) Join(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httpx.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID, ok := reqctx.UserIDFromContext(r.Context())
+	if !ok {
+		httpx.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	var req joinRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpx.WriteError(w, http.StatusBadRequest, "invalid json body")
+		return
+	}
+	code := strings.ToUpper(strings.TrimSpace(req.InviteCode))
+	if code == "" {
+		httpx.WriteError(w, http.StatusBadRequest, "invite_code required")
 		return
 	}
+	contestID, err := h.repo.Join(r.Context(), code, userID)
+	if err != nil {
+		switch {
+		case h.repo.IsNotFound(err):
+			httpx.WriteError(w, http.StatusNotFound, "invalid invite code")
+		case errors.Is(err, ErrContestClosed):
+			httpx.WriteError(w, http.StatusConflict, err.Error())
+		default:
+			httpx.WriteError(w, http.StatusInternalServerError, "failed to join contest")
+		}
+		return
+	}
+	out, err := h.repo.GetWithItems(r.Context(), contestID, userID)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load contest")
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, out)
+}
 
-	out, _ := h.repo.GetWithItems(r.Context(), contestID, userID)
+// Leaderboard ranks a shared contest's participants by solved count, then time.
+func (h *Handler) Leaderboard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httpx.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID, ok := reqctx.UserIDFromContext(r.Context())
+	if !ok {
+		httpx.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	contestID := strings.TrimSpace(chi.URLParam(r, "id"))
+	if contestID == "" {
+		httpx.WriteError(w, http.StatusBadRequest, "id required")
+		return
+	}
+	out, err := h.repo.Leaderboard(r.Context(), contestID, userID)
+	if err != nil {
+		if h.repo.IsNotFound(err) {
+			httpx.WriteError(w, http.StatusNotFound, "not found")
+			return
+		}
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load leaderboard")
+		return
+	}
 	httpx.WriteJSON(w, http.StatusOK, out)
 }
 
+// recordResultTx saves one contest result and, the first time the problem is
+// recorded for the contest, logs it as a contest review and advances the
+// problem's schedule by its grade.
+func (h *Handler) recordResultTx(ctx context.Context, tx pgx.Tx, userID string, in ResultInput, settings users.Settings, loc *time.Location) error {
+	prev, err := h.repo.UpsertResultTx(ctx, tx, in)
+	if err != nil {
+		return err
+	}
+	grade := *in.Grade
+	now := in.RecordedAtUTC
+	next := activity.ContestResult{RecordedAtUTC: now, Grade: in.Grade, TimeSpentSec: in.TimeSpentSec, Solved: in.SolvedFlag != nil && *in.SolvedFlag}
+	if err := activity.RecordContestResultTx(ctx, tx, userID, loc, prev, next); err != nil {
+		return err
+	}
+	logged, err := h.repo.InsertReviewLogTx(ctx, tx, userID, in.ContestID, in.ProblemID, now, grade, in.TimeSpentSec)
+	if err != nil {
+		return err
+	}
+	// A problem already logged for this contest (a terminal event followed by
+	// the end-of-contest submit, or a resubmit) was one attempt: the schedule
+	// has moved for it once already.
+	if !logged {
+		return nil
+	}
+
+	state, err := h.problems.GetStateForUpdate(ctx, tx, userID, in.ProblemID)
+	if err != nil {
+		// Safety: if state doesn't exist, initialize and retry.
+		_ = h.problems.EnsureUserStateTx(ctx, tx, userID, in.ProblemID, now)
+		state, err = h.problems.GetStateForUpdate(ctx, tx, userID, in.ProblemID)
+		if err != nil {
+			return err
+		}
+	}
+	rv := activity.Review{ProblemID: in.ProblemID, Grade: grade, ReviewedAtUTC: now, TimeSpentSec: in.TimeSpentSec}
+	if err := activity.RecordReviewTx(ctx, tx, userID, loc, rv); err != nil {
+		return err
+	}
+
+	out := scheduler.Update(scheduler.State{
+		Reps:         state.Reps,
+		IntervalDays: state.IntervalDays,
+		Ease:         state.Ease,
+	}, grade, now, loc, settings.MinIntervalDays, settings.DueHourLocal, settings.DueMinuteLocal)
+
+	state.Reps = out.State.Reps
+	state.IntervalDays = out.State.IntervalDays
+	state.Ease = out.State.Ease
+	state.DueAt = out.DueAt
+	state.LastReviewAt = ptrTime(now)
+	state.LastGrade = ptrInt(grade)
+
+	return h.problems.UpdateState(ctx, tx, userID, in.ProblemID, state)
+}
+
+// writeTransitionError maps lifecycle errors from Start, Complete and result
+// submission onto HTTP statuses.
+func writeTransitionError(w http.ResponseWriter, repo *Repository, err error) {
+	switch {
+	case repo.IsNotFound(err):
+		httpx.WriteError(w, http.StatusNotFound, "not found")
+	case errors.Is(err, ErrNotHost):
+		httpx.WriteError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, ErrInvalidTransition), errors.Is(err, ErrContestNotRunning):
+		httpx.WriteError(w, http.StatusConflict, err.Error())
+	default:
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to update contest")
+	}
+}
+
 func ptrInt(v int) *int              { return &v }
 func ptrTime(v time.Time) *time.Time { return &v }
 
@@ -0,0 +1,85 @@
+package contests
+
+import (
+	"crypto/rand"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrContestClosed is returned when joining a contest that has already completed or expired.
+var ErrContestClosed = errors.New("contest is no longer open to join")
+
+// Invite codes avoid characters that are easy to misread when shared aloud.
+const inviteAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+const inviteCodeLen = 8
+
+func newInviteCode() (string, error) {
+	buf := make([]byte, inviteCodeLen)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	for i, b := range buf {
+		buf[i] = inviteAlphabet[int(b)%len(inviteAlphabet)]
+	}
+	return string(buf), nil
+}
+
+// LeaderboardEntry is one participant's standing. Participants see each other
+// by DisplayName; Email is only filled in for the host and for the viewer's own
+// entry.
+type LeaderboardEntry struct {
+	Rank           int        `json:"rank"`
+	UserID         string     `json:"user_id"`
+	DisplayName    string     `json:"display_name"`
+	Email          string     `json:"email,omitempty"`
+	IsHost         bool       `json:"is_host"`
+	JoinedAt       time.Time  `json:"joined_at"`
+	SolvedCount    int        `json:"solved_count"`
+	RecordedCount  int        `json:"recorded_count"`
+	SolvedTimeSec  int        `json:"solved_time_sec"`
+	TotalTimeSec   int        `json:"total_time_sec"`
+	AvgGrade       *float64   `json:"avg_grade,omitempty"`
+	LastRecordedAt *time.Time `json:"last_recorded_at,omitempty"`
+}
+
+type Leaderboard struct {
+	Contest    Contest            `json:"contest"`
+	TotalItems int                `json:"total_items"`
+	Entries    []LeaderboardEntry `json:"entries"`
+}
+
+// displayName is the local part of an email address, which is as much of it as
+// other participants see.
+func displayName(email string) string {
+	local, _, _ := strings.Cut(email, "@")
+	if local = strings.TrimSpace(local); local == "" {
+		return "participant"
+	}
+	return local
+}
+
+// rankLeaderboard orders participants by solved count, then by time spent on the
+// problems they solved, and assigns competition ranks: tied participants share a
+// rank and the next rank skips past them (1, 1, 3). Ties list in join order.
+func rankLeaderboard(entries []LeaderboardEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.SolvedCount != b.SolvedCount {
+			return a.SolvedCount > b.SolvedCount
+		}
+		if a.SolvedTimeSec != b.SolvedTimeSec {
+			return a.SolvedTimeSec < b.SolvedTimeSec
+		}
+		return a.JoinedAt.Before(b.JoinedAt)
+	})
+	for i := range entries {
+		if i > 0 && entries[i].SolvedCount == entries[i-1].SolvedCount && entries[i].SolvedTimeSec == entries[i-1].SolvedTimeSec {
+			entries[i].Rank = entries[i-1].Rank
+		} else {
+			entries[i].Rank = i + 1
+		}
+	}
+}
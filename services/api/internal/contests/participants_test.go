@@ -0,0 +1,61 @@
+package contests
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRankLeaderboardBySolvedThenTime(t *testing.T) {
+	t0 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	entries := []LeaderboardEntry{
+		{UserID: "slow", SolvedCount: 2, SolvedTimeSec: 3000, JoinedAt: t0},
+		{UserID: "none", SolvedCount: 0, JoinedAt: t0},
+		{UserID: "fast", SolvedCount: 2, SolvedTimeSec: 1800, JoinedAt: t0.Add(time.Minute)},
+		{UserID: "tie", SolvedCount: 2, SolvedTimeSec: 1800, JoinedAt: t0.Add(2 * time.Minute)},
+		{UserID: "one", SolvedCount: 1, SolvedTimeSec: 600, JoinedAt: t0},
+	}
+	rankLeaderboard(entries)
+
+	want := []struct {
+		id   string
+		rank int
+	}{{"fast", 1}, {"tie", 1}, {"slow", 3}, {"one", 4}, {"none", 5}}
+	for i, w := range want {
+		if entries[i].UserID != w.id || entries[i].Rank != w.rank {
+			t.Fatalf("position %d: expected %s at rank %d, got %s at rank %d", i, w.id, w.rank, entries[i].UserID, entries[i].Rank)
+		}
+	}
+}
+
+func TestDisplayNameHidesDomain(t *testing.T) {
+	for in, want := range map[string]string{
+		"ada.lovelace@example.com": "ada.lovelace",
+		"no-at-sign":               "no-at-sign",
+		"@example.com":             "participant",
+	} {
+		if got := displayName(in); got != want {
+			t.Fatalf("displayName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestNewInviteCodeUsesAlphabet(t *testing.T) {
+	code, err := newInviteCode()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(code) != inviteCodeLen {
+		t.Fatalf("expected %d characters, got %q", inviteCodeLen, code)
+	}
+	for _, ch := range code {
+		found := false
+		for _, a := range inviteAlphabet {
+			if ch == a {
+				found = true
+			}
+		}
+		if !found {
+			t.Fatalf("unexpected character %q in %q", ch, code)
+		}
+	}
+}
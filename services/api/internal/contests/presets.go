@@ -0,0 +1,175 @@
+package contests
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TargetMinutes is the per-problem time budget for each difficulty.
+type TargetMinutes struct {
+	Easy   int `json:"easy"`
+	Medium int `json:"medium"`
+	Hard   int `json:"hard"`
+}
+
+func (t TargetMinutes) forDifficulty(d string) int {
+	switch normalizeDifficulty(d) {
+	case "easy":
+		return t.Easy
+	case "medium":
+		return t.Medium
+	case "hard":
+		return t.Hard
+	}
+	return 0
+}
+
+// Preset is a named contest shape. Built-in presets model common interview loops
+// and use slug ids; user presets are stored per user and use UUIDs.
+type Preset struct {
+	ID                 string        `json:"id"`
+	Name               string        `json:"name"`
+	BuiltIn            bool          `json:"built_in"`
+	Strategy           string        `json:"strategy"`
+	DurationMinutes    int           `json:"duration_minutes"`
+	DifficultyMix      DifficultyMix `json:"difficulty_mix"`
+	TargetMinutes      TargetMinutes `json:"target_minutes"`
+	IncludeTopics      []string      `json:"include_topics"`
+	ExcludeTopics      []string      `json:"exclude_topics"`
+	Platforms          []string      `json:"platforms"`
+	NeverAttemptedOnly bool          `json:"never_attempted_only"`
+	CreatedAt          *time.Time    `json:"created_at,omitempty"`
+}
+
+var builtinPresets = []Preset{
+	{
+		ID:              "phone-screen",
+		Name:            "Phone screen",
+		Strategy:        "balanced",
+		DurationMinutes: 45,
+		DifficultyMix:   DifficultyMix{Easy: 1, Medium: 1},
+		TargetMinutes:   TargetMinutes{Easy: 15, Medium: 25, Hard: 40},
+	},
+	{
+		ID:              "onsite-coding-round",
+		Name:            "Onsite coding round",
+		Strategy:        "balanced",
+		DurationMinutes: 60,
+		DifficultyMix:   DifficultyMix{Medium: 1, Hard: 1},
+		TargetMinutes:   TargetMinutes{Easy: 15, Medium: 25, Hard: 35},
+	},
+	{
+		ID:              "oa-90",
+		Name:            "OA 90min",
+		Strategy:        "balanced",
+		DurationMinutes: 90,
+		DifficultyMix:   DifficultyMix{Medium: 2, Hard: 1},
+		TargetMinutes:   TargetMinutes{Easy: 15, Medium: 25, Hard: 40},
+	},
+}
+
+func builtinPreset(id string) (Preset, bool) {
+	for _, p := range builtinPresets {
+		if p.ID == id {
+			p.BuiltIn = true
+			p.IncludeTopics = nonEmpty(p.IncludeTopics)
+			p.ExcludeTopics = nonEmpty(p.ExcludeTopics)
+			p.Platforms = nonEmpty(p.Platforms)
+			return p, true
+		}
+	}
+	return Preset{}, false
+}
+
+var errInvalidPreset = errors.New("invalid preset")
+
+// Normalize trims and validates a user-defined preset. A zero duration becomes the
+// sum of the per-difficulty targets for the mix.
+func (p Preset) Normalize() (Preset, error) {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" || len(p.Name) > 80 {
+		return Preset{}, fmt.Errorf("%w: name must be 1..80 characters", errInvalidPreset)
+	}
+	p.Strategy = strings.TrimSpace(strings.ToLower(p.Strategy))
+	switch p.Strategy {
+	case "":
+		p.Strategy = "balanced"
+	case "balanced", "due-heavy", "weakness":
+	default:
+		return Preset{}, fmt.Errorf("%w: strategy must be balanced|due-heavy|weakness", errInvalidPreset)
+	}
+	m, t := p.DifficultyMix, p.TargetMinutes
+	if m.Easy < 0 || m.Medium < 0 || m.Hard < 0 || m.Easy+m.Medium+m.Hard == 0 || m.Easy+m.Medium+m.Hard > 20 {
+		return Preset{}, fmt.Errorf("%w: difficulty_mix must total 1..20 with counts >= 0", errInvalidPreset)
+	}
+	for _, pair := range [][2]int{{m.Easy, t.Easy}, {m.Medium, t.Medium}, {m.Hard, t.Hard}} {
+		if pair[1] < 0 || pair[1] > 240 || (pair[0] > 0 && pair[1] == 0) {
+			return Preset{}, fmt.Errorf("%w: target_minutes must be 1..240 for every difficulty in the mix", errInvalidPreset)
+		}
+	}
+	if p.DurationMinutes == 0 {
+		p.DurationMinutes = m.Easy*t.Easy + m.Medium*t.Medium + m.Hard*t.Hard
+	}
+	if p.DurationMinutes < 1 || p.DurationMinutes > 600 {
+		return Preset{}, fmt.Errorf("%w: duration_minutes must be 1..600", errInvalidPreset)
+	}
+	p.IncludeTopics = nonEmpty(p.IncludeTopics)
+	p.ExcludeTopics = nonEmpty(p.ExcludeTopics)
+	p.Platforms = nonEmpty(p.Platforms)
+	return p, nil
+}
+
+func nonEmpty(in []string) []string {
+	out := make([]string, 0, len(in))
+	for _, v := range in {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// applyPreset fills the request fields left at their zero value from the preset,
+// so explicit values in a generate request override the preset.
+func (p GenerateParams) applyPreset(pr Preset) GenerateParams {
+	if p.Strategy == "" {
+		p.Strategy = pr.Strategy
+	}
+	if p.DurationMinutes == 0 {
+		p.DurationMinutes = pr.DurationMinutes
+	}
+	if p.totalCount() == 0 {
+		p.DifficultyMix = pr.DifficultyMix
+	}
+	if p.TargetMinutes == nil {
+		t := pr.TargetMinutes
+		p.TargetMinutes = &t
+	}
+	if len(p.IncludeTopics) == 0 {
+		p.IncludeTopics = pr.IncludeTopics
+	}
+	if len(p.ExcludeTopics) == 0 {
+		p.ExcludeTopics = pr.ExcludeTopics
+	}
+	if len(p.Platforms) == 0 {
+		p.Platforms = pr.Platforms
+	}
+	p.NeverAttemptedOnly = p.NeverAttemptedOnly || pr.NeverAttemptedOnly
+	return p
+}
+
+// itemTargets returns each chosen problem's target minutes: the per-difficulty
+// budget when one is set, otherwise an even split of the contest duration.
+func itemTargets(params GenerateParams, difficulties []string) []int {
+	out := make([]int, len(difficulties))
+	for i, d := range difficulties {
+		if params.TargetMinutes != nil {
+			out[i] = params.TargetMinutes.forDifficulty(d)
+		} else {
+			out[i] = params.DurationMinutes / len(difficulties)
+		}
+	}
+	return out
+}
@@ -0,0 +1,39 @@
+package contests
+
+import "testing"
+
+func TestPresetNormalizeDerivesDuration(t *testing.T) {
+	p, err := Preset{
+		Name:          " Loop ",
+		DifficultyMix: DifficultyMix{Easy: 1, Medium: 2},
+		TargetMinutes: TargetMinutes{Easy: 15, Medium: 25},
+	}.Normalize()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Name != "Loop" || p.Strategy != "balanced" || p.DurationMinutes != 65 {
+		t.Fatalf("unexpected normalized preset: %+v", p)
+	}
+	if _, err := (Preset{Name: "x", DifficultyMix: DifficultyMix{Hard: 1}}).Normalize(); err == nil {
+		t.Fatalf("expected error for missing hard target")
+	}
+}
+
+func TestApplyPresetKeepsExplicitFieldsAndSetsTargets(t *testing.T) {
+	pr, ok := builtinPreset("phone-screen")
+	if !ok {
+		t.Fatalf("expected built-in phone-screen")
+	}
+	params := GenerateParams{Strategy: "weakness"}.applyPreset(pr)
+	if params.Strategy != "weakness" || params.DurationMinutes != 45 || params.totalCount() != 2 {
+		t.Fatalf("unexpected params: %+v", params)
+	}
+	got := itemTargets(params, []string{"Easy", "medium"})
+	if got[0] != 15 || got[1] != 25 {
+		t.Fatalf("expected per-difficulty targets [15 25], got %v", got)
+	}
+	even := itemTargets(GenerateParams{DurationMinutes: 60}, []string{"easy", "hard"})
+	if even[0] != 30 || even[1] != 30 {
+		t.Fatalf("expected even split without targets, got %v", even)
+	}
+}
@@ -0,0 +1,133 @@
+package contests
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// Contest rating is Elo-style: every problem in a completed contest is a match
+// against the problem's difficulty rating, scored by how well it went. Ratings are
+// kept per scope: the overall "interview readiness" number, one per difficulty and
+// one per topic.
+const (
+	initialRating = 1500.0
+	ratingK       = 24.0
+
+	ScopeOverall = "overall"
+)
+
+var difficultyRatings = map[string]float64{
+	"easy":   1200,
+	"medium": 1500,
+	"hard":   1800,
+}
+
+// ratedProblem is one contest item as the rating sees it. Grade and TimeSpentSec
+// are nil when no result was recorded, which scores as a loss.
+type ratedProblem struct {
+	Difficulty    string
+	Topics        []string
+	TargetMinutes int
+	Grade         *int
+	TimeSpentSec  *int
+	Solved        *bool
+}
+
+// performance scores a problem in [0, 1]. Unsolved problems score 0. A solve is
+// worth 0.5, plus up to 0.25 for the grade and up to 0.25 for finishing within
+// target_minutes (scaled down when over target; neutral when time is unknown).
+func performance(p ratedProblem) float64 {
+	solved := p.Solved != nil && *p.Solved
+	if p.Solved == nil && p.Grade != nil {
+		solved = *p.Grade >= 2
+	}
+	if !solved {
+		return 0
+	}
+	s := 0.5
+	if p.Grade != nil {
+		s += 0.25 * clamp(float64(*p.Grade)/4, 0, 1)
+	}
+	switch {
+	case p.TimeSpentSec == nil || p.TargetMinutes <= 0:
+		s += 0.125
+	case *p.TimeSpentSec <= p.TargetMinutes*60:
+		s += 0.25
+	default:
+		s += 0.25 * float64(p.TargetMinutes*60) / float64(*p.TimeSpentSec)
+	}
+	return s
+}
+
+func expectedScore(rating, opponent float64) float64 {
+	return 1 / (1 + math.Pow(10, (opponent-rating)/400))
+}
+
+// ratingScopes lists the scopes a problem counts toward.
+func ratingScopes(p ratedProblem) []string {
+	out := []string{ScopeOverall}
+	if d := normalizeDifficulty(p.Difficulty); d != "unknown" {
+		out = append(out, "difficulty:"+d)
+	}
+	for t := range topicSet(p.Topics) {
+		out = append(out, "topic:"+t)
+	}
+	return out
+}
+
+// rateContest returns the new rating for every scope touched by the contest.
+// Each scope's update uses its pre-contest rating for all of its problems, so the
+// result does not depend on item order. Missing scopes start at initialRating.
+func rateContest(current map[string]float64, items []ratedProblem) map[string]float64 {
+	deltas := map[string]float64{}
+	for _, p := range items {
+		opp, ok := difficultyRatings[normalizeDifficulty(p.Difficulty)]
+		if !ok {
+			continue
+		}
+		score := performance(p)
+		for _, scope := range ratingScopes(p) {
+			r, ok := current[scope]
+			if !ok {
+				r = initialRating
+			}
+			deltas[scope] += ratingK * (score - expectedScore(r, opp))
+		}
+	}
+	out := make(map[string]float64, len(deltas))
+	for scope, d := range deltas {
+		r, ok := current[scope]
+		if !ok {
+			r = initialRating
+		}
+		out[scope] = math.Round((r+d)*10) / 10
+	}
+	return out
+}
+
+func sortedScopes(m map[string]float64) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		// overall first, then difficulty:*, then topic:*.
+		ri, rj := scopeRank(out[i]), scopeRank(out[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return out[i] < out[j]
+	})
+	return out
+}
+
+func scopeRank(s string) int {
+	switch {
+	case s == ScopeOverall:
+		return 0
+	case strings.HasPrefix(s, "difficulty:"):
+		return 1
+	}
+	return 2
+}
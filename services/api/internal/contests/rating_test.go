@@ -0,0 +1,57 @@
+package contests
+
+import "testing"
+
+func TestPerformanceRewardsGradeAndPace(t *testing.T) {
+	yes, no := true, false
+	g4, g2 := 4, 2
+	fast, slow := 600, 3600
+
+	clean := performance(ratedProblem{Difficulty: "medium", TargetMinutes: 20, Grade: &g4, TimeSpentSec: &fast, Solved: &yes})
+	if clean != 1 {
+		t.Fatalf("expected a clean on-target solve to score 1, got %v", clean)
+	}
+	late := performance(ratedProblem{Difficulty: "medium", TargetMinutes: 20, Grade: &g2, TimeSpentSec: &slow, Solved: &yes})
+	if late <= 0.5 || late >= clean {
+		t.Fatalf("expected a slow, weak solve between 0.5 and 1, got %v", late)
+	}
+	if s := performance(ratedProblem{Difficulty: "medium", Grade: &g4, Solved: &no}); s != 0 {
+		t.Fatalf("expected unsolved to score 0, got %v", s)
+	}
+	if s := performance(ratedProblem{Difficulty: "medium"}); s != 0 {
+		t.Fatalf("expected a problem without a result to score 0, got %v", s)
+	}
+}
+
+func TestRateContestUpdatesScopes(t *testing.T) {
+	yes := true
+	g4 := 4
+	items := []ratedProblem{
+		{Difficulty: "Hard", Topics: []string{"Graphs", "graphs"}, TargetMinutes: 40, Grade: &g4, Solved: &yes},
+		{Difficulty: "easy", Topics: []string{"arrays"}, TargetMinutes: 15},
+	}
+	got := rateContest(map[string]float64{ScopeOverall: 1500}, items)
+
+	want := []string{ScopeOverall, "difficulty:easy", "difficulty:hard", "topic:arrays", "topic:graphs"}
+	if scopes := sortedScopes(got); len(scopes) != len(want) {
+		t.Fatalf("expected scopes %v, got %v", want, scopes)
+	} else {
+		for i := range want {
+			if scopes[i] != want[i] {
+				t.Fatalf("expected scopes %v, got %v", want, scopes)
+			}
+		}
+	}
+	if got["difficulty:hard"] <= initialRating || got["topic:graphs"] <= initialRating {
+		t.Fatalf("expected solving a hard problem to raise its scopes, got %v", got)
+	}
+	if got["difficulty:easy"] >= initialRating || got["topic:arrays"] >= initialRating {
+		t.Fatalf("expected missing an easy problem to lower its scopes, got %v", got)
+	}
+
+	// Order of items does not matter.
+	rev := rateContest(map[string]float64{ScopeOverall: 1500}, []ratedProblem{items[1], items[0]})
+	if rev[ScopeOverall] != got[ScopeOverall] {
+		t.Fatalf("expected order-independent overall rating, got %v vs %v", rev[ScopeOverall], got[ScopeOverall])
+	}
+}
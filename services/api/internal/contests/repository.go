@@ -3,10 +3,12 @@ package contests
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/activity"
 	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/db"
 	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/problems"
 )
@@ -16,16 +18,58 @@ type Contest struct {
 	UserID          string     `json:"user_id"`
 	DurationMinutes int        `json:"duration_minutes"`
 	Strategy        string     `json:"strategy"`
+	Seed            *int64     `json:"seed,omitempty"`
+	InviteCode      *string    `json:"invite_code,omitempty"`
 	CreatedAt       time.Time  `json:"created_at"`
 	StartedAt       *time.Time `json:"started_at,omitempty"`
 	CompletedAt     *time.Time `json:"completed_at,omitempty"`
+	ExpiredAt       *time.Time `json:"expired_at,omitempty"`
+	DeadlineAt      *time.Time `json:"deadline_at,omitempty"`
+	Status          string     `json:"status"`
+}
+
+// Contest statuses, derived from the lifecycle timestamps.
+const (
+	StatusGenerated  = "generated"
+	StatusInProgress = "in_progress"
+	StatusCompleted  = "completed"
+	StatusExpired    = "expired"
+)
+
+// statusSQL mirrors deriveStatus for filtering in queries over the contests table aliased as c.
+const statusSQL = `CASE
+	WHEN c.completed_at IS NOT NULL THEN 'completed'
+	WHEN c.expired_at IS NOT NULL THEN 'expired'
+	WHEN c.started_at IS NOT NULL THEN 'in_progress'
+	ELSE 'generated'
+END`
+
+// withDerived fills the fields computed from the stored timestamps.
+func withDerived(c Contest) Contest {
+	c.Status = deriveStatus(c)
+	c.DeadlineAt = deadline(c)
+	return c
+}
+
+func deriveStatus(c Contest) string {
+	switch {
+	case c.CompletedAt != nil:
+		return StatusCompleted
+	case c.ExpiredAt != nil:
+		return StatusExpired
+	case c.StartedAt != nil:
+		return StatusInProgress
+	default:
+		return StatusGenerated
+	}
 }
 
 type ContestItem struct {
-	Problem       problems.Problem `json:"problem"`
-	OrderIndex    int              `json:"order_index"`
-	TargetMinutes int              `json:"target_minutes"`
-	Result        *ContestResult   `json:"result,omitempty"`
+	Problem        problems.Problem `json:"problem"`
+	OrderIndex     int              `json:"order_index"`
+	TargetMinutes  int              `json:"target_minutes"`
+	ScoreBreakdown *ScoreBreakdown  `json:"score_breakdown,omitempty"`
+	Result         *ContestResult   `json:"result,omitempty"`
 }
 
 type ContestWithItems struct {
@@ -38,6 +82,8 @@ type ContestResult struct {
 	TimeSpentSec *int       `json:"time_spent_sec,omitempty"`
 	SolvedFlag   *bool      `json:"solved_flag,omitempty"`
 	RecordedAt   *time.Time `json:"recorded_at,omitempty"`
+	IsLate       bool       `json:"is_late"`
+	TimeComputed bool       `json:"time_computed"`
 }
 
 type Repository struct {
@@ -46,26 +92,36 @@ type Repository struct {
 
 func NewRepository(pool *pgxpool.Pool) *Repository { return &Repository{pool: pool} }
 
-func (r *Repository) CreateTx(ctx context.Context, tx pgx.Tx, userID string, durationMinutes int, strategy string) (Contest, error) {
+func (r *Repository) CreateTx(ctx context.Context, tx pgx.Tx, userID string, durationMinutes int, strategy string, seed *int64) (Contest, error) {
 	var out Contest
 	err := tx.QueryRow(ctx, `
-		INSERT INTO contests (user_id, duration_minutes, strategy)
-		VALUES ($1, $2, $3)
-		RETURNING id::text, user_id::text, duration_minutes, strategy, created_at, started_at, completed_at
-	`, userID, durationMinutes, strategy).Scan(
-		&out.ID, &out.UserID, &out.DurationMinutes, &out.Strategy, &out.CreatedAt, &out.StartedAt, &out.CompletedAt,
+		INSERT INTO contests (user_id, duration_minutes, strategy, seed)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id::text, user_id::text, duration_minutes, strategy, seed, invite_code, created_at, started_at, completed_at, expired_at
+	`, userID, durationMinutes, strategy, seed).Scan(
+		&out.ID, &out.UserID, &out.DurationMinutes, &out.Strategy, &out.Seed, &out.InviteCode, &out.CreatedAt, &out.StartedAt, &out.CompletedAt, &out.ExpiredAt,
 	)
-	return out, err
+	if err != nil {
+		return Contest{}, err
+	}
+	// The host is a participant like everyone else, so membership checks are uniform.
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO contest_participants (contest_id, user_id) VALUES ($1, $2)
+	`, out.ID, userID); err != nil {
+		return Contest{}, err
+	}
+	return withDerived(out), nil
 }
 
-func (r *Repository) AddItemTx(ctx context.Context, tx pgx.Tx, contestID string, problemID string, orderIndex int, targetMinutes int) error {
+func (r *Repository) AddItemTx(ctx context.Context, tx pgx.Tx, contestID string, problemID string, orderIndex int, targetMinutes int, breakdown *ScoreBreakdown) error {
 	_, err := tx.Exec(ctx, `
-		INSERT INTO contest_items (contest_id, problem_id, order_index, target_minutes)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO contest_items (contest_id, problem_id, order_index, target_minutes, score_breakdown)
+		VALUES ($1, $2, $3, $4, $5)
 		ON CONFLICT (contest_id, problem_id) DO UPDATE
 		SET order_index = EXCLUDED.order_index,
-		    target_minutes = EXCLUDED.target_minutes
-	`, contestID, problemID, orderIndex, targetMinutes)
+		    target_minutes = EXCLUDED.target_minutes,
+		    score_breakdown = EXCLUDED.score_breakdown
+	`, contestID, problemID, orderIndex, targetMinutes, breakdown)
 	return err
 }
 
@@ -78,41 +134,242 @@ func (r *Repository) EnsureOwnership(ctx context.Context, contestID string, user
 	return nil
 }
 
-func (r *Repository) Start(ctx context.Context, contestID string, userID string) (Contest, error) {
-	if err := r.EnsureOwnership(ctx, contestID, userID); err != nil {
+// memberSQL restricts a query over contests aliased as c to contests the user in
+// $2 hosts or has joined.
+const memberSQL = `EXISTS (SELECT 1 FROM contest_participants cp WHERE cp.contest_id = c.id AND cp.user_id = $2)`
+
+// ErrNotHost is returned when a participant tries an action reserved for the
+// contest's host (starting, completing, deleting or inviting).
+var ErrNotHost = errors.New("only the contest host can do this")
+
+// lockTx loads a contest row FOR UPDATE so concurrent transitions and result
+// submissions on the same contest serialize. Any participant can lock it.
+func (r *Repository) lockTx(ctx context.Context, tx pgx.Tx, contestID string, userID string) (Contest, error) {
+	var out Contest
+	err := tx.QueryRow(ctx, `
+		SELECT c.id::text, c.user_id::text, c.duration_minutes, c.strategy, c.seed, c.invite_code, c.created_at, c.started_at, c.completed_at, c.expired_at
+		FROM contests c
+		WHERE c.id = $1 AND `+memberSQL+`
+		FOR UPDATE OF c
+	`, contestID, userID).Scan(
+		&out.ID, &out.UserID, &out.DurationMinutes, &out.Strategy, &out.Seed, &out.InviteCode, &out.CreatedAt, &out.StartedAt, &out.CompletedAt, &out.ExpiredAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Contest{}, db.ErrNotFound
+		}
 		return Contest{}, err
 	}
-	_, err := r.pool.Exec(ctx, `UPDATE contests SET started_at = COALESCE(started_at, now()) WHERE id = $1 AND user_id = $2`, contestID, userID)
+	return withDerived(out), nil
+}
+
+// LockRunningTx locks the contest for a result submission and fails with
+// ErrContestNotRunning unless it is in progress.
+func (r *Repository) LockRunningTx(ctx context.Context, tx pgx.Tx, contestID string, userID string) (Contest, error) {
+	c, err := r.lockTx(ctx, tx, contestID, userID)
 	if err != nil {
 		return Contest{}, err
 	}
-	return r.Get(ctx, contestID, userID)
+	if c.Status != StatusInProgress {
+		return Contest{}, fmt.Errorf("%w (status: %s)", ErrContestNotRunning, c.Status)
+	}
+	return c, nil
 }
 
-func (r *Repository) Complete(ctx context.Context, contestID string, userID string) (Contest, error) {
-	if err := r.EnsureOwnership(ctx, contestID, userID); err != nil {
+// apply validates action against the contest's current status and stamps the
+// timestamp of the resulting state. Only the host drives the shared clock.
+func (r *Repository) apply(ctx context.Context, contestID string, userID string, action string) (Contest, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return Contest{}, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	c, err := r.lockTx(ctx, tx, contestID, userID)
+	if err != nil {
 		return Contest{}, err
 	}
-	_, err := r.pool.Exec(ctx, `UPDATE contests SET completed_at = COALESCE(completed_at, now()) WHERE id = $1 AND user_id = $2`, contestID, userID)
+	if c.UserID != userID {
+		return Contest{}, ErrNotHost
+	}
+	to, err := transition(c.Status, action)
 	if err != nil {
 		return Contest{}, err
 	}
+	var column string
+	switch to {
+	case StatusInProgress:
+		column = "started_at"
+	case StatusCompleted:
+		column = "completed_at"
+	case StatusExpired:
+		column = "expired_at"
+	}
+	var at time.Time
+	if err := tx.QueryRow(ctx, `UPDATE contests SET `+column+` = now() WHERE id = $1 RETURNING `+column, contestID).Scan(&at); err != nil {
+		return Contest{}, err
+	}
+	if to == StatusCompleted {
+		if err := r.rateTx(ctx, tx, c); err != nil {
+			return Contest{}, err
+		}
+		if err := r.recordFinishedTx(ctx, tx, c.ID, at); err != nil {
+			return Contest{}, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return Contest{}, err
+	}
 	return r.Get(ctx, contestID, userID)
 }
 
+// recordFinishedTx counts the completed contest in each participant's daily rollup.
+func (r *Repository) recordFinishedTx(ctx context.Context, tx pgx.Tx, contestID string, completedAt time.Time) error {
+	userIDs, err := r.participantIDsTx(ctx, tx, contestID)
+	if err != nil {
+		return err
+	}
+	for _, userID := range userIDs {
+		loc, err := activity.LocationTx(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+		if err := activity.RecordContestFinishedTx(ctx, tx, userID, loc, completedAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Repository) participantIDsTx(ctx context.Context, tx pgx.Tx, contestID string) ([]string, error) {
+	rows, err := tx.Query(ctx, `SELECT user_id::text FROM contest_participants WHERE contest_id = $1 ORDER BY user_id`, contestID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		out = append(out, id)
+	}
+	return out, rows.Err()
+}
+
+// rateTx updates every participant's contest ratings for a contest that just
+// completed. It runs inside the complete transition, so each contest is rated
+// exactly once.
+func (r *Repository) rateTx(ctx context.Context, tx pgx.Tx, c Contest) error {
+	userIDs, err := r.participantIDsTx(ctx, tx, c.ID)
+	if err != nil {
+		return err
+	}
+	for _, userID := range userIDs {
+		if err := r.rateParticipantTx(ctx, tx, c.ID, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rateParticipantTx rates one participant on their own results and records one
+// history row per touched scope.
+func (r *Repository) rateParticipantTx(ctx context.Context, tx pgx.Tx, contestID string, userID string) error {
+	rows, err := tx.Query(ctx, `
+		SELECT p.difficulty, p.topics, ci.target_minutes, cr.grade, cr.time_spent_sec, cr.solved_flag
+		FROM contest_items ci
+		JOIN problems p ON p.id = ci.problem_id
+		LEFT JOIN contest_results cr ON cr.contest_id = ci.contest_id AND cr.problem_id = ci.problem_id AND cr.user_id = $2
+		WHERE ci.contest_id = $1
+		ORDER BY ci.order_index ASC
+	`, contestID, userID)
+	if err != nil {
+		return err
+	}
+	var items []ratedProblem
+	for rows.Next() {
+		var p ratedProblem
+		if err := rows.Scan(&p.Difficulty, &p.Topics, &p.TargetMinutes, &p.Grade, &p.TimeSpentSec, &p.Solved); err != nil {
+			rows.Close()
+			return err
+		}
+		items = append(items, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return nil
+	}
+
+	current := map[string]float64{}
+	rows, err = tx.Query(ctx, `SELECT scope, rating FROM contest_ratings WHERE user_id = $1 FOR UPDATE`, userID)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var scope string
+		var rating float64
+		if err := rows.Scan(&scope, &rating); err != nil {
+			rows.Close()
+			return err
+		}
+		current[scope] = rating
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	updated := rateContest(current, items)
+	for _, scope := range sortedScopes(updated) {
+		before, ok := current[scope]
+		if !ok {
+			before = initialRating
+		}
+		after := updated[scope]
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO contest_ratings (user_id, scope, rating, contests, updated_at)
+			VALUES ($1, $2, $3, 1, now())
+			ON CONFLICT (user_id, scope) DO UPDATE
+			SET rating = EXCLUDED.rating, contests = contest_ratings.contests + 1, updated_at = now()
+		`, userID, scope, after); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO contest_rating_history (user_id, contest_id, scope, rating_before, rating_after)
+			VALUES ($1, $2, $3, $4, $5)
+		`, userID, contestID, scope, before, after); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Repository) Start(ctx context.Context, contestID string, userID string) (Contest, error) {
+	return r.apply(ctx, contestID, userID, actionStart)
+}
+
+func (r *Repository) Complete(ctx context.Context, contestID string, userID string) (Contest, error) {
+	return r.apply(ctx, contestID, userID, actionComplete)
+}
+
 func (r *Repository) Get(ctx context.Context, contestID string, userID string) (Contest, error) {
 	var out Contest
 	err := r.pool.QueryRow(ctx, `
-		SELECT id::text, user_id::text, duration_minutes, strategy, created_at, started_at, completed_at
-		FROM contests
-		WHERE id = $1 AND user_id = $2
+		SELECT c.id::text, c.user_id::text, c.duration_minutes, c.strategy, c.seed, c.invite_code, c.created_at, c.started_at, c.completed_at, c.expired_at
+		FROM contests c
+		WHERE c.id = $1 AND `+memberSQL+`
 	`, contestID, userID).Scan(
-		&out.ID, &out.UserID, &out.DurationMinutes, &out.Strategy, &out.CreatedAt, &out.StartedAt, &out.CompletedAt,
+		&out.ID, &out.UserID, &out.DurationMinutes, &out.Strategy, &out.Seed, &out.InviteCode, &out.CreatedAt, &out.StartedAt, &out.CompletedAt, &out.ExpiredAt,
 	)
 	if err != nil {
 		return Contest{}, db.ErrNotFound
 	}
-	return out, nil
+	return withDerived(out), nil
 }
 
 func (r *Repository) GetWithItems(ctx context.Context, contestID string, userID string) (ContestWithItems, error) {
@@ -121,15 +378,16 @@ func (r *Repository) GetWithItems(ctx context.Context, contestID string, userID
 		return ContestWithItems{}, err
 	}
 	rows, err := r.pool.Query(ctx, `
-		SELECT ci.order_index, ci.target_minutes,
+		SELECT ci.order_index, ci.target_minutes, ci.score_breakdown,
 		       p.id::text, p.url, p.platform, p.title, p.difficulty, p.topics,
-		       cr.grade, cr.time_spent_sec, cr.solved_flag, cr.recorded_at
+		       cr.grade, cr.time_spent_sec, cr.solved_flag, cr.recorded_at,
+		       COALESCE(cr.is_late, false), COALESCE(cr.time_computed, false)
 		FROM contest_items ci
 		JOIN problems p ON p.id = ci.problem_id
-		LEFT JOIN contest_results cr ON cr.contest_id = ci.contest_id AND cr.problem_id = ci.problem_id
+		LEFT JOIN contest_results cr ON cr.contest_id = ci.contest_id AND cr.problem_id = ci.problem_id AND cr.user_id = $2
 		WHERE ci.contest_id = $1
 		ORDER BY ci.order_index ASC
-	`, contestID)
+	`, contestID, userID)
 	if err != nil {
 		return ContestWithItems{}, err
 	}
@@ -141,44 +399,319 @@ func (r *Repository) GetWithItems(ctx context.Context, contestID string, userID
 		var timeSpentSec *int
 		var solvedFlag *bool
 		var recordedAt *time.Time
+		var isLate, timeComputed bool
 		if err := rows.Scan(
-			&it.OrderIndex, &it.TargetMinutes,
+			&it.OrderIndex, &it.TargetMinutes, &it.ScoreBreakdown,
 			&it.Problem.ID, &it.Problem.URL, &it.Problem.Platform, &it.Problem.Title, &it.Problem.Difficulty, &it.Problem.Topics,
-			&grade, &timeSpentSec, &solvedFlag, &recordedAt,
+			&grade, &timeSpentSec, &solvedFlag, &recordedAt, &isLate, &timeComputed,
 		); err != nil {
 			return ContestWithItems{}, err
 		}
 		if grade != nil || timeSpentSec != nil || solvedFlag != nil || recordedAt != nil {
-			it.Result = &ContestResult{Grade: grade, TimeSpentSec: timeSpentSec, SolvedFlag: solvedFlag, RecordedAt: recordedAt}
+			it.Result = &ContestResult{
+				Grade: grade, TimeSpentSec: timeSpentSec, SolvedFlag: solvedFlag, RecordedAt: recordedAt,
+				IsLate: isLate, TimeComputed: timeComputed,
+			}
 		}
 		out.Items = append(out.Items, it)
 	}
 	return out, nil
 }
 
+type Summary struct {
+	TotalItems    int      `json:"total_items"`
+	RecordedCount int      `json:"recorded_count"`
+	SolvedCount   int      `json:"solved_count"`
+	AvgGrade      *float64 `json:"avg_grade,omitempty"`
+	TotalTimeSec  int      `json:"total_time_sec"`
+}
+
+type ContestWithSummary struct {
+	Contest
+	Summary Summary `json:"summary"`
+}
+
+type ListFilter struct {
+	Status        string
+	Strategy      string
+	CreatedFrom   *time.Time
+	CreatedBefore *time.Time
+	Limit         int
+	Offset        int
+}
+
+// List returns the contests the user hosts or has joined, newest first, with the
+// user's own results summarized. It fetches one row past Limit so
+// callers can tell whether another page exists.
+func (r *Repository) List(ctx context.Context, userID string, f ListFilter) ([]ContestWithSummary, bool, error) {
+	var status, strategy *string
+	if f.Status != "" {
+		status = &f.Status
+	}
+	if f.Strategy != "" {
+		strategy = &f.Strategy
+	}
+	rows, err := r.pool.Query(ctx, `
+		SELECT c.id::text, c.user_id::text, c.duration_minutes, c.strategy, c.seed, c.invite_code, c.created_at, c.started_at, c.completed_at, c.expired_at,
+		       ci.total_items, cr.recorded_count, cr.solved_count, cr.avg_grade, cr.total_time_sec
+		FROM contests c
+		LEFT JOIN LATERAL (
+			SELECT COUNT(*) AS total_items
+			FROM contest_items
+			WHERE contest_id = c.id
+		) ci ON true
+		LEFT JOIN LATERAL (
+			SELECT COUNT(*) AS recorded_count,
+			       COUNT(*) FILTER (WHERE solved_flag = true) AS solved_count,
+			       AVG(grade)::float8 AS avg_grade,
+			       COALESCE(SUM(time_spent_sec), 0) AS total_time_sec
+			FROM contest_results
+			WHERE contest_id = c.id AND user_id = $1
+		) cr ON true
+		WHERE EXISTS (SELECT 1 FROM contest_participants cp WHERE cp.contest_id = c.id AND cp.user_id = $1)
+		  AND ($2::text IS NULL OR `+statusSQL+` = $2)
+		  AND ($3::text IS NULL OR c.strategy = $3)
+		  AND ($4::timestamptz IS NULL OR c.created_at >= $4)
+		  AND ($5::timestamptz IS NULL OR c.created_at < $5)
+		ORDER BY c.created_at DESC, c.id ASC
+		LIMIT $6 OFFSET $7
+	`, userID, status, strategy, f.CreatedFrom, f.CreatedBefore, f.Limit+1, f.Offset)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+	out := make([]ContestWithSummary, 0, f.Limit)
+	for rows.Next() {
+		var c ContestWithSummary
+		if err := rows.Scan(
+			&c.ID, &c.UserID, &c.DurationMinutes, &c.Strategy, &c.Seed, &c.InviteCode, &c.CreatedAt, &c.StartedAt, &c.CompletedAt, &c.ExpiredAt,
+			&c.Summary.TotalItems, &c.Summary.RecordedCount, &c.Summary.SolvedCount, &c.Summary.AvgGrade, &c.Summary.TotalTimeSec,
+		); err != nil {
+			return nil, false, err
+		}
+		c.Contest = withDerived(c.Contest)
+		out = append(out, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+	hasMore := len(out) > f.Limit
+	if hasMore {
+		out = out[:f.Limit]
+	}
+	return out, hasMore, nil
+}
+
+// RecentContestProblemIDs returns the problems that appeared in the user's last n contests.
+func (r *Repository) RecentContestProblemIDs(ctx context.Context, userID string, n int) (map[string]bool, error) {
+	out := map[string]bool{}
+	if n <= 0 {
+		return out, nil
+	}
+	rows, err := r.pool.Query(ctx, `
+		SELECT DISTINCT ci.problem_id::text
+		FROM contest_items ci
+		JOIN (
+			SELECT c.id
+			FROM contests c
+			JOIN contest_participants cp ON cp.contest_id = c.id
+			WHERE cp.user_id = $1
+			ORDER BY c.created_at DESC
+			LIMIT $2
+		) recent ON recent.id = ci.contest_id
+	`, userID, n)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		out[id] = true
+	}
+	return out, rows.Err()
+}
+
+// ErrContestStarted is returned when deleting a contest that has already been started.
+var ErrContestStarted = errors.New("only contests that have not been started can be deleted")
+
+func (r *Repository) DeleteUnstarted(ctx context.Context, contestID string, userID string) error {
+	c, err := r.Get(ctx, contestID, userID)
+	if err != nil {
+		return err
+	}
+	if c.UserID != userID {
+		return ErrNotHost
+	}
+	if c.StartedAt != nil {
+		return ErrContestStarted
+	}
+	ct, err := r.pool.Exec(ctx, `DELETE FROM contests WHERE id = $1 AND user_id = $2 AND started_at IS NULL`, contestID, userID)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		// Started between the read and the delete.
+		return ErrContestStarted
+	}
+	return nil
+}
+
+// ExpireStale marks contests that were started more than `after` ago but never
+// completed as expired, returning how many were updated.
+func (r *Repository) ExpireStale(ctx context.Context, after time.Duration) (int64, error) {
+	ct, err := r.pool.Exec(ctx, `
+		UPDATE contests
+		SET expired_at = now()
+		WHERE completed_at IS NULL
+		  AND expired_at IS NULL
+		  AND started_at IS NOT NULL
+		  AND started_at < $1
+	`, time.Now().UTC().Add(-after))
+	if err != nil {
+		return 0, err
+	}
+	return ct.RowsAffected(), nil
+}
+
 type ResultInput struct {
 	ContestID     string
+	UserID        string
 	ProblemID     string
 	Grade         *int
 	TimeSpentSec  *int
 	SolvedFlag    *bool
 	RecordedAtUTC time.Time
+	IsLate        bool
+	TimeComputed  bool
 }
 
-func (r *Repository) UpsertResultTx(ctx context.Context, tx pgx.Tx, in ResultInput) error {
-	_, err := tx.Exec(ctx, `
-		INSERT INTO contest_results (contest_id, problem_id, grade, time_spent_sec, solved_flag, recorded_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		ON CONFLICT (contest_id, problem_id) DO UPDATE
+// UpsertResultTx saves a participant's result and returns the one it replaced,
+// if any, so the daily rollup can move it.
+func (r *Repository) UpsertResultTx(ctx context.Context, tx pgx.Tx, in ResultInput) (*activity.ContestResult, error) {
+	var prev activity.ContestResult
+	err := tx.QueryRow(ctx, `
+		SELECT recorded_at, grade, time_spent_sec, solved_flag IS TRUE
+		FROM contest_results
+		WHERE contest_id = $1 AND user_id = $2 AND problem_id = $3
+		FOR UPDATE
+	`, in.ContestID, in.UserID, in.ProblemID).Scan(&prev.RecordedAtUTC, &prev.Grade, &prev.TimeSpentSec, &prev.Solved)
+	found := err == nil
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO contest_results (contest_id, user_id, problem_id, grade, time_spent_sec, solved_flag, recorded_at, is_late, time_computed)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (contest_id, user_id, problem_id) DO UPDATE
 		SET grade = EXCLUDED.grade,
 		    time_spent_sec = EXCLUDED.time_spent_sec,
 		    solved_flag = EXCLUDED.solved_flag,
-		    recorded_at = EXCLUDED.recorded_at
-	`, in.ContestID, in.ProblemID, in.Grade, in.TimeSpentSec, in.SolvedFlag, in.RecordedAtUTC)
-	return err
+		    recorded_at = EXCLUDED.recorded_at,
+		    is_late = EXCLUDED.is_late,
+		    time_computed = EXCLUDED.time_computed
+	`, in.ContestID, in.UserID, in.ProblemID, in.Grade, in.TimeSpentSec, in.SolvedFlag, in.RecordedAtUTC, in.IsLate, in.TimeComputed)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &prev, nil
+}
+
+// ItemProblemIDsTx returns the set of problem ids that belong to the contest.
+func (r *Repository) ItemProblemIDsTx(ctx context.Context, tx pgx.Tx, contestID string) (map[string]bool, error) {
+	rows, err := tx.Query(ctx, `SELECT problem_id::text FROM contest_items WHERE contest_id = $1`, contestID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string]bool{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		out[id] = true
+	}
+	return out, rows.Err()
+}
+
+// LapStartTx returns when the user's clock for the next result started: the latest
+// recorded_at among their results for problems outside exclude, or startedAt if none is later.
+func (r *Repository) LapStartTx(ctx context.Context, tx pgx.Tx, contestID string, userID string, startedAt time.Time, exclude []string) (time.Time, error) {
+	var last *time.Time
+	if err := tx.QueryRow(ctx, `
+		SELECT MAX(recorded_at)
+		FROM contest_results
+		WHERE contest_id = $1 AND user_id = $2 AND NOT (problem_id::text = ANY($3))
+	`, contestID, userID, exclude).Scan(&last); err != nil {
+		return time.Time{}, err
+	}
+	if last != nil && last.After(startedAt) {
+		return *last, nil
+	}
+	return startedAt, nil
+}
+
+func (r *Repository) InsertEventTx(ctx context.Context, tx pgx.Tx, contestID string, userID string, e Event) (Event, error) {
+	err := tx.QueryRow(ctx, `
+		INSERT INTO contest_events (contest_id, user_id, problem_id, type, occurred_at, offset_sec, note)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`, contestID, userID, e.ProblemID, e.Type, e.OccurredAt, e.OffsetSec, e.Note).Scan(&e.ID)
+	return e, err
+}
+
+const eventsQuery = `
+	SELECT id, problem_id::text, type, occurred_at, offset_sec, note
+	FROM contest_events
+	WHERE contest_id = $1 AND user_id = $2
+	ORDER BY occurred_at ASC, id ASC
+`
+
+func scanEvents(rows pgx.Rows) ([]Event, error) {
+	defer rows.Close()
+	out := make([]Event, 0)
+	for rows.Next() {
+		var e Event
+		if err := rows.Scan(&e.ID, &e.ProblemID, &e.Type, &e.OccurredAt, &e.OffsetSec, &e.Note); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// ListEvents returns one participant's attempt events in order.
+func (r *Repository) ListEvents(ctx context.Context, contestID string, userID string) ([]Event, error) {
+	rows, err := r.pool.Query(ctx, eventsQuery, contestID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return scanEvents(rows)
 }
 
-func (r *Repository) InsertReviewLogTx(ctx context.Context, tx pgx.Tx, userID string, contestID string, problemID string, reviewedAtUTC time.Time, grade int, timeSpentSec *int) error {
+func (r *Repository) ListEventsTx(ctx context.Context, tx pgx.Tx, contestID string, userID string) ([]Event, error) {
+	rows, err := tx.Query(ctx, eventsQuery, contestID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return scanEvents(rows)
+}
+
+// HasResultTx reports whether the user has already recorded a result for the problem.
+func (r *Repository) HasResultTx(ctx context.Context, tx pgx.Tx, contestID string, userID string, problemID string) (bool, error) {
+	var exists bool
+	err := tx.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM contest_results WHERE contest_id = $1 AND user_id = $2 AND problem_id = $3)
+	`, contestID, userID, problemID).Scan(&exists)
+	return exists, err
+}
+
+// InsertReviewLogTx logs a contest result as a review and reports whether a new
+// log was written.
+func (r *Repository) InsertReviewLogTx(ctx context.Context, tx pgx.Tx, userID string, contestID string, problemID string, reviewedAtUTC time.Time, grade int, timeSpentSec *int) (bool, error) {
 	// Avoid duplicating contest-driven review logs if results are resubmitted.
 	var one int
 	if err := tx.QueryRow(ctx, `
@@ -187,15 +720,231 @@ func (r *Repository) InsertReviewLogTx(ctx context.Context, tx pgx.Tx, userID st
 		WHERE user_id = $1 AND contest_id = $2 AND problem_id = $3
 		LIMIT 1
 	`, userID, contestID, problemID).Scan(&one); err == nil {
-		return nil
+		return false, nil
 	} else if !errors.Is(err, pgx.ErrNoRows) {
-		return err
+		return false, err
 	}
 	_, err := tx.Exec(ctx, `
 		INSERT INTO review_logs (user_id, problem_id, reviewed_at, grade, time_spent_sec, source, contest_id)
 		VALUES ($1, $2, $3, $4, $5, 'contest', $6)
 	`, userID, problemID, reviewedAtUTC, grade, timeSpentSec, contestID)
-	return err
+	return err == nil, err
 }
 
 func (r *Repository) IsNotFound(err error) bool { return errors.Is(err, db.ErrNotFound) }
+
+const presetColumns = `id::text, name, strategy, duration_minutes,
+	easy_count, medium_count, hard_count, easy_minutes, medium_minutes, hard_minutes,
+	include_topics, exclude_topics, platforms, never_attempted_only, created_at`
+
+func scanPreset(row pgx.Row, p *Preset) error {
+	var createdAt time.Time
+	err := row.Scan(
+		&p.ID, &p.Name, &p.Strategy, &p.DurationMinutes,
+		&p.DifficultyMix.Easy, &p.DifficultyMix.Medium, &p.DifficultyMix.Hard,
+		&p.TargetMinutes.Easy, &p.TargetMinutes.Medium, &p.TargetMinutes.Hard,
+		&p.IncludeTopics, &p.ExcludeTopics, &p.Platforms, &p.NeverAttemptedOnly, &createdAt,
+	)
+	p.CreatedAt = &createdAt
+	return err
+}
+
+// ErrPresetNameTaken is returned when a user already has a preset with the same name.
+var ErrPresetNameTaken = errors.New("a preset with this name already exists")
+
+// ListPresets returns the built-in presets followed by the user's saved ones.
+func (r *Repository) ListPresets(ctx context.Context, userID string) ([]Preset, error) {
+	out := make([]Preset, 0, len(builtinPresets))
+	for _, b := range builtinPresets {
+		p, _ := builtinPreset(b.ID)
+		out = append(out, p)
+	}
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+presetColumns+`
+		FROM contest_presets
+		WHERE user_id = $1
+		ORDER BY lower(name) ASC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p Preset
+		if err := scanPreset(rows, &p); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// GetPreset resolves a built-in slug or one of the user's saved preset ids.
+func (r *Repository) GetPreset(ctx context.Context, userID string, presetID string) (Preset, error) {
+	if p, ok := builtinPreset(presetID); ok {
+		return p, nil
+	}
+	var out Preset
+	err := scanPreset(r.pool.QueryRow(ctx, `
+		SELECT `+presetColumns+`
+		FROM contest_presets
+		WHERE id::text = $1 AND user_id = $2
+	`, presetID, userID), &out)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Preset{}, db.ErrNotFound
+		}
+		return Preset{}, err
+	}
+	return out, nil
+}
+
+func (r *Repository) CreatePreset(ctx context.Context, userID string, p Preset) (Preset, error) {
+	var out Preset
+	err := scanPreset(r.pool.QueryRow(ctx, `
+		INSERT INTO contest_presets (
+			user_id, name, strategy, duration_minutes,
+			easy_count, medium_count, hard_count, easy_minutes, medium_minutes, hard_minutes,
+			include_topics, exclude_topics, platforms, never_attempted_only
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		RETURNING `+presetColumns,
+		userID, p.Name, p.Strategy, p.DurationMinutes,
+		p.DifficultyMix.Easy, p.DifficultyMix.Medium, p.DifficultyMix.Hard,
+		p.TargetMinutes.Easy, p.TargetMinutes.Medium, p.TargetMinutes.Hard,
+		p.IncludeTopics, p.ExcludeTopics, p.Platforms, p.NeverAttemptedOnly,
+	), &out)
+	if err != nil {
+		if problems.IsUniqueViolation(err) {
+			return Preset{}, ErrPresetNameTaken
+		}
+		return Preset{}, err
+	}
+	return out, nil
+}
+
+func (r *Repository) DeletePreset(ctx context.Context, userID string, presetID string) error {
+	ct, err := r.pool.Exec(ctx, `DELETE FROM contest_presets WHERE id::text = $1 AND user_id = $2`, presetID, userID)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return db.ErrNotFound
+	}
+	return nil
+}
+
+// EnsureInviteCode returns the contest's invite code, creating one on first use.
+// Only the host can invite.
+func (r *Repository) EnsureInviteCode(ctx context.Context, contestID string, userID string) (string, error) {
+	c, err := r.Get(ctx, contestID, userID)
+	if err != nil {
+		return "", err
+	}
+	if c.UserID != userID {
+		return "", ErrNotHost
+	}
+	if c.InviteCode != nil {
+		return *c.InviteCode, nil
+	}
+	for attempt := 0; attempt < 5; attempt++ {
+		code, err := newInviteCode()
+		if err != nil {
+			return "", err
+		}
+		var out string
+		err = r.pool.QueryRow(ctx, `
+			UPDATE contests
+			SET invite_code = COALESCE(invite_code, $3)
+			WHERE id = $1 AND user_id = $2
+			RETURNING invite_code
+		`, contestID, userID, code).Scan(&out)
+		if err == nil {
+			return out, nil
+		}
+		if !problems.IsUniqueViolation(err) {
+			return "", err
+		}
+	}
+	return "", errors.New("could not allocate a unique invite code")
+}
+
+// Join adds the user to the contest with the given invite code. Joining again is a
+// no-op; contests that completed or expired cannot be joined.
+func (r *Repository) Join(ctx context.Context, inviteCode string, userID string) (string, error) {
+	var c Contest
+	err := r.pool.QueryRow(ctx, `
+		SELECT id::text, started_at, completed_at, expired_at
+		FROM contests
+		WHERE invite_code = $1
+	`, inviteCode).Scan(&c.ID, &c.StartedAt, &c.CompletedAt, &c.ExpiredAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", db.ErrNotFound
+		}
+		return "", err
+	}
+	if s := deriveStatus(c); s == StatusCompleted || s == StatusExpired {
+		return "", fmt.Errorf("%w (status: %s)", ErrContestClosed, s)
+	}
+	if _, err := r.pool.Exec(ctx, `
+		INSERT INTO contest_participants (contest_id, user_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`, c.ID, userID); err != nil {
+		return "", err
+	}
+	return c.ID, nil
+}
+
+// Leaderboard ranks the contest's participants on their own results. The caller
+// must be a participant; only the host sees the other participants' emails.
+func (r *Repository) Leaderboard(ctx context.Context, contestID string, userID string) (Leaderboard, error) {
+	c, err := r.Get(ctx, contestID, userID)
+	if err != nil {
+		return Leaderboard{}, err
+	}
+	out := Leaderboard{Contest: c, Entries: make([]LeaderboardEntry, 0)}
+	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM contest_items WHERE contest_id = $1`, contestID).Scan(&out.TotalItems); err != nil {
+		return Leaderboard{}, err
+	}
+	rows, err := r.pool.Query(ctx, `
+		SELECT cp.user_id::text, u.email, cp.joined_at,
+		       COUNT(*) FILTER (WHERE cr.solved_flag = true) AS solved_count,
+		       COUNT(cr.problem_id) AS recorded_count,
+		       COALESCE(SUM(cr.time_spent_sec) FILTER (WHERE cr.solved_flag = true), 0) AS solved_time_sec,
+		       COALESCE(SUM(cr.time_spent_sec), 0) AS total_time_sec,
+		       AVG(cr.grade)::float8 AS avg_grade,
+		       MAX(cr.recorded_at) AS last_recorded_at
+		FROM contest_participants cp
+		JOIN users u ON u.id = cp.user_id
+		LEFT JOIN contest_results cr ON cr.contest_id = cp.contest_id AND cr.user_id = cp.user_id
+		WHERE cp.contest_id = $1
+		GROUP BY cp.user_id, u.email, cp.joined_at
+	`, contestID)
+	if err != nil {
+		return Leaderboard{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var e LeaderboardEntry
+		var email string
+		if err := rows.Scan(
+			&e.UserID, &email, &e.JoinedAt, &e.SolvedCount, &e.RecordedCount,
+			&e.SolvedTimeSec, &e.TotalTimeSec, &e.AvgGrade, &e.LastRecordedAt,
+		); err != nil {
+			return Leaderboard{}, err
+		}
+		e.IsHost = e.UserID == c.UserID
+		e.DisplayName = displayName(email)
+		if userID == c.UserID || userID == e.UserID {
+			e.Email = email
+		}
+		out.Entries = append(out.Entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return Leaderboard{}, err
+	}
+	rankLeaderboard(out.Entries)
+	return out, nil
+}
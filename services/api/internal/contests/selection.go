@@ -1,20 +1,57 @@
 package contests
 
 import (
-	"math"
+	"math/rand"
 	"sort"
 	"strings"
 	"time"
 
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/mastery"
 	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/problems"
 )
 
 type candidate struct {
-	problem problems.Problem
-	state   problems.UserState
-	score   float64
+	problem   problems.Problem
+	state     problems.UserState
+	score     float64
+	breakdown ScoreBreakdown
 }
 
+// ScoreBreakdown explains a candidate's selection score. Total is the sum of the
+// point, bonus and penalty fields; TopicNovelty (topics not yet in the contest
+// when it was picked) is not part of Total but steers the balanced strategy
+// toward topic variety.
+type ScoreBreakdown struct {
+	OverdueDays     int     `json:"overdue_days"`
+	OverduePoints   float64 `json:"overdue_points"`
+	Mastery         float64 `json:"mastery"`
+	MasteryPoints   float64 `json:"mastery_points"`
+	RecentFailBonus float64 `json:"recent_fail_bonus"`
+	StrategyBonus   float64 `json:"strategy_bonus"`
+	// Cooldown penalties (<= 0) push back problems the user has just seen.
+	RecentContestPenalty float64 `json:"recent_contest_penalty"`
+	RecentReviewPenalty  float64 `json:"recent_review_penalty"`
+	TopicNovelty         int     `json:"topic_novelty"`
+	Total                float64 `json:"total"`
+}
+
+// pick is a selected problem together with why it was selected.
+type pick struct {
+	problems.Problem
+	Breakdown ScoreBreakdown
+}
+
+// seedScoreBand is how far below the best remaining score a candidate may be and
+// still be drawn when a seed asks for variety.
+const seedScoreBand = 10.0
+
+// Cooldown penalties. They are large enough to sink a just-seen problem below
+// typical fresh candidates without excluding it when nothing else is eligible.
+const (
+	recentContestPenalty = 60.0
+	recentReviewPenalty  = 40.0
+)
+
 func clamp(v, lo, hi float64) float64 {
 	if v < lo {
 		return lo
@@ -25,15 +62,6 @@ func clamp(v, lo, hi float64) float64 {
 	return v
 }
 
-func masteryScore(reps int, ease float64, overdueDays int) float64 {
-	overduePenalty := float64(overdueDays * 2)
-	if overduePenalty > 30 {
-		overduePenalty = 30
-	}
-	m := 20*math.Log2(float64(reps)+1) + 25*(ease-1.3) - overduePenalty
-	return clamp(m, 0, 100)
-}
-
 func overdueDays(now time.Time, dueAt time.Time) int {
 	if now.Before(dueAt) {
 		return 0
@@ -62,12 +90,84 @@ type GenerateParams struct {
 	Strategy        string        `json:"strategy"`
 	DurationMinutes int           `json:"duration_minutes"`
 	DifficultyMix   DifficultyMix `json:"difficulty_mix"`
+
+	// Preset names a built-in or saved preset whose values fill any field above
+	// (and the scope below) that the request leaves empty.
+	Preset string `json:"preset,omitempty"`
+	// TargetMinutes sets per-difficulty item budgets instead of an even split.
+	TargetMinutes *TargetMinutes `json:"target_minutes,omitempty"`
+
+	// Candidate scope. Empty values leave the whole active library eligible.
+	ListID             string   `json:"list_id,omitempty"`
+	IncludeTopics      []string `json:"include_topics,omitempty"`
+	ExcludeTopics      []string `json:"exclude_topics,omitempty"`
+	Platforms          []string `json:"platforms,omitempty"`
+	NeverAttemptedOnly bool     `json:"never_attempted_only,omitempty"`
+
+	// Seed, when set, draws randomly among near-equal top scores (within
+	// seedScoreBand) so repeated contests vary; the same seed gives the same contest.
+	Seed *int64 `json:"seed,omitempty"`
+
+	// listProblemIDs is the resolved membership of ListID (nil when unscoped).
+	listProblemIDs map[string]bool
+	// recentContestProblemIDs holds problems from the user's last N contests and
+	// reviewCooldown the window in which a review counts as recent (user settings).
+	recentContestProblemIDs map[string]bool
+	reviewCooldown          time.Duration
+	// recentGrades feeds the mastery estimate (problem id -> grades, newest first).
+	recentGrades map[string][]int
 }
 
 func (p GenerateParams) totalCount() int {
 	return p.DifficultyMix.Easy + p.DifficultyMix.Medium + p.DifficultyMix.Hard
 }
 
+func (p GenerateParams) scoped() bool {
+	return p.ListID != "" || len(p.IncludeTopics) > 0 || len(p.ExcludeTopics) > 0 || len(p.Platforms) > 0 || p.NeverAttemptedOnly
+}
+
+// inScope reports whether a candidate passes the list/topic/platform/attempt filters.
+func (p GenerateParams) inScope(c problems.ProblemWithState) bool {
+	if p.listProblemIDs != nil && !p.listProblemIDs[c.ID] {
+		return false
+	}
+	if p.NeverAttemptedOnly && c.State.LastReviewAt != nil {
+		return false
+	}
+	if len(p.Platforms) > 0 {
+		platform := strings.TrimSpace(strings.ToLower(c.Platform))
+		match := false
+		for _, want := range p.Platforms {
+			if strings.TrimSpace(strings.ToLower(want)) == platform {
+				match = true
+				break
+			}
+		}
+		if !match {
+			return false
+		}
+	}
+	ts := topicSet(c.Topics)
+	if len(p.IncludeTopics) > 0 {
+		match := false
+		for t := range topicSet(p.IncludeTopics) {
+			if _, ok := ts[t]; ok {
+				match = true
+				break
+			}
+		}
+		if !match {
+			return false
+		}
+	}
+	for t := range topicSet(p.ExcludeTopics) {
+		if _, ok := ts[t]; ok {
+			return false
+		}
+	}
+	return true
+}
+
 func normalizeDifficulty(d string) string {
 	d = strings.TrimSpace(strings.ToLower(d))
 	switch d {
@@ -90,8 +190,13 @@ func topicSet(topics []string) map[string]struct{} {
 	return out
 }
 
-func pickContestProblems(now time.Time, params GenerateParams, all []problems.ProblemWithState) []problems.Problem {
-	// Deterministic scoring and selection so tests are stable.
+func pickContestProblems(now time.Time, params GenerateParams, all []problems.ProblemWithState) []pick {
+	// Deterministic scoring and selection so tests are stable; a seed makes the
+	// tie-band draw reproducible too.
+	var rng *rand.Rand
+	if params.Seed != nil {
+		rng = rand.New(rand.NewSource(*params.Seed))
+	}
 	strategy := strings.TrimSpace(strings.ToLower(params.Strategy))
 	if strategy == "" {
 		strategy = "balanced"
@@ -104,28 +209,46 @@ func pickContestProblems(now time.Time, params GenerateParams, all []problems.Pr
 	}
 
 	for _, p := range all {
+		if !params.inScope(p) {
+			continue
+		}
 		d := normalizeDifficulty(p.Difficulty)
 		if d == "unknown" {
 			continue
 		}
 		od := overdueDays(now, p.State.DueAt)
-		mastery := masteryScore(p.State.Reps, p.State.Ease, od)
+		m := mastery.Problem(p.State, params.recentGrades[p.ID], now).Score
 		recentFail := 0.0
 		if hasRecentFail(p.State, now) {
 			recentFail = 15
 		}
 		// Base priority from AGENTS.md.
-		score := float64(3*od) + 2*(100-mastery) + recentFail
+		b := ScoreBreakdown{
+			OverdueDays:     od,
+			OverduePoints:   float64(3 * od),
+			Mastery:         m,
+			MasteryPoints:   2 * (100 - m),
+			RecentFailBonus: recentFail,
+		}
 		switch strategy {
 		case "due-heavy":
-			score += float64(2 * od)
+			b.StrategyBonus = float64(2 * od)
 		case "weakness":
-			score += float64(100 - mastery)
+			b.StrategyBonus = 100 - m
+		}
+		if params.recentContestProblemIDs[p.ID] {
+			b.RecentContestPenalty = -recentContestPenalty
+		}
+		if params.reviewCooldown > 0 && p.State.LastReviewAt != nil && now.Sub(*p.State.LastReviewAt) < params.reviewCooldown {
+			b.RecentReviewPenalty = -recentReviewPenalty
 		}
+		b.Total = b.OverduePoints + b.MasteryPoints + b.RecentFailBonus + b.StrategyBonus +
+			b.RecentContestPenalty + b.RecentReviewPenalty
 		buckets[d] = append(buckets[d], candidate{
-			problem: p.Problem,
-			state:   p.State,
-			score:   score,
+			problem:   p.Problem,
+			state:     p.State,
+			score:     b.Total,
+			breakdown: b,
 		})
 	}
 
@@ -144,34 +267,54 @@ func pickContestProblems(now time.Time, params GenerateParams, all []problems.Pr
 		"hard":   params.DifficultyMix.Hard,
 	}
 	usedTopics := map[string]int{}
-	chosen := make([]problems.Problem, 0, params.totalCount())
+	chosen := make([]pick, 0, params.totalCount())
+
+	novelty := func(c candidate) int {
+		n := 0
+		for t := range topicSet(c.problem.Topics) {
+			if usedTopics[t] == 0 {
+				n++
+			}
+		}
+		return n
+	}
 
 	pickFrom := func(bucket string) {
 		for want[bucket] > 0 && len(buckets[bucket]) > 0 {
+			cands := buckets[bucket]
 			bestIdx := 0
-			if strategy == "balanced" {
-				// Greedy: prefer a candidate that introduces new topics.
-				for i := 0; i < len(buckets[bucket]); i++ {
-					ts := topicSet(buckets[bucket][i].problem.Topics)
-					newTopics := 0
-					for t := range ts {
-						if usedTopics[t] == 0 {
-							newTopics++
-						}
+			if rng != nil {
+				// Draw among the near-top band, keeping the balanced preference for
+				// candidates that introduce new topics when the band has any.
+				band := make([]int, 0)
+				fresh := make([]int, 0)
+				for i := 0; i < len(cands) && cands[i].score >= cands[0].score-seedScoreBand; i++ {
+					band = append(band, i)
+					if novelty(cands[i]) > 0 {
+						fresh = append(fresh, i)
 					}
-					if newTopics > 0 {
+				}
+				if strategy == "balanced" && len(fresh) > 0 {
+					band = fresh
+				}
+				bestIdx = band[rng.Intn(len(band))]
+			} else if strategy == "balanced" {
+				// Greedy: prefer a candidate that introduces new topics.
+				for i := 0; i < len(cands); i++ {
+					if novelty(cands[i]) > 0 {
 						bestIdx = i
 						break
 					}
 				}
 			}
-			c := buckets[bucket][bestIdx]
+			c := cands[bestIdx]
+			c.breakdown.TopicNovelty = novelty(c)
 			// Remove selected.
-			buckets[bucket] = append(buckets[bucket][:bestIdx], buckets[bucket][bestIdx+1:]...)
+			buckets[bucket] = append(cands[:bestIdx], cands[bestIdx+1:]...)
 			for t := range topicSet(c.problem.Topics) {
 				usedTopics[t]++
 			}
-			chosen = append(chosen, c.problem)
+			chosen = append(chosen, pick{Problem: c.problem, Breakdown: c.breakdown})
 			want[bucket]--
 		}
 	}
@@ -0,0 +1,102 @@
+package contests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/problems"
+)
+
+func TestPickContestProblemsRespectsScope(t *testing.T) {
+	now := time.Date(2026, 2, 10, 12, 0, 0, 0, time.UTC)
+	reviewed := now.Add(-48 * time.Hour)
+	mk := func(id, difficulty string, topics []string, lastReview *time.Time) problems.ProblemWithState {
+		return problems.ProblemWithState{
+			Problem: problems.Problem{ID: id, Platform: "LeetCode", Difficulty: difficulty, Topics: topics},
+			State:   problems.UserState{Ease: 2.5, DueAt: now, LastReviewAt: lastReview},
+		}
+	}
+	all := []problems.ProblemWithState{
+		mk("graph-in-list", "medium", []string{"graphs"}, nil),
+		mk("graph-reviewed", "medium", []string{"graphs"}, &reviewed),
+		mk("graph-off-list", "medium", []string{"Graphs"}, nil),
+		mk("dp-in-list", "medium", []string{"dp"}, nil),
+	}
+	params := GenerateParams{
+		DifficultyMix:      DifficultyMix{Medium: 3},
+		ListID:             "blind75",
+		IncludeTopics:      []string{"graphs"},
+		NeverAttemptedOnly: true,
+		listProblemIDs:     map[string]bool{"graph-in-list": true, "graph-reviewed": true, "dp-in-list": true},
+	}
+	chosen := pickContestProblems(now, params, all)
+	if len(chosen) != 1 || chosen[0].ID != "graph-in-list" {
+		t.Fatalf("expected only graph-in-list, got %+v", chosen)
+	}
+}
+
+func TestPickContestProblemsSeedIsReproducibleAndExplains(t *testing.T) {
+	now := time.Date(2026, 2, 10, 12, 0, 0, 0, time.UTC)
+	all := make([]problems.ProblemWithState, 0, 8)
+	for i := 0; i < 8; i++ {
+		all = append(all, problems.ProblemWithState{
+			Problem: problems.Problem{ID: string(rune('a' + i)), Difficulty: "medium", Topics: []string{"arrays"}},
+			State:   problems.UserState{Ease: 2.5, DueAt: now},
+		})
+	}
+	pickIDs := func(seed *int64) string {
+		out := ""
+		for _, p := range pickContestProblems(now, GenerateParams{DifficultyMix: DifficultyMix{Medium: 3}, Seed: seed}, all) {
+			out += p.ID
+		}
+		return out
+	}
+	if got := pickIDs(nil); got != "abc" {
+		t.Fatalf("expected deterministic order without a seed, got %q", got)
+	}
+	s1, s2 := int64(1), int64(2)
+	if pickIDs(&s1) != pickIDs(&s1) {
+		t.Fatalf("expected the same seed to give the same contest")
+	}
+	varied := false
+	for s := int64(2); s < 20 && !varied; s++ {
+		seed := s
+		varied = pickIDs(&seed) != pickIDs(&s1)
+	}
+	if !varied {
+		t.Fatalf("expected different seeds to vary the contest (seed 2: %q)", pickIDs(&s2))
+	}
+
+	chosen := pickContestProblems(now, GenerateParams{DifficultyMix: DifficultyMix{Medium: 2}, Strategy: "weakness"}, all)
+	b := chosen[0].Breakdown
+	if b.Total != b.OverduePoints+b.MasteryPoints+b.RecentFailBonus+b.StrategyBonus || b.StrategyBonus == 0 {
+		t.Fatalf("unexpected breakdown: %+v", b)
+	}
+	if b.TopicNovelty != 1 || chosen[1].Breakdown.TopicNovelty != 0 {
+		t.Fatalf("expected novelty 1 then 0, got %d and %d", b.TopicNovelty, chosen[1].Breakdown.TopicNovelty)
+	}
+}
+
+func TestPickContestProblemsPenalizesRecentlySeen(t *testing.T) {
+	now := time.Date(2026, 2, 10, 12, 0, 0, 0, time.UTC)
+	reviewedJustNow := now.Add(-2 * time.Hour)
+	mk := func(id string, lastReview *time.Time) problems.ProblemWithState {
+		return problems.ProblemWithState{
+			Problem: problems.Problem{ID: id, Difficulty: "medium"},
+			State:   problems.UserState{Ease: 2.5, DueAt: now, LastReviewAt: lastReview},
+		}
+	}
+	all := []problems.ProblemWithState{mk("in-last-contest", nil), mk("just-reviewed", &reviewedJustNow), mk("fresh", nil)}
+	params := GenerateParams{
+		DifficultyMix:           DifficultyMix{Medium: 3},
+		recentContestProblemIDs: map[string]bool{"in-last-contest": true},
+		reviewCooldown:          12 * time.Hour,
+	}
+	chosen := pickContestProblems(now, params, all)
+	if len(chosen) != 3 || chosen[0].ID != "fresh" || chosen[1].ID != "just-reviewed" || chosen[2].ID != "in-last-contest" {
+		t.Fatalf("expected fresh, just-reviewed, in-last-contest; got %v", []string{chosen[0].ID, chosen[1].ID, chosen[2].ID})
+	}
+	if chosen[2].Breakdown.RecentContestPenalty != -recentContestPenalty || chosen[1].Breakdown.RecentReviewPenalty != -recentReviewPenalty {
+		t.Fatalf("expected penalties in breakdowns: %+v %+v", chosen[1].Breakdown, chosen[2].Breakdown)
+	}
+}
@@ -0,0 +1,57 @@
+package contests
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Contest lifecycle:
+//
+//	generated --start--> in_progress --complete--> completed
+//	                     in_progress --expire----> expired
+//
+// in_progress is the "running" state and completed the "finished" one; the stored
+// timestamps (started_at, completed_at, expired_at) are the source of truth and
+// deriveStatus maps them onto these names.
+const (
+	actionStart    = "start"
+	actionComplete = "complete"
+	actionExpire   = "expire"
+)
+
+var transitions = map[string]map[string]string{
+	StatusGenerated:  {actionStart: StatusInProgress},
+	StatusInProgress: {actionComplete: StatusCompleted, actionExpire: StatusExpired},
+}
+
+// ErrInvalidTransition is returned when an action is not allowed from the contest's current status.
+var ErrInvalidTransition = errors.New("invalid contest state transition")
+
+// ErrContestNotRunning is returned when results are submitted outside the in_progress state.
+var ErrContestNotRunning = errors.New("contest is not in progress")
+
+func transition(from string, action string) (string, error) {
+	if to, ok := transitions[from][action]; ok {
+		return to, nil
+	}
+	return "", fmt.Errorf("%w: cannot %s a contest that is %s", ErrInvalidTransition, action, from)
+}
+
+// deadline is when the contest's time budget runs out, or nil if it has not started.
+func deadline(c Contest) *time.Time {
+	if c.StartedAt == nil {
+		return nil
+	}
+	d := c.StartedAt.Add(time.Duration(c.DurationMinutes) * time.Minute)
+	return &d
+}
+
+// splitElapsed divides the time since lapStart evenly across n results that were
+// submitted without a time_spent_sec, so a batch does not double count the same span.
+func splitElapsed(lapStart time.Time, now time.Time, n int) int {
+	if n <= 0 || !now.After(lapStart) {
+		return 0
+	}
+	return int(now.Sub(lapStart).Seconds()) / n
+}
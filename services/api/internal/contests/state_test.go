@@ -0,0 +1,36 @@
+package contests
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTransitionRules(t *testing.T) {
+	if to, err := transition(StatusGenerated, actionStart); err != nil || to != StatusInProgress {
+		t.Fatalf("expected generated -> in_progress, got %q %v", to, err)
+	}
+	if to, err := transition(StatusInProgress, actionComplete); err != nil || to != StatusCompleted {
+		t.Fatalf("expected in_progress -> completed, got %q %v", to, err)
+	}
+	for _, tc := range []struct{ from, action string }{
+		{StatusGenerated, actionComplete},
+		{StatusInProgress, actionStart},
+		{StatusCompleted, actionStart},
+		{StatusExpired, actionComplete},
+	} {
+		if _, err := transition(tc.from, tc.action); !errors.Is(err, ErrInvalidTransition) {
+			t.Fatalf("expected invalid transition for %s from %s, got %v", tc.action, tc.from, err)
+		}
+	}
+}
+
+func TestSplitElapsed(t *testing.T) {
+	start := time.Date(2026, 2, 10, 12, 0, 0, 0, time.UTC)
+	if got := splitElapsed(start, start.Add(10*time.Minute), 2); got != 300 {
+		t.Fatalf("expected 300s each, got %d", got)
+	}
+	if got := splitElapsed(start, start.Add(-time.Minute), 1); got != 0 {
+		t.Fatalf("expected 0 for clock skew, got %d", got)
+	}
+}
@@ -0,0 +1,195 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/activity"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/testutil"
+)
+
+// activityRow is one daily_user_activity row with the verdicts as flags.
+type activityRow struct {
+	Date             string
+	Reviews          int
+	TimeSpentSec     int
+	GoalMet          bool
+	Frozen           bool
+	ContestsFinished int
+	ContestProblems  int
+	ContestSolved    int
+	ContestGraded    int
+	ContestGradeSum  int
+	ContestTimeSec   int
+}
+
+func loadActivity(t *testing.T, pool *pgxpool.Pool) ([]activityRow, map[string][]int) {
+	t.Helper()
+	ctx := context.Background()
+	rows, err := pool.Query(ctx, `
+		SELECT local_date::text, reviews, time_spent_sec, goal_met_at IS NOT NULL, frozen_at IS NOT NULL,
+		       contests_finished, contest_problems, contest_solved, contest_graded, contest_grade_sum, contest_time_sec
+		FROM daily_user_activity
+		ORDER BY local_date
+	`)
+	if err != nil {
+		t.Fatalf("load activity: %v", err)
+	}
+	defer rows.Close()
+	var days []activityRow
+	for rows.Next() {
+		var d activityRow
+		if err := rows.Scan(&d.Date, &d.Reviews, &d.TimeSpentSec, &d.GoalMet, &d.Frozen,
+			&d.ContestsFinished, &d.ContestProblems, &d.ContestSolved, &d.ContestGraded, &d.ContestGradeSum, &d.ContestTimeSec); err != nil {
+			t.Fatalf("scan activity: %v", err)
+		}
+		days = append(days, d)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("load activity: %v", err)
+	}
+
+	grades := map[string][]int{}
+	gradeRows, err := pool.Query(ctx, `SELECT problem_id::text, recent_grades FROM user_problem_state`)
+	if err != nil {
+		t.Fatalf("load recent grades: %v", err)
+	}
+	defer gradeRows.Close()
+	for gradeRows.Next() {
+		var id string
+		var g []int32
+		if err := gradeRows.Scan(&id, &g); err != nil {
+			t.Fatalf("scan recent grades: %v", err)
+		}
+		grades[id] = make([]int, len(g))
+		for i, v := range g {
+			grades[id][i] = int(v)
+		}
+	}
+	if err := gradeRows.Err(); err != nil {
+		t.Fatalf("load recent grades: %v", err)
+	}
+	return days, grades
+}
+
+func TestDailyActivityMatchesRebuild(t *testing.T) {
+	dbURL := testutil.RequireDBURL(t)
+	testutil.MigrateUp(t, dbURL)
+	pool := testutil.OpenPool(t, dbURL)
+	testutil.ResetDB(t, pool)
+
+	r := newTestRouter(pool)
+	access := registerUser(t, r, "activity@example.com", "America/New_York")
+	settingsResp := doJSON(t, r, "PATCH", "/api/v1/users/me/settings", map[string]any{
+		"daily_goal_reviews": 3,
+		"daily_goal_minutes": 0,
+	}, access)
+	if settingsResp.Code != http.StatusOK {
+		t.Fatalf("settings status=%d body=%s", settingsResp.Code, settingsResp.Body.String())
+	}
+	first := createProblem(t, r, access, map[string]any{
+		"url":        "https://leetcode.com/problems/two-sum/",
+		"title":      "Two Sum",
+		"difficulty": "easy",
+	})
+	second := createProblem(t, r, access, map[string]any{
+		"url":        "https://leetcode.com/problems/valid-parentheses/",
+		"title":      "Valid Parentheses",
+		"difficulty": "easy",
+	})
+
+	// More reviews than recent_grades keeps, so the array is cut to its length.
+	for i := 0; i < 12; i++ {
+		resp := doJSON(t, r, "POST", "/api/v1/reviews/", map[string]any{
+			"problem_id":     first,
+			"grade":          i % 5,
+			"time_spent_sec": 60 + i,
+			"source":         "daily_review",
+		}, access)
+		if resp.Code != http.StatusOK {
+			t.Fatalf("post review %d status=%d body=%s", i, resp.Code, resp.Body.String())
+		}
+	}
+
+	genResp := doJSON(t, r, "POST", "/api/v1/contests/generate", map[string]any{
+		"duration_minutes": 30,
+		"difficulty_mix":   map[string]any{"easy": 2},
+	}, access)
+	if genResp.Code != http.StatusCreated {
+		t.Fatalf("generate status=%d body=%s", genResp.Code, genResp.Body.String())
+	}
+	var contest map[string]any
+	_ = json.Unmarshal(genResp.Body.Bytes(), &contest)
+	contestID := contest["id"].(string)
+	if resp := doJSON(t, r, "POST", "/api/v1/contests/"+contestID+"/start", nil, access); resp.Code != http.StatusOK {
+		t.Fatalf("start status=%d body=%s", resp.Code, resp.Body.String())
+	}
+	submit := func(grade int, spent int, solved bool) {
+		t.Helper()
+		resp := doJSON(t, r, "POST", "/api/v1/contests/"+contestID+"/results", map[string]any{
+			"results": []map[string]any{{"problem_id": second, "grade": grade, "time_spent_sec": spent, "solved_flag": solved}},
+		}, access)
+		if resp.Code != http.StatusOK {
+			t.Fatalf("submit status=%d body=%s", resp.Code, resp.Body.String())
+		}
+	}
+	// The resubmit replaces the first result in the rollup instead of adding to it.
+	submit(1, 300, false)
+	submit(4, 420, true)
+	if resp := doJSON(t, r, "POST", "/api/v1/contests/"+contestID+"/complete", nil, access); resp.Code != http.StatusOK {
+		t.Fatalf("complete status=%d body=%s", resp.Code, resp.Body.String())
+	}
+
+	// A freeze on an earlier day, and a goal raised after today was met: both
+	// verdicts have to survive the rebuild.
+	if _, err := pool.Exec(context.Background(), `
+		INSERT INTO daily_user_activity (user_id, local_date, frozen_at)
+		SELECT id, (now() AT TIME ZONE 'America/New_York')::date - 3, now() FROM users
+	`); err != nil {
+		t.Fatalf("insert freeze: %v", err)
+	}
+	settingsResp = doJSON(t, r, "PATCH", "/api/v1/users/me/settings", map[string]any{"daily_goal_reviews": 50}, access)
+	if settingsResp.Code != http.StatusOK {
+		t.Fatalf("settings status=%d body=%s", settingsResp.Code, settingsResp.Body.String())
+	}
+
+	days, grades := loadActivity(t, pool)
+	if len(days) != 2 || !days[0].Frozen || days[0].Reviews != 0 {
+		t.Fatalf("expected the frozen day and today, got %+v", days)
+	}
+	today := days[1]
+	want := activityRow{
+		Date: today.Date, Reviews: 13, TimeSpentSec: 12*60 + 66 + 300, GoalMet: true,
+		ContestsFinished: 1, ContestProblems: 1, ContestSolved: 1, ContestGraded: 1, ContestGradeSum: 4, ContestTimeSec: 420,
+	}
+	if today != want {
+		t.Fatalf("today = %+v, want %+v", today, want)
+	}
+	if g := grades[first]; fmt.Sprint(g) != "[1 0 4 3 2 1 0 4 3 2]" {
+		t.Fatalf("recent grades for the reviewed problem = %v", g)
+	}
+	// The contest review is logged once, from the first submission.
+	if g := grades[second]; fmt.Sprint(g) != "[1]" {
+		t.Fatalf("recent grades for the contest problem = %v", g)
+	}
+
+	var userID string
+	if err := pool.QueryRow(context.Background(), `SELECT id::text FROM users`).Scan(&userID); err != nil {
+		t.Fatalf("load user: %v", err)
+	}
+	if err := activity.NewRepository(pool).Rebuild(context.Background(), userID); err != nil {
+		t.Fatalf("rebuild: %v", err)
+	}
+	rebuiltDays, rebuiltGrades := loadActivity(t, pool)
+	if !reflect.DeepEqual(rebuiltDays, days) {
+		t.Fatalf("rebuilt rollup differs:\n got %+v\nwant %+v", rebuiltDays, days)
+	}
+	if !reflect.DeepEqual(rebuiltGrades, grades) {
+		t.Fatalf("rebuilt recent grades differ:\n got %v\nwant %v", rebuiltGrades, grades)
+	}
+}
@@ -14,8 +14,12 @@ import (
 	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/calendar"
 	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/contests"
 	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/docs"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/interviews"
 	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/lists"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/notes"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/plans"
 	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/problems"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/recommendations"
 	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/reviews"
 	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/stats"
 	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/testutil"
@@ -80,15 +84,19 @@ func newTestRouter(pool *pgxpool.Pool) http.Handler {
 
 	problemsRepo := problems.NewRepository(pool)
 	problemsHandler := problems.NewHandler(problemsRepo, userRepo)
+	notesHandler := notes.NewHandler(notes.NewRepository(pool, 50))
 	reviewsHandler := reviews.NewHandler(pool, userRepo, problemsRepo)
 	listsRepo := lists.NewRepository(pool)
 	listsHandler := lists.NewHandler(pool, listsRepo, problemsRepo, userRepo)
+	plansHandler := plans.NewHandler(pool, plans.NewRepository(pool), listsRepo, problemsRepo, userRepo)
+	interviewsHandler := interviews.NewHandler(pool, interviews.NewRepository(pool), problemsRepo, userRepo)
 	contestsRepo := contests.NewRepository(pool)
-	contestsHandler := contests.NewHandler(pool, contestsRepo, problemsRepo, userRepo)
+	contestsHandler := contests.NewHandler(pool, contestsRepo, problemsRepo, listsRepo, userRepo)
 	statsHandler := stats.NewHandler(pool, userRepo)
+	recommendationsHandler := recommendations.NewHandler(pool)
 
 	tokenRepo := calendar.NewTokenRepo(pool)
-	calendarHandler := calendar.NewHandler(tokenRepo, userRepo, "")
+	calendarHandler := calendar.NewHandler(tokenRepo, userRepo, listsRepo, "")
 	docsHandler := docs.NewHandler("")
 
 	r := chi.NewRouter()
@@ -113,9 +121,12 @@ func newTestRouter(pool *pgxpool.Pool) http.Handler {
 				r.Post("/", problemsHandler.Create)
 				r.Get("/", problemsHandler.List)
 				r.Patch("/{id}", problemsHandler.Patch)
+				r.Get("/{id}/notes", notesHandler.Get)
+				r.Put("/{id}/notes", notesHandler.Put)
 			})
 			r.Route("/reviews", func(r chi.Router) {
 				r.Get("/due", reviewsHandler.Due)
+				r.Get("/history.csv", statsHandler.ReviewHistoryCSV)
 				r.Post("/", reviewsHandler.Post)
 			})
 			r.Route("/lists", func(r chi.Router) {
@@ -123,21 +134,59 @@ func newTestRouter(pool *pgxpool.Pool) http.Handler {
 				r.Get("/", listsHandler.List)
 				r.Post("/import", listsHandler.Import)
 				r.Get("/{id}", listsHandler.Get)
+				r.Patch("/{id}", listsHandler.Patch)
 				r.Post("/{id}/items", listsHandler.AddItem)
 				r.Patch("/{id}/items/reorder", listsHandler.Reorder)
 			})
+			r.Route("/interviews", func(r chi.Router) {
+				r.Post("/", interviewsHandler.Create)
+				r.Get("/", interviewsHandler.List)
+				r.Get("/rubrics", interviewsHandler.RubricHistory)
+				r.Get("/{id}", interviewsHandler.Get)
+				r.Post("/{id}/start", interviewsHandler.Start)
+				r.Post("/{id}/cancel", interviewsHandler.Cancel)
+				r.Post("/{id}/rubric", interviewsHandler.SubmitRubric)
+			})
+			r.Route("/plans", func(r chi.Router) {
+				r.Post("/", plansHandler.Create)
+				r.Get("/", plansHandler.List)
+				r.Get("/{id}", plansHandler.Get)
+				r.Post("/{id}/rebalance", plansHandler.Rebalance)
+				r.Delete("/{id}", plansHandler.Delete)
+			})
 			r.Route("/contests", func(r chi.Router) {
+				r.Get("/", contestsHandler.List)
 				r.Post("/generate", contestsHandler.Generate)
+				r.Post("/join", contestsHandler.Join)
+				r.Get("/presets", contestsHandler.ListPresets)
+				r.Post("/presets", contestsHandler.CreatePreset)
+				r.Delete("/presets/{id}", contestsHandler.DeletePreset)
 				r.Get("/{id}", contestsHandler.Get)
+				r.Delete("/{id}", contestsHandler.Delete)
 				r.Post("/{id}/start", contestsHandler.Start)
 				r.Post("/{id}/complete", contestsHandler.Complete)
 				r.Post("/{id}/results", contestsHandler.SubmitResults)
+				r.Post("/{id}/events", contestsHandler.PostEvent)
+				r.Get("/{id}/timeline", contestsHandler.Timeline)
+				r.Post("/{id}/invite", contestsHandler.Invite)
+				r.Get("/{id}/leaderboard", contestsHandler.Leaderboard)
 			})
 			r.Route("/stats", func(r chi.Router) {
 				r.Get("/overview", statsHandler.Overview)
 				r.Get("/topics", statsHandler.Topics)
+				r.Get("/mastery", statsHandler.Mastery)
 				r.Get("/streaks", statsHandler.Streaks)
+				r.Post("/streaks/freeze", statsHandler.Freeze)
+				r.Get("/goals", statsHandler.Goals)
+				r.Get("/retention", statsHandler.Retention)
+				r.Get("/grades", statsHandler.Grades)
+				r.Get("/time-spent", statsHandler.TimeSpent)
+				r.Get("/heatmap", statsHandler.Heatmap)
+				r.Get("/daily.csv", statsHandler.DailyCSV)
+				r.Get("/report", statsHandler.Report)
 			})
+			r.Get("/notes/search", notesHandler.Search)
+			r.Get("/recommendations", recommendationsHandler.Get)
 		})
 	})
 	return r
@@ -159,3 +208,31 @@ func doJSON(t *testing.T, h http.Handler, method, path string, body any, bearer
 	h.ServeHTTP(rr, req)
 	return rr
 }
+
+// registerUser registers email with a test password and returns its access token.
+func registerUser(t *testing.T, h http.Handler, email string, timezone string) string {
+	t.Helper()
+	resp := doJSON(t, h, "POST", "/api/v1/auth/register", map[string]any{
+		"email":    email,
+		"password": "pass1234",
+		"timezone": timezone,
+	}, "")
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("register status=%d body=%s", resp.Code, resp.Body.String())
+	}
+	var tokens map[string]any
+	_ = json.Unmarshal(resp.Body.Bytes(), &tokens)
+	return tokens["access_token"].(string)
+}
+
+// createProblem adds a problem to the user's library and returns its id.
+func createProblem(t *testing.T, h http.Handler, access string, body map[string]any) string {
+	t.Helper()
+	resp := doJSON(t, h, "POST", "/api/v1/problems/", body, access)
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("create problem status=%d body=%s", resp.Code, resp.Body.String())
+	}
+	var p map[string]any
+	_ = json.Unmarshal(resp.Body.Bytes(), &p)
+	return p["id"].(string)
+}
@@ -0,0 +1,120 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/testutil"
+)
+
+func TestContestEventThenSubmitSchedulesOnce(t *testing.T) {
+	dbURL := testutil.RequireDBURL(t)
+	testutil.MigrateUp(t, dbURL)
+	pool := testutil.OpenPool(t, dbURL)
+	testutil.ResetDB(t, pool)
+
+	r := newTestRouter(pool)
+	access := registerUser(t, r, "contest@example.com", "America/New_York")
+	problemID := createProblem(t, r, access, map[string]any{
+		"url":        "https://leetcode.com/problems/two-sum/",
+		"title":      "Two Sum",
+		"difficulty": "easy",
+	})
+	hinted := createProblem(t, r, access, map[string]any{
+		"url":        "https://leetcode.com/problems/valid-parentheses/",
+		"title":      "Valid Parentheses",
+		"difficulty": "easy",
+	})
+
+	genResp := doJSON(t, r, "POST", "/api/v1/contests/generate", map[string]any{
+		"duration_minutes": 30,
+		"difficulty_mix":   map[string]any{"easy": 2},
+	}, access)
+	if genResp.Code != http.StatusCreated {
+		t.Fatalf("generate status=%d body=%s", genResp.Code, genResp.Body.String())
+	}
+	var contest map[string]any
+	_ = json.Unmarshal(genResp.Body.Bytes(), &contest)
+	contestID := contest["id"].(string)
+
+	if resp := doJSON(t, r, "POST", "/api/v1/contests/"+contestID+"/start", nil, access); resp.Code != http.StatusOK {
+		t.Fatalf("start status=%d body=%s", resp.Code, resp.Body.String())
+	}
+
+	type schedule struct {
+		reps  int
+		dueAt time.Time
+	}
+	load := func() schedule {
+		t.Helper()
+		var s schedule
+		if err := pool.QueryRow(context.Background(), `
+			SELECT reps, due_at FROM user_problem_state WHERE problem_id = $1
+		`, problemID).Scan(&s.reps, &s.dueAt); err != nil {
+			t.Fatalf("load state: %v", err)
+		}
+		return s
+	}
+	before := load()
+
+	evResp := doJSON(t, r, "POST", "/api/v1/contests/"+contestID+"/events", map[string]any{
+		"problem_id": problemID,
+		"type":       "solved",
+	}, access)
+	if evResp.Code != http.StatusCreated {
+		t.Fatalf("event status=%d body=%s", evResp.Code, evResp.Body.String())
+	}
+	afterEvent := load()
+	if afterEvent.reps != before.reps+1 || !afterEvent.dueAt.After(before.dueAt) {
+		t.Fatalf("expected the solved event to advance the schedule: before=%+v after=%+v", before, afterEvent)
+	}
+
+	submitResp := doJSON(t, r, "POST", "/api/v1/contests/"+contestID+"/results", map[string]any{
+		"results": []map[string]any{{"problem_id": problemID, "grade": 4}},
+	}, access)
+	if submitResp.Code != http.StatusOK {
+		t.Fatalf("submit status=%d body=%s", submitResp.Code, submitResp.Body.String())
+	}
+	afterSubmit := load()
+	if afterSubmit.reps != afterEvent.reps || !afterSubmit.dueAt.Equal(afterEvent.dueAt) {
+		t.Fatalf("expected the submit not to advance the schedule again: after event=%+v after submit=%+v", afterEvent, afterSubmit)
+	}
+
+	var logs int
+	if err := pool.QueryRow(context.Background(), `
+		SELECT count(*) FROM review_logs WHERE contest_id = $1
+	`, contestID).Scan(&logs); err != nil {
+		t.Fatalf("count review logs: %v", err)
+	}
+	if logs != 1 {
+		t.Fatalf("expected one contest review log, got %d", logs)
+	}
+
+	// Without a grade, a result needs a terminal event to derive it from.
+	noGrade := map[string]any{"results": []map[string]any{{"problem_id": hinted}}}
+	if resp := doJSON(t, r, "POST", "/api/v1/contests/"+contestID+"/results", noGrade, access); resp.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a result without grade or events, got %d body=%s", resp.Code, resp.Body.String())
+	}
+	for _, typ := range []string{"hint", "solved"} {
+		resp := doJSON(t, r, "POST", "/api/v1/contests/"+contestID+"/events", map[string]any{"problem_id": hinted, "type": typ}, access)
+		if resp.Code != http.StatusCreated {
+			t.Fatalf("%s event status=%d body=%s", typ, resp.Code, resp.Body.String())
+		}
+	}
+	if resp := doJSON(t, r, "POST", "/api/v1/contests/"+contestID+"/results", noGrade, access); resp.Code != http.StatusOK {
+		t.Fatalf("submit without grade status=%d body=%s", resp.Code, resp.Body.String())
+	}
+	var grade int
+	var solved bool
+	if err := pool.QueryRow(context.Background(), `
+		SELECT grade, solved_flag FROM contest_results WHERE contest_id = $1 AND problem_id = $2
+	`, contestID, hinted).Scan(&grade, &solved); err != nil {
+		t.Fatalf("load contest result: %v", err)
+	}
+	if grade != 3 || !solved {
+		t.Fatalf("expected the event-derived grade 3 (solved with a hint), got grade=%d solved=%v", grade, solved)
+	}
+}
@@ -0,0 +1,99 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/testutil"
+)
+
+func TestNotesSearchIsolationAndFilters(t *testing.T) {
+	dbURL := testutil.RequireDBURL(t)
+	testutil.MigrateUp(t, dbURL)
+	pool := testutil.OpenPool(t, dbURL)
+	testutil.ResetDB(t, pool)
+
+	r := newTestRouter(pool)
+	alice := registerUser(t, r, "alice@example.com", "UTC")
+	bob := registerUser(t, r, "bob@example.com", "UTC")
+
+	putNotes := func(access, problemID, text string) {
+		t.Helper()
+		resp := doJSON(t, r, "PUT", "/api/v1/problems/"+problemID+"/notes", map[string]any{
+			"content_json": map[string]any{
+				"type": "doc",
+				"content": []map[string]any{{
+					"type":    "paragraph",
+					"content": []map[string]any{{"type": "text", "text": text}},
+				}},
+			},
+		}, access)
+		if resp.Code != http.StatusOK {
+			t.Fatalf("put notes status=%d body=%s", resp.Code, resp.Body.String())
+		}
+	}
+	search := func(access string, params url.Values) []map[string]any {
+		t.Helper()
+		resp := doJSON(t, r, "GET", "/api/v1/notes/search?"+params.Encode(), nil, access)
+		if resp.Code != http.StatusOK {
+			t.Fatalf("search status=%d body=%s", resp.Code, resp.Body.String())
+		}
+		var out struct {
+			Results []map[string]any `json:"results"`
+		}
+		_ = json.Unmarshal(resp.Body.Bytes(), &out)
+		return out.Results
+	}
+
+	// Both users add the same problem and write notes that share a word.
+	twoSum := map[string]any{
+		"url":        "https://leetcode.com/problems/two-sum/",
+		"title":      "Two Sum",
+		"difficulty": "easy",
+		"topics":     []string{"Arrays", "Hash Table"},
+	}
+	aliceTwoSum := createProblem(t, r, alice, twoSum)
+	bobTwoSum := createProblem(t, r, bob, twoSum)
+	putNotes(alice, aliceTwoSum, "Use a hashmap of complements, alicesecret trick.")
+	putNotes(bob, bobTwoSum, "A hashmap works here too, bobsecret trick.")
+
+	aliceTrees := createProblem(t, r, alice, map[string]any{
+		"url":        "https://leetcode.com/problems/binary-tree-maximum-path-sum/",
+		"title":      "Binary Tree Maximum Path Sum",
+		"difficulty": "hard",
+		"topics":     []string{"Trees"},
+	})
+	putNotes(alice, aliceTrees, "Keep a hashmap-free recursion; hashmap not needed.")
+
+	results := search(alice, url.Values{"q": {"hashmap"}})
+	if len(results) != 2 {
+		t.Fatalf("alice hashmap results = %+v, want her two notes", results)
+	}
+	for _, res := range results {
+		if strings.Contains(res["snippet"].(string), "bobsecret") {
+			t.Fatalf("alice's search returned bob's note: %+v", res)
+		}
+	}
+	if got := search(alice, url.Values{"q": {"bobsecret"}}); len(got) != 0 {
+		t.Fatalf("alice found bob's note: %+v", got)
+	}
+	if got := search(bob, url.Values{"q": {"hashmap"}}); len(got) != 1 || !strings.Contains(got[0]["snippet"].(string), "bobsecret") {
+		t.Fatalf("bob hashmap results = %+v, want only his note", got)
+	}
+
+	// Topic is matched after normalization, difficulty case-insensitively.
+	byTopic := search(alice, url.Values{"q": {"hashmap"}, "topic": {" trees "}})
+	if len(byTopic) != 1 || byTopic[0]["problem_id"] != aliceTrees {
+		t.Fatalf("topic filter results = %+v, want only %s", byTopic, aliceTrees)
+	}
+	byDifficulty := search(alice, url.Values{"q": {"hashmap"}, "difficulty": {"Easy"}})
+	if len(byDifficulty) != 1 || byDifficulty[0]["problem_id"] != aliceTwoSum {
+		t.Fatalf("difficulty filter results = %+v, want only %s", byDifficulty, aliceTwoSum)
+	}
+	if got := search(alice, url.Values{"q": {"hashmap"}, "topic": {"trees"}, "difficulty": {"easy"}}); len(got) != 0 {
+		t.Fatalf("combined filters results = %+v, want none", got)
+	}
+}
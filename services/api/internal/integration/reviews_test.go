@@ -2,6 +2,7 @@ package integration
 
 import (
 	"bytes"
+	"encoding/csv"
 	"encoding/json"
 	"net/http"
 	"strings"
@@ -170,3 +171,65 @@ func TestEmptyListsReturnJSONArrays(t *testing.T) {
 		t.Fatalf("expected [] for due list, got: %s", dueResp.Body.String())
 	}
 }
+
+func TestReviewHistoryAndDailyCSV(t *testing.T) {
+	dbURL := testutil.RequireDBURL(t)
+	testutil.MigrateUp(t, dbURL)
+	pool := testutil.OpenPool(t, dbURL)
+	testutil.ResetDB(t, pool)
+
+	r := newTestRouter(pool)
+	access := registerUser(t, r, "csv@example.com", "America/New_York")
+	problemID := createProblem(t, r, access, map[string]any{
+		"url":        "https://leetcode.com/problems/two-sum/",
+		"title":      "Two Sum",
+		"platform":   "leetcode",
+		"difficulty": "easy",
+		"topics":     []string{"array", "hashmap"},
+	})
+	createProblem(t, r, access, map[string]any{
+		"url":    "https://leetcode.com/problems/valid-parentheses/",
+		"title":  "Valid Parentheses",
+		"topics": []string{"stack"},
+	})
+
+	reviewResp := doJSON(t, r, "POST", "/api/v1/reviews/", map[string]any{
+		"problem_id":     problemID,
+		"grade":          3,
+		"time_spent_sec": 600,
+		"source":         "daily_review",
+	}, access)
+	if reviewResp.Code != http.StatusOK {
+		t.Fatalf("post review status=%d body=%s", reviewResp.Code, reviewResp.Body.String())
+	}
+
+	historyResp := doJSON(t, r, "GET", "/api/v1/reviews/history.csv?topic=Array", nil, access)
+	if historyResp.Code != http.StatusOK || !strings.HasPrefix(historyResp.Header().Get("Content-Type"), "text/csv") {
+		t.Fatalf("history csv status=%d content-type=%s", historyResp.Code, historyResp.Header().Get("Content-Type"))
+	}
+	history, err := csv.NewReader(historyResp.Body).ReadAll()
+	if err != nil || len(history) != 2 {
+		t.Fatalf("expected a header and one review, got %v (%v)", history, err)
+	}
+	if history[0][0] != "review_id" || history[1][3] != problemID || history[1][8] != "3" || history[1][9] != "600" || history[1][10] != "daily_review" {
+		t.Fatalf("unexpected review row: %v", history)
+	}
+
+	stackResp := doJSON(t, r, "GET", "/api/v1/reviews/history.csv?topic=stack", nil, access)
+	stack, err := csv.NewReader(stackResp.Body).ReadAll()
+	if stackResp.Code != http.StatusOK || err != nil || len(stack) != 1 {
+		t.Fatalf("expected only the header for an unreviewed topic, got status=%d %v (%v)", stackResp.Code, stack, err)
+	}
+
+	dailyResp := doJSON(t, r, "GET", "/api/v1/stats/daily.csv?window_days=3", nil, access)
+	if dailyResp.Code != http.StatusOK {
+		t.Fatalf("daily csv status=%d body=%s", dailyResp.Code, dailyResp.Body.String())
+	}
+	daily, err := csv.NewReader(dailyResp.Body).ReadAll()
+	if err != nil || len(daily) != 4 {
+		t.Fatalf("expected a header and three days, got %v (%v)", daily, err)
+	}
+	if daily[1][1] != "0" || daily[3][1] != "1" || daily[3][2] != "600" {
+		t.Fatalf("expected only today to have the review, got %v", daily)
+	}
+}
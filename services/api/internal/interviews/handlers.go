@@ -0,0 +1,365 @@
+package interviews
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/activity"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/httpx"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/problems"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/reqctx"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/scheduler"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/users"
+)
+
+type Handler struct {
+	pool     *pgxpool.Pool
+	repo     *Repository
+	problems *problems.Repository
+	users    *users.Repository
+}
+
+func NewHandler(pool *pgxpool.Pool, repo *Repository, problemsRepo *problems.Repository, usersRepo *users.Repository) *Handler {
+	return &Handler{pool: pool, repo: repo, problems: problemsRepo, users: usersRepo}
+}
+
+type createRequest struct {
+	CandidateEmail string `json:"candidate_email"`
+	ProblemID      string `json:"problem_id"`
+	Difficulty     string `json:"difficulty"`
+	Topic          string `json:"topic"`
+}
+
+// Create schedules a mock interview with the caller as interviewer. The problem is
+// either picked explicitly (it must be in the candidate's library) or generated
+// from the candidate's library, optionally filtered by difficulty and topic.
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httpx.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID, ok := reqctx.UserIDFromContext(r.Context())
+	if !ok {
+		httpx.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	var req createRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpx.WriteError(w, http.StatusBadRequest, "invalid json body")
+		return
+	}
+	req.CandidateEmail = strings.TrimSpace(req.CandidateEmail)
+	req.ProblemID = strings.TrimSpace(req.ProblemID)
+	req.Difficulty = strings.TrimSpace(req.Difficulty)
+	req.Topic = strings.TrimSpace(req.Topic)
+	if req.CandidateEmail == "" {
+		httpx.WriteError(w, http.StatusBadRequest, "candidate_email required")
+		return
+	}
+	candidate, err := h.users.GetByEmail(r.Context(), req.CandidateEmail)
+	if err != nil {
+		if h.repo.IsNotFound(err) {
+			httpx.WriteError(w, http.StatusNotFound, "candidate not found")
+			return
+		}
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load candidate")
+		return
+	}
+	if candidate.ID == userID {
+		httpx.WriteError(w, http.StatusBadRequest, "interviewer and candidate must be different users")
+		return
+	}
+
+	problemID := req.ProblemID
+	if problemID != "" {
+		ok, err := h.repo.InCandidateLibrary(r.Context(), candidate.ID, problemID)
+		if err != nil {
+			httpx.WriteError(w, http.StatusInternalServerError, "failed to check candidate library")
+			return
+		}
+		if !ok {
+			httpx.WriteError(w, http.StatusBadRequest, "problem is not in the candidate's library")
+			return
+		}
+	} else {
+		problemID, err = h.repo.PickProblem(r.Context(), candidate.ID, req.Difficulty, req.Topic, time.Now().UTC())
+		if err != nil {
+			if errors.Is(err, ErrNoProblem) {
+				httpx.WriteError(w, http.StatusNotFound, err.Error())
+				return
+			}
+			httpx.WriteError(w, http.StatusInternalServerError, "failed to pick a problem")
+			return
+		}
+	}
+
+	id, err := h.repo.Create(r.Context(), userID, candidate.ID, problemID)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to create interview")
+		return
+	}
+	out, err := h.repo.Get(r.Context(), id, userID)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load interview")
+		return
+	}
+	httpx.WriteJSON(w, http.StatusCreated, out)
+}
+
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httpx.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID, ok := reqctx.UserIDFromContext(r.Context())
+	if !ok {
+		httpx.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	role := strings.TrimSpace(strings.ToLower(r.URL.Query().Get("role")))
+	switch role {
+	case "", RoleInterviewer, RoleCandidate:
+	default:
+		httpx.WriteError(w, http.StatusBadRequest, "role must be interviewer|candidate")
+		return
+	}
+	out, err := h.repo.List(r.Context(), userID, role)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to list interviews")
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, out)
+}
+
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httpx.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID, ok := reqctx.UserIDFromContext(r.Context())
+	if !ok {
+		httpx.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	id := strings.TrimSpace(chi.URLParam(r, "id"))
+	if id == "" {
+		httpx.WriteError(w, http.StatusBadRequest, "id required")
+		return
+	}
+	out, err := h.repo.Get(r.Context(), id, userID)
+	if err != nil {
+		if h.repo.IsNotFound(err) {
+			httpx.WriteError(w, http.StatusNotFound, "not found")
+			return
+		}
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load interview")
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, out)
+}
+
+func (h *Handler) Start(w http.ResponseWriter, r *http.Request) {
+	h.transition(w, r, h.repo.Start)
+}
+
+func (h *Handler) Cancel(w http.ResponseWriter, r *http.Request) {
+	h.transition(w, r, h.repo.Cancel)
+}
+
+func (h *Handler) transition(w http.ResponseWriter, r *http.Request, apply func(ctx context.Context, interviewID string, userID string) error) {
+	if r.Method != http.MethodPost {
+		httpx.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID, ok := reqctx.UserIDFromContext(r.Context())
+	if !ok {
+		httpx.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	id := strings.TrimSpace(chi.URLParam(r, "id"))
+	if id == "" {
+		httpx.WriteError(w, http.StatusBadRequest, "id required")
+		return
+	}
+	if err := apply(r.Context(), id, userID); err != nil {
+		writeError(w, h.repo, err)
+		return
+	}
+	out, err := h.repo.Get(r.Context(), id, userID)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load interview")
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, out)
+}
+
+// SubmitRubric records the interviewer's rubric and grade, completing the
+// interview. The grade is logged as a mock_interview review for the candidate and
+// advances the candidate's schedule for the problem.
+func (h *Handler) SubmitRubric(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httpx.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID, ok := reqctx.UserIDFromContext(r.Context())
+	if !ok {
+		httpx.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	id := strings.TrimSpace(chi.URLParam(r, "id"))
+	if id == "" {
+		httpx.WriteError(w, http.StatusBadRequest, "id required")
+		return
+	}
+	var rb Rubric
+	if err := json.NewDecoder(r.Body).Decode(&rb); err != nil {
+		httpx.WriteError(w, http.StatusBadRequest, "invalid json body")
+		return
+	}
+	rb, err := rb.Normalize()
+	if err != nil {
+		httpx.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	now := time.Now().UTC()
+	rb.SubmittedAt = now
+
+	ctx := r.Context()
+	tx, err := h.pool.Begin(ctx)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to start transaction")
+		return
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	li, err := h.repo.lockTx(ctx, tx, id, userID)
+	if err != nil {
+		writeError(w, h.repo, err)
+		return
+	}
+	if li.InterviewerID != userID {
+		writeError(w, h.repo, ErrNotInterviewer)
+		return
+	}
+	if li.Closed {
+		writeError(w, h.repo, ErrInterviewClosed)
+		return
+	}
+	if rb.TimeSpentSec == nil && li.StartedAt != nil {
+		sec := int(now.Sub(*li.StartedAt).Seconds())
+		rb.TimeSpentSec = &sec
+	}
+	if err := h.repo.CompleteTx(ctx, tx, id, li.CandidateID, rb); err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to save rubric")
+		return
+	}
+	// The candidate's own settings decide their local day and next due time.
+	settings, err := h.users.GetSettings(ctx, li.CandidateID)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load candidate settings")
+		return
+	}
+	loc, err := time.LoadLocation(settings.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	if err := h.repo.InsertReviewLogTx(ctx, tx, li.CandidateID, li.ProblemID, now, rb.Grade, rb.TimeSpentSec); err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to write review log")
+		return
+	}
+	if err := activity.RecordReviewTx(ctx, tx, li.CandidateID, loc, activity.Review{
+		ProblemID: li.ProblemID, Grade: rb.Grade, ReviewedAtUTC: now, TimeSpentSec: rb.TimeSpentSec,
+	}); err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to record daily activity")
+		return
+	}
+	state, err := h.problems.GetStateForUpdate(ctx, tx, li.CandidateID, li.ProblemID)
+	if err != nil {
+		httpx.WriteError(w, http.StatusNotFound, "problem state not found")
+		return
+	}
+	res := scheduler.Update(scheduler.State{
+		Reps:         state.Reps,
+		IntervalDays: state.IntervalDays,
+		Ease:         state.Ease,
+	}, rb.Grade, now, loc, settings.MinIntervalDays, settings.DueHourLocal, settings.DueMinuteLocal)
+	state.Reps = res.State.Reps
+	state.IntervalDays = res.State.IntervalDays
+	state.Ease = res.State.Ease
+	state.DueAt = res.DueAt
+	state.LastReviewAt = &now
+	state.LastGrade = &rb.Grade
+	if err := h.problems.UpdateState(ctx, tx, li.CandidateID, li.ProblemID, state); err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to update scheduling state")
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to commit transaction")
+		return
+	}
+	out, err := h.repo.Get(r.Context(), id, userID)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load interview")
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, out)
+}
+
+type rubricHistoryResponse struct {
+	Averages RubricAverages `json:"averages"`
+	Items    []RubricEntry  `json:"items"`
+}
+
+// RubricHistory returns the rubrics the caller received as a candidate, newest
+// first, with per-dimension averages over the returned items.
+func (h *Handler) RubricHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httpx.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID, ok := reqctx.UserIDFromContext(r.Context())
+	if !ok {
+		httpx.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	limit := 50
+	if q := r.URL.Query().Get("limit"); q != "" {
+		n, err := strconv.Atoi(q)
+		if err != nil || n < 1 || n > 200 {
+			httpx.WriteError(w, http.StatusBadRequest, "limit must be 1..200")
+			return
+		}
+		limit = n
+	}
+	items, err := h.repo.RubricHistory(r.Context(), userID, limit)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load rubric history")
+		return
+	}
+	rubrics := make([]Rubric, len(items))
+	for i, it := range items {
+		rubrics[i] = it.Rubric
+	}
+	httpx.WriteJSON(w, http.StatusOK, rubricHistoryResponse{Averages: averageRubrics(rubrics), Items: items})
+}
+
+func writeError(w http.ResponseWriter, repo *Repository, err error) {
+	switch {
+	case repo.IsNotFound(err):
+		httpx.WriteError(w, http.StatusNotFound, "not found")
+	case errors.Is(err, ErrNotInterviewer):
+		httpx.WriteError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, ErrInterviewClosed):
+		httpx.WriteError(w, http.StatusConflict, err.Error())
+	default:
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to update interview")
+	}
+}
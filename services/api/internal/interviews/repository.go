@@ -0,0 +1,320 @@
+package interviews
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/db"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/problems"
+)
+
+type Participant struct {
+	ID    string `json:"id"`
+	Email string `json:"email"`
+}
+
+type Interview struct {
+	ID          string           `json:"id"`
+	Interviewer Participant      `json:"interviewer"`
+	Candidate   Participant      `json:"candidate"`
+	Problem     problems.Problem `json:"problem"`
+	Role        string           `json:"role"`
+	Status      string           `json:"status"`
+	CreatedAt   time.Time        `json:"created_at"`
+	StartedAt   *time.Time       `json:"started_at,omitempty"`
+	CompletedAt *time.Time       `json:"completed_at,omitempty"`
+	CanceledAt  *time.Time       `json:"canceled_at,omitempty"`
+	Rubric      *Rubric          `json:"rubric,omitempty"`
+}
+
+func deriveStatus(i Interview) string {
+	switch {
+	case i.CompletedAt != nil:
+		return StatusCompleted
+	case i.CanceledAt != nil:
+		return StatusCanceled
+	case i.StartedAt != nil:
+		return StatusInProgress
+	default:
+		return StatusScheduled
+	}
+}
+
+// ErrNotInterviewer is returned when the candidate tries an interviewer-only action.
+var ErrNotInterviewer = errors.New("only the interviewer can do this")
+
+// ErrInterviewClosed is returned when acting on a completed or canceled interview.
+var ErrInterviewClosed = errors.New("interview is already completed or canceled")
+
+// ErrNoProblem is returned when the candidate's library has no problem matching the request.
+var ErrNoProblem = errors.New("no matching problem in the candidate's library")
+
+type Repository struct {
+	pool *pgxpool.Pool
+}
+
+func NewRepository(pool *pgxpool.Pool) *Repository { return &Repository{pool: pool} }
+
+func (r *Repository) IsNotFound(err error) bool { return errors.Is(err, db.ErrNotFound) }
+
+// interviewQuery selects interviews with both participants, the problem and the
+// rubric, as seen by the user in $1. Callers append their own WHERE conditions.
+const interviewQuery = `
+	SELECT mi.id::text, mi.created_at, mi.started_at, mi.completed_at, mi.canceled_at,
+	       iu.id::text, iu.email, cu.id::text, cu.email,
+	       p.id::text, p.url, p.platform, p.title, p.difficulty, p.topics,
+	       CASE WHEN mi.interviewer_id = $1 THEN 'interviewer' ELSE 'candidate' END,
+	       mr.communication, mr.problem_solving, mr.code_quality, mr.testing, mr.grade,
+	       mr.time_spent_sec, mr.notes, mr.submitted_at
+	FROM mock_interviews mi
+	JOIN users iu ON iu.id = mi.interviewer_id
+	JOIN users cu ON cu.id = mi.candidate_id
+	JOIN problems p ON p.id = mi.problem_id
+	LEFT JOIN mock_interview_rubrics mr ON mr.interview_id = mi.id
+	WHERE (mi.interviewer_id = $1 OR mi.candidate_id = $1)
+`
+
+func scanInterview(row pgx.Row) (Interview, error) {
+	var i Interview
+	var comm, solving, quality, testing, grade *int
+	var timeSpent *int
+	var notes *string
+	var submittedAt *time.Time
+	if err := row.Scan(
+		&i.ID, &i.CreatedAt, &i.StartedAt, &i.CompletedAt, &i.CanceledAt,
+		&i.Interviewer.ID, &i.Interviewer.Email, &i.Candidate.ID, &i.Candidate.Email,
+		&i.Problem.ID, &i.Problem.URL, &i.Problem.Platform, &i.Problem.Title, &i.Problem.Difficulty, &i.Problem.Topics,
+		&i.Role,
+		&comm, &solving, &quality, &testing, &grade, &timeSpent, &notes, &submittedAt,
+	); err != nil {
+		return Interview{}, err
+	}
+	if submittedAt != nil {
+		i.Rubric = &Rubric{
+			Communication: *comm, ProblemSolving: *solving, CodeQuality: *quality, Testing: *testing, Grade: *grade,
+			TimeSpentSec: timeSpent, Notes: *notes, SubmittedAt: *submittedAt,
+		}
+	}
+	i.Status = deriveStatus(i)
+	return i, nil
+}
+
+func (r *Repository) Create(ctx context.Context, interviewerID string, candidateID string, problemID string) (string, error) {
+	var id string
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO mock_interviews (interviewer_id, candidate_id, problem_id)
+		VALUES ($1, $2, $3)
+		RETURNING id::text
+	`, interviewerID, candidateID, problemID).Scan(&id)
+	return id, err
+}
+
+// InCandidateLibrary reports whether the problem is an active problem in the candidate's library.
+func (r *Repository) InCandidateLibrary(ctx context.Context, candidateID string, problemID string) (bool, error) {
+	var ok bool
+	err := r.pool.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM user_problem_state
+			WHERE user_id = $1 AND problem_id::text = $2 AND is_active = true
+		)
+	`, candidateID, problemID).Scan(&ok)
+	return ok, err
+}
+
+// PickProblem chooses a problem from the candidate's active library: problems not
+// used in one of their mock interviews during the last 14 days come first, then
+// the most overdue, then the weakest last grade. difficulty and topic are optional filters.
+func (r *Repository) PickProblem(ctx context.Context, candidateID string, difficulty string, topic string, now time.Time) (string, error) {
+	var id string
+	err := r.pool.QueryRow(ctx, `
+		SELECT p.id::text
+		FROM user_problem_state s
+		JOIN problems p ON p.id = s.problem_id
+		WHERE s.user_id = $1 AND s.is_active = true
+		  AND ($2 = '' OR lower(p.difficulty) = lower($2))
+		  AND ($3 = '' OR EXISTS (SELECT 1 FROM unnest(p.topics) t WHERE lower(t) = lower($3)))
+		ORDER BY
+		  EXISTS (
+		    SELECT 1 FROM mock_interviews mi
+		    WHERE mi.candidate_id = $1 AND mi.problem_id = p.id AND mi.created_at >= $4
+		  ) ASC,
+		  s.due_at ASC,
+		  s.last_grade ASC NULLS FIRST,
+		  p.id ASC
+		LIMIT 1
+	`, candidateID, difficulty, topic, now.Add(-14*24*time.Hour)).Scan(&id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrNoProblem
+		}
+		return "", err
+	}
+	return id, nil
+}
+
+func (r *Repository) Get(ctx context.Context, interviewID string, userID string) (Interview, error) {
+	i, err := scanInterview(r.pool.QueryRow(ctx, interviewQuery+` AND mi.id::text = $2`, userID, interviewID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Interview{}, db.ErrNotFound
+		}
+		return Interview{}, err
+	}
+	return i, nil
+}
+
+// List returns the user's interviews newest first; role narrows to one side.
+func (r *Repository) List(ctx context.Context, userID string, role string) ([]Interview, error) {
+	rows, err := r.pool.Query(ctx, interviewQuery+`
+		AND ($2 = '' OR ($2 = 'interviewer' AND mi.interviewer_id = $1) OR ($2 = 'candidate' AND mi.candidate_id = $1))
+		ORDER BY mi.created_at DESC, mi.id ASC
+		LIMIT 200
+	`, userID, role)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]Interview, 0)
+	for rows.Next() {
+		i, err := scanInterview(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, i)
+	}
+	return out, rows.Err()
+}
+
+// lockedInterview is the subset of an interview needed to validate a state change.
+type lockedInterview struct {
+	InterviewerID string
+	CandidateID   string
+	ProblemID     string
+	StartedAt     *time.Time
+	Closed        bool
+}
+
+// lockTx loads an interview FOR UPDATE if the user takes part in it.
+func (r *Repository) lockTx(ctx context.Context, tx pgx.Tx, interviewID string, userID string) (lockedInterview, error) {
+	var out lockedInterview
+	err := tx.QueryRow(ctx, `
+		SELECT interviewer_id::text, candidate_id::text, problem_id::text, started_at,
+		       completed_at IS NOT NULL OR canceled_at IS NOT NULL
+		FROM mock_interviews
+		WHERE id::text = $1 AND (interviewer_id = $2 OR candidate_id = $2)
+		FOR UPDATE
+	`, interviewID, userID).Scan(&out.InterviewerID, &out.CandidateID, &out.ProblemID, &out.StartedAt, &out.Closed)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return lockedInterview{}, db.ErrNotFound
+		}
+		return lockedInterview{}, err
+	}
+	return out, nil
+}
+
+// Start stamps started_at. Either participant can start; starting twice is a no-op.
+func (r *Repository) Start(ctx context.Context, interviewID string, userID string) error {
+	return r.stamp(ctx, interviewID, userID, "started_at")
+}
+
+// Cancel stamps canceled_at. Either participant can cancel an open interview.
+func (r *Repository) Cancel(ctx context.Context, interviewID string, userID string) error {
+	return r.stamp(ctx, interviewID, userID, "canceled_at")
+}
+
+func (r *Repository) stamp(ctx context.Context, interviewID string, userID string, column string) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	li, err := r.lockTx(ctx, tx, interviewID, userID)
+	if err != nil {
+		return err
+	}
+	if li.Closed {
+		return ErrInterviewClosed
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE mock_interviews SET `+column+` = COALESCE(`+column+`, now()) WHERE id::text = $1
+	`, interviewID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// CompleteTx stores the rubric and marks the interview completed.
+func (r *Repository) CompleteTx(ctx context.Context, tx pgx.Tx, interviewID string, candidateID string, rb Rubric) error {
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO mock_interview_rubrics (
+			interview_id, candidate_id, communication, problem_solving, code_quality, testing,
+			grade, time_spent_sec, notes, submitted_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`, interviewID, candidateID, rb.Communication, rb.ProblemSolving, rb.CodeQuality, rb.Testing,
+		rb.Grade, rb.TimeSpentSec, rb.Notes, rb.SubmittedAt); err != nil {
+		return err
+	}
+	_, err := tx.Exec(ctx, `
+		UPDATE mock_interviews
+		SET completed_at = $2, started_at = COALESCE(started_at, $2)
+		WHERE id::text = $1
+	`, interviewID, rb.SubmittedAt)
+	return err
+}
+
+func (r *Repository) InsertReviewLogTx(ctx context.Context, tx pgx.Tx, userID string, problemID string, reviewedAtUTC time.Time, grade int, timeSpentSec *int) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO review_logs (user_id, problem_id, reviewed_at, grade, time_spent_sec, source)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, userID, problemID, reviewedAtUTC, grade, timeSpentSec, ReviewSource)
+	return err
+}
+
+// RubricEntry is one rubric in a candidate's history.
+type RubricEntry struct {
+	InterviewID string           `json:"interview_id"`
+	Interviewer Participant      `json:"interviewer"`
+	Problem     problems.Problem `json:"problem"`
+	Rubric      Rubric           `json:"rubric"`
+}
+
+// RubricHistory returns the candidate's rubrics newest first.
+func (r *Repository) RubricHistory(ctx context.Context, candidateID string, limit int) ([]RubricEntry, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT mi.id::text, iu.id::text, iu.email,
+		       p.id::text, p.url, p.platform, p.title, p.difficulty, p.topics,
+		       mr.communication, mr.problem_solving, mr.code_quality, mr.testing, mr.grade,
+		       mr.time_spent_sec, mr.notes, mr.submitted_at
+		FROM mock_interview_rubrics mr
+		JOIN mock_interviews mi ON mi.id = mr.interview_id
+		JOIN users iu ON iu.id = mi.interviewer_id
+		JOIN problems p ON p.id = mi.problem_id
+		WHERE mr.candidate_id = $1
+		ORDER BY mr.submitted_at DESC, mi.id ASC
+		LIMIT $2
+	`, candidateID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]RubricEntry, 0)
+	for rows.Next() {
+		var e RubricEntry
+		rb := &e.Rubric
+		if err := rows.Scan(
+			&e.InterviewID, &e.Interviewer.ID, &e.Interviewer.Email,
+			&e.Problem.ID, &e.Problem.URL, &e.Problem.Platform, &e.Problem.Title, &e.Problem.Difficulty, &e.Problem.Topics,
+			&rb.Communication, &rb.ProblemSolving, &rb.CodeQuality, &rb.Testing, &rb.Grade,
+			&rb.TimeSpentSec, &rb.Notes, &rb.SubmittedAt,
+		); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
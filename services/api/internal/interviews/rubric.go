@@ -0,0 +1,103 @@
+package interviews
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ReviewSource tags the candidate's review_logs rows written by a mock interview.
+const ReviewSource = "mock_interview"
+
+// Interview statuses, derived from the lifecycle timestamps.
+const (
+	StatusScheduled  = "scheduled"
+	StatusInProgress = "in_progress"
+	StatusCompleted  = "completed"
+	StatusCanceled   = "canceled"
+)
+
+// Caller roles on an interview.
+const (
+	RoleInterviewer = "interviewer"
+	RoleCandidate   = "candidate"
+)
+
+// Rubric is the interviewer's structured feedback. Each dimension is scored 1..4
+// (no hire .. strong hire); Grade is the 0..4 review grade that drives scheduling.
+type Rubric struct {
+	Communication  int       `json:"communication"`
+	ProblemSolving int       `json:"problem_solving"`
+	CodeQuality    int       `json:"code_quality"`
+	Testing        int       `json:"testing"`
+	Grade          int       `json:"grade"`
+	TimeSpentSec   *int      `json:"time_spent_sec,omitempty"`
+	Notes          string    `json:"notes"`
+	SubmittedAt    time.Time `json:"submitted_at"`
+}
+
+var errInvalidRubric = errors.New("invalid rubric")
+
+// Normalize trims the notes and checks every score is in range.
+func (r Rubric) Normalize() (Rubric, error) {
+	for _, d := range []struct {
+		name  string
+		score int
+	}{
+		{"communication", r.Communication},
+		{"problem_solving", r.ProblemSolving},
+		{"code_quality", r.CodeQuality},
+		{"testing", r.Testing},
+	} {
+		if d.score < 1 || d.score > 4 {
+			return Rubric{}, fmt.Errorf("%w: %s must be 1..4", errInvalidRubric, d.name)
+		}
+	}
+	if r.Grade < 0 || r.Grade > 4 {
+		return Rubric{}, fmt.Errorf("%w: grade must be 0..4", errInvalidRubric)
+	}
+	if r.TimeSpentSec != nil && *r.TimeSpentSec < 0 {
+		return Rubric{}, fmt.Errorf("%w: time_spent_sec must be >= 0", errInvalidRubric)
+	}
+	r.Notes = strings.TrimSpace(r.Notes)
+	if len(r.Notes) > 4000 {
+		return Rubric{}, fmt.Errorf("%w: notes must be at most 4000 characters", errInvalidRubric)
+	}
+	return r, nil
+}
+
+// RubricAverages summarizes a candidate's rubric history per dimension.
+type RubricAverages struct {
+	Count          int     `json:"count"`
+	Communication  float64 `json:"communication"`
+	ProblemSolving float64 `json:"problem_solving"`
+	CodeQuality    float64 `json:"code_quality"`
+	Testing        float64 `json:"testing"`
+	Grade          float64 `json:"grade"`
+}
+
+func averageRubrics(rs []Rubric) RubricAverages {
+	out := RubricAverages{Count: len(rs)}
+	if len(rs) == 0 {
+		return out
+	}
+	for _, r := range rs {
+		out.Communication += float64(r.Communication)
+		out.ProblemSolving += float64(r.ProblemSolving)
+		out.CodeQuality += float64(r.CodeQuality)
+		out.Testing += float64(r.Testing)
+		out.Grade += float64(r.Grade)
+	}
+	n := float64(len(rs))
+	out.Communication = round2(out.Communication / n)
+	out.ProblemSolving = round2(out.ProblemSolving / n)
+	out.CodeQuality = round2(out.CodeQuality / n)
+	out.Testing = round2(out.Testing / n)
+	out.Grade = round2(out.Grade / n)
+	return out
+}
+
+func round2(v float64) float64 {
+	return float64(int(v*100+0.5)) / 100
+}
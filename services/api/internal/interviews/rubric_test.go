@@ -0,0 +1,37 @@
+package interviews
+
+import "testing"
+
+func TestRubricNormalize(t *testing.T) {
+	rb, err := Rubric{Communication: 3, ProblemSolving: 4, CodeQuality: 2, Testing: 1, Grade: 3, Notes: "  solid  "}.Normalize()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rb.Notes != "solid" {
+		t.Fatalf("expected trimmed notes, got %q", rb.Notes)
+	}
+	bad := []Rubric{
+		{Communication: 0, ProblemSolving: 3, CodeQuality: 3, Testing: 3, Grade: 3},
+		{Communication: 3, ProblemSolving: 3, CodeQuality: 5, Testing: 3, Grade: 3},
+		{Communication: 3, ProblemSolving: 3, CodeQuality: 3, Testing: 3, Grade: 5},
+	}
+	for i, b := range bad {
+		if _, err := b.Normalize(); err == nil {
+			t.Fatalf("case %d: expected error for %+v", i, b)
+		}
+	}
+}
+
+func TestAverageRubrics(t *testing.T) {
+	got := averageRubrics([]Rubric{
+		{Communication: 4, ProblemSolving: 3, CodeQuality: 2, Testing: 1, Grade: 4},
+		{Communication: 3, ProblemSolving: 3, CodeQuality: 3, Testing: 2, Grade: 2},
+		{Communication: 3, ProblemSolving: 2, CodeQuality: 3, Testing: 2, Grade: 3},
+	})
+	if got.Count != 3 || got.Communication != 3.33 || got.ProblemSolving != 2.67 || got.Testing != 1.67 || got.Grade != 3 {
+		t.Fatalf("unexpected averages: %+v", got)
+	}
+	if empty := averageRubrics(nil); empty.Count != 0 || empty.Grade != 0 {
+		t.Fatalf("expected zero averages for no rubrics, got %+v", empty)
+	}
+}
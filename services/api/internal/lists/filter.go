@@ -0,0 +1,168 @@
+package lists
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/mastery"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/problems"
+)
+
+// Filter is the saved query behind a smart list. Every set field narrows the
+// result; an empty filter matches the whole active library.
+type Filter struct {
+	Difficulties     []string `json:"difficulties,omitempty"`
+	Topics           []string `json:"topics,omitempty"`         // match any
+	ExcludeTopics    []string `json:"exclude_topics,omitempty"` // match none
+	Platforms        []string `json:"platforms,omitempty"`
+	MasteryBelow     *float64 `json:"mastery_below,omitempty"`
+	MasteryAtLeast   *float64 `json:"mastery_at_least,omitempty"`
+	FailedWithinDays *int     `json:"failed_within_days,omitempty"` // any review graded 0..1 in the window
+	DueWithinDays    *int     `json:"due_within_days,omitempty"`
+	NeverReviewed    bool     `json:"never_reviewed,omitempty"`
+	IncludeInactive  bool     `json:"include_inactive,omitempty"`
+	Sort             string   `json:"sort,omitempty"` // due | mastery | title
+	Limit            int      `json:"limit,omitempty"`
+}
+
+const maxSmartListItems = 500
+
+var errInvalidFilter = errors.New("invalid filter")
+
+func lowerAll(in []string) []string {
+	out := make([]string, 0, len(in))
+	for _, v := range in {
+		v = strings.TrimSpace(strings.ToLower(v))
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// Normalize lowercases set members and validates ranges, returning a copy that is
+// safe to persist and evaluate.
+func (f Filter) Normalize() (Filter, error) {
+	f.Difficulties = lowerAll(f.Difficulties)
+	for _, d := range f.Difficulties {
+		if d != "easy" && d != "medium" && d != "hard" && d != "unknown" {
+			return Filter{}, fmt.Errorf("%w: difficulties must be easy|medium|hard|unknown", errInvalidFilter)
+		}
+	}
+	f.Topics = lowerAll(f.Topics)
+	f.ExcludeTopics = lowerAll(f.ExcludeTopics)
+	f.Platforms = lowerAll(f.Platforms)
+	for _, m := range []*float64{f.MasteryBelow, f.MasteryAtLeast} {
+		if m != nil && (*m < 0 || *m > 100) {
+			return Filter{}, fmt.Errorf("%w: mastery bounds must be 0..100", errInvalidFilter)
+		}
+	}
+	for _, d := range []*int{f.FailedWithinDays, f.DueWithinDays} {
+		if d != nil && (*d < 0 || *d > 3650) {
+			return Filter{}, fmt.Errorf("%w: day windows must be 0..3650", errInvalidFilter)
+		}
+	}
+	f.Sort = strings.TrimSpace(strings.ToLower(f.Sort))
+	switch f.Sort {
+	case "":
+		f.Sort = "due"
+	case "due", "mastery", "title":
+	default:
+		return Filter{}, fmt.Errorf("%w: sort must be due|mastery|title", errInvalidFilter)
+	}
+	if f.Limit < 0 {
+		return Filter{}, fmt.Errorf("%w: limit must be >= 0", errInvalidFilter)
+	}
+	if f.Limit == 0 || f.Limit > maxSmartListItems {
+		f.Limit = maxSmartListItems
+	}
+	return f, nil
+}
+
+// sqlWhere renders the SQL-expressible part of the filter. $1 is the user id and
+// the returned args start at $2. Mastery bounds, sort and limit are applied in Go.
+func (f Filter) sqlWhere(now time.Time) (string, []any) {
+	clauses := []string{"s.user_id = $1"}
+	args := []any{}
+	next := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args)+1)
+	}
+	if !f.IncludeInactive {
+		clauses = append(clauses, "s.is_active = true")
+	}
+	if len(f.Difficulties) > 0 {
+		clauses = append(clauses, "lower(p.difficulty) = ANY("+next(f.Difficulties)+")")
+	}
+	if len(f.Topics) > 0 {
+		clauses = append(clauses, "EXISTS (SELECT 1 FROM unnest(p.topics) t WHERE lower(t) = ANY("+next(f.Topics)+"))")
+	}
+	if len(f.ExcludeTopics) > 0 {
+		clauses = append(clauses, "NOT EXISTS (SELECT 1 FROM unnest(p.topics) t WHERE lower(t) = ANY("+next(f.ExcludeTopics)+"))")
+	}
+	if len(f.Platforms) > 0 {
+		clauses = append(clauses, "lower(p.platform) = ANY("+next(f.Platforms)+")")
+	}
+	if f.FailedWithinDays != nil {
+		clauses = append(clauses, `EXISTS (
+			SELECT 1 FROM review_logs rl
+			WHERE rl.user_id = s.user_id AND rl.problem_id = p.id AND rl.grade <= 1 AND rl.reviewed_at >= `+next(now.AddDate(0, 0, -*f.FailedWithinDays))+`
+		)`)
+	}
+	if f.DueWithinDays != nil {
+		clauses = append(clauses, "s.due_at <= "+next(now.AddDate(0, 0, *f.DueWithinDays)))
+	}
+	if f.NeverReviewed {
+		clauses = append(clauses, "s.last_review_at IS NULL")
+	}
+	return strings.Join(clauses, " AND "), args
+}
+
+// usesMastery reports whether apply needs mastery estimates.
+func (f Filter) usesMastery() bool {
+	return f.MasteryBelow != nil || f.MasteryAtLeast != nil || f.Sort == "mastery"
+}
+
+// apply runs the in-memory part of the filter (mastery bounds, sort, limit).
+// grades holds recent review grades per problem id for the mastery estimate.
+func (f Filter) apply(now time.Time, in []problems.ProblemWithState, grades map[string][]int) []problems.ProblemWithState {
+	type scored struct {
+		p       problems.ProblemWithState
+		mastery float64
+	}
+	rows := make([]scored, 0, len(in))
+	for _, p := range in {
+		m := mastery.Problem(p.State, grades[p.ID], now).Score
+		if f.MasteryBelow != nil && m >= *f.MasteryBelow {
+			continue
+		}
+		if f.MasteryAtLeast != nil && m < *f.MasteryAtLeast {
+			continue
+		}
+		rows = append(rows, scored{p: p, mastery: m})
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		switch f.Sort {
+		case "mastery":
+			if rows[i].mastery != rows[j].mastery {
+				return rows[i].mastery < rows[j].mastery
+			}
+		case "title":
+			if rows[i].p.Title != rows[j].p.Title {
+				return strings.ToLower(rows[i].p.Title) < strings.ToLower(rows[j].p.Title)
+			}
+		}
+		return rows[i].p.State.DueAt.Before(rows[j].p.State.DueAt)
+	})
+	if f.Limit > 0 && len(rows) > f.Limit {
+		rows = rows[:f.Limit]
+	}
+	out := make([]problems.ProblemWithState, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, r.p)
+	}
+	return out
+}
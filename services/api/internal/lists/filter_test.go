@@ -0,0 +1,61 @@
+package lists
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/problems"
+)
+
+func TestFilterNormalize(t *testing.T) {
+	f, err := Filter{Difficulties: []string{" Medium "}, Topics: []string{"Graph", ""}}.Normalize()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(f.Difficulties) != 1 || f.Difficulties[0] != "medium" {
+		t.Fatalf("expected normalized difficulty, got %v", f.Difficulties)
+	}
+	if len(f.Topics) != 1 || f.Topics[0] != "graph" {
+		t.Fatalf("expected normalized topics, got %v", f.Topics)
+	}
+	if f.Sort != "due" || f.Limit != maxSmartListItems {
+		t.Fatalf("expected defaults, got sort=%q limit=%d", f.Sort, f.Limit)
+	}
+
+	if _, err := (Filter{Difficulties: []string{"insane"}}).Normalize(); err == nil {
+		t.Fatalf("expected invalid difficulty error")
+	}
+	bad := 101.0
+	if _, err := (Filter{MasteryBelow: &bad}).Normalize(); err == nil {
+		t.Fatalf("expected invalid mastery error")
+	}
+}
+
+func TestFilterSQLWhereNumbersArgsAfterUserID(t *testing.T) {
+	days := 14
+	f, _ := Filter{Difficulties: []string{"medium"}, Topics: []string{"graph"}, FailedWithinDays: &days}.Normalize()
+	where, args := f.sqlWhere(time.Date(2026, 2, 10, 0, 0, 0, 0, time.UTC))
+	if len(args) != 3 {
+		t.Fatalf("expected 3 args, got %d", len(args))
+	}
+	for _, want := range []string{"s.user_id = $1", "ANY($2)", "ANY($3)", ">= $4", "s.is_active = true"} {
+		if !strings.Contains(where, want) {
+			t.Fatalf("expected %q in where clause: %s", want, where)
+		}
+	}
+}
+
+func TestFilterApplyMasteryBound(t *testing.T) {
+	now := time.Date(2026, 2, 10, 0, 0, 0, 0, time.UTC)
+	below := 40.0
+	f, _ := Filter{MasteryBelow: &below}.Normalize()
+	in := []problems.ProblemWithState{
+		{Problem: problems.Problem{ID: "weak"}, State: problems.UserState{Reps: 0, Ease: 2.5, DueAt: now}},
+		{Problem: problems.Problem{ID: "strong"}, State: problems.UserState{Reps: 6, Ease: 2.7, DueAt: now.AddDate(0, 0, 20)}},
+	}
+	out := f.apply(now, in, nil)
+	if len(out) != 1 || out[0].ID != "weak" {
+		t.Fatalf("expected only the weak problem, got %+v", out)
+	}
+}
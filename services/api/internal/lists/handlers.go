@@ -2,6 +2,7 @@ package lists
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
 	"time"
@@ -9,6 +10,7 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/db"
 	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/httpx"
 	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/problems"
 	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/reqctx"
@@ -29,8 +31,9 @@ func NewHandler(pool *pgxpool.Pool, repo *Repository, problemsRepo *problems.Rep
 }
 
 type createListRequest struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Filter      *Filter `json:"filter"`
 }
 
 func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
@@ -54,6 +57,20 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 		httpx.WriteError(w, http.StatusBadRequest, "name required")
 		return
 	}
+	if req.Filter != nil {
+		f, err := req.Filter.Normalize()
+		if err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		out, err := h.repo.CreateSmart(r.Context(), userID, req.Name, req.Description, f)
+		if err != nil {
+			httpx.WriteError(w, http.StatusInternalServerError, "failed to create list")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusCreated, out)
+		return
+	}
 	out, err := h.repo.Create(r.Context(), userID, req.Name, req.Description)
 	if err != nil {
 		httpx.WriteError(w, http.StatusInternalServerError, "failed to create list")
@@ -103,6 +120,69 @@ func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 	httpx.WriteJSON(w, http.StatusOK, out)
 }
 
+type patchListRequest struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	Filter      *Filter `json:"filter"`
+}
+
+func (h *Handler) Patch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPatch {
+		httpx.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID, ok := reqctx.UserIDFromContext(r.Context())
+	if !ok {
+		httpx.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	listID := strings.TrimSpace(chi.URLParam(r, "id"))
+	if listID == "" {
+		httpx.WriteError(w, http.StatusBadRequest, "id required")
+		return
+	}
+	var req patchListRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpx.WriteError(w, http.StatusBadRequest, "invalid json body")
+		return
+	}
+	var patch Patch
+	if req.Name != nil {
+		v := strings.TrimSpace(*req.Name)
+		if v == "" {
+			httpx.WriteError(w, http.StatusBadRequest, "name must not be empty")
+			return
+		}
+		patch.Name = &v
+	}
+	if req.Description != nil {
+		v := strings.TrimSpace(*req.Description)
+		patch.Description = &v
+	}
+	if req.Filter != nil {
+		f, err := req.Filter.Normalize()
+		if err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		patch.Filter = &f
+	}
+	out, err := h.repo.Update(r.Context(), userID, listID, patch)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			httpx.WriteError(w, http.StatusNotFound, "not found")
+			return
+		}
+		if errors.Is(err, ErrFilterOnStaticList) {
+			httpx.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to update list")
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, out)
+}
+
 type addItemRequest struct {
 	ProblemID string `json:"problem_id"`
 }
@@ -153,11 +233,15 @@ func (h *Handler) AddItem(w http.ResponseWriter, r *http.Request) {
 	defer func() { _ = tx.Rollback(ctx) }()
 
 	// Ensure ownership.
-	var one int
-	if err := tx.QueryRow(ctx, `SELECT 1 FROM lists WHERE id = $1 AND owner_user_id = $2`, listID, userID).Scan(&one); err != nil {
+	sourceType, err := h.repo.SourceTypeTx(ctx, tx, userID, listID)
+	if err != nil {
 		httpx.WriteError(w, http.StatusNotFound, "not found")
 		return
 	}
+	if sourceType == "smart" {
+		httpx.WriteError(w, http.StatusBadRequest, ErrSmartListReadOnly.Error())
+		return
+	}
 
 	// Ensure user state exists (in case this problem was referenced without being in the library yet).
 	if err := h.problems.EnsureUserStateTx(ctx, tx, userID, req.ProblemID, dueAt); err != nil {
@@ -224,6 +308,10 @@ func (h *Handler) Reorder(w http.ResponseWriter, r *http.Request) {
 		req.ProblemIDs[i] = strings.TrimSpace(req.ProblemIDs[i])
 	}
 	if err := h.repo.Reorder(r.Context(), userID, listID, req.ProblemIDs); err != nil {
+		if errors.Is(err, ErrSmartListReadOnly) {
+			httpx.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		httpx.WriteError(w, http.StatusBadRequest, "failed to reorder")
 		return
 	}
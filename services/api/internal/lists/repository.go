@@ -9,6 +9,7 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/db"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/mastery"
 	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/problems"
 )
 
@@ -19,6 +20,7 @@ type List struct {
 	SourceType  string    `json:"source_type"`
 	SourceKey   *string   `json:"source_key,omitempty"`
 	Version     *string   `json:"version,omitempty"`
+	Filter      *Filter   `json:"filter,omitempty"`
 	CreatedAt   time.Time `json:"created_at"`
 }
 
@@ -32,20 +34,41 @@ type ListWithItems struct {
 	Items []Item `json:"items"`
 }
 
+// ErrSmartListReadOnly is returned when trying to edit the items of a smart list.
+var ErrSmartListReadOnly = errors.New("smart list items are defined by its filter")
+
+// ErrFilterOnStaticList is returned when a filter is set on a custom or template list.
+var ErrFilterOnStaticList = errors.New("filter can only be set on smart lists")
+
 type Repository struct {
-	pool *pgxpool.Pool
+	pool    *pgxpool.Pool
+	mastery *mastery.Repository
 }
 
-func NewRepository(pool *pgxpool.Pool) *Repository { return &Repository{pool: pool} }
+func NewRepository(pool *pgxpool.Pool) *Repository {
+	return &Repository{pool: pool, mastery: mastery.NewRepository(pool)}
+}
 
 func (r *Repository) Create(ctx context.Context, userID string, name string, description string) (List, error) {
 	var out List
 	err := r.pool.QueryRow(ctx, `
 		INSERT INTO lists (owner_user_id, name, description, source_type)
 		VALUES ($1, $2, $3, 'custom')
-		RETURNING id::text, name, description, source_type, source_key, version, created_at
+		RETURNING id::text, name, description, source_type, source_key, version, filter, created_at
 	`, userID, name, description).Scan(
-		&out.ID, &out.Name, &out.Description, &out.SourceType, &out.SourceKey, &out.Version, &out.CreatedAt,
+		&out.ID, &out.Name, &out.Description, &out.SourceType, &out.SourceKey, &out.Version, &out.Filter, &out.CreatedAt,
+	)
+	return out, err
+}
+
+func (r *Repository) CreateSmart(ctx context.Context, userID string, name string, description string, filter Filter) (List, error) {
+	var out List
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO lists (owner_user_id, name, description, source_type, filter)
+		VALUES ($1, $2, $3, 'smart', $4)
+		RETURNING id::text, name, description, source_type, source_key, version, filter, created_at
+	`, userID, name, description, filter).Scan(
+		&out.ID, &out.Name, &out.Description, &out.SourceType, &out.SourceKey, &out.Version, &out.Filter, &out.CreatedAt,
 	)
 	return out, err
 }
@@ -55,16 +78,16 @@ func (r *Repository) CreateTemplateSnapshotTx(ctx context.Context, tx pgx.Tx, us
 	err := tx.QueryRow(ctx, `
 		INSERT INTO lists (owner_user_id, name, description, source_type, source_key, version)
 		VALUES ($1, $2, '', 'template', $3, $4)
-		RETURNING id::text, name, description, source_type, source_key, version, created_at
+		RETURNING id::text, name, description, source_type, source_key, version, filter, created_at
 	`, userID, name, sourceKey, version).Scan(
-		&out.ID, &out.Name, &out.Description, &out.SourceType, &out.SourceKey, &out.Version, &out.CreatedAt,
+		&out.ID, &out.Name, &out.Description, &out.SourceType, &out.SourceKey, &out.Version, &out.Filter, &out.CreatedAt,
 	)
 	return out, err
 }
 
 func (r *Repository) List(ctx context.Context, userID string) ([]List, error) {
 	rows, err := r.pool.Query(ctx, `
-		SELECT id::text, name, description, source_type, source_key, version, created_at
+		SELECT id::text, name, description, source_type, source_key, version, filter, created_at
 		FROM lists
 		WHERE owner_user_id = $1
 		ORDER BY created_at DESC
@@ -76,7 +99,7 @@ func (r *Repository) List(ctx context.Context, userID string) ([]List, error) {
 	out := make([]List, 0)
 	for rows.Next() {
 		var l List
-		if err := rows.Scan(&l.ID, &l.Name, &l.Description, &l.SourceType, &l.SourceKey, &l.Version, &l.CreatedAt); err != nil {
+		if err := rows.Scan(&l.ID, &l.Name, &l.Description, &l.SourceType, &l.SourceKey, &l.Version, &l.Filter, &l.CreatedAt); err != nil {
 			return nil, err
 		}
 		out = append(out, l)
@@ -87,11 +110,11 @@ func (r *Repository) List(ctx context.Context, userID string) ([]List, error) {
 func (r *Repository) Get(ctx context.Context, userID string, listID string) (ListWithItems, error) {
 	var out ListWithItems
 	err := r.pool.QueryRow(ctx, `
-		SELECT id::text, name, description, source_type, source_key, version, created_at
+		SELECT id::text, name, description, source_type, source_key, version, filter, created_at
 		FROM lists
 		WHERE id = $1 AND owner_user_id = $2
 	`, listID, userID).Scan(
-		&out.ID, &out.Name, &out.Description, &out.SourceType, &out.SourceKey, &out.Version, &out.CreatedAt,
+		&out.ID, &out.Name, &out.Description, &out.SourceType, &out.SourceKey, &out.Version, &out.Filter, &out.CreatedAt,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -100,6 +123,22 @@ func (r *Repository) Get(ctx context.Context, userID string, listID string) (Lis
 		return ListWithItems{}, err
 	}
 
+	if out.SourceType == "smart" {
+		f := Filter{}
+		if out.Filter != nil {
+			f = *out.Filter
+		}
+		matched, err := r.Evaluate(ctx, userID, f, time.Now().UTC())
+		if err != nil {
+			return ListWithItems{}, err
+		}
+		out.Items = make([]Item, 0, len(matched))
+		for idx, p := range matched {
+			out.Items = append(out.Items, Item{Problem: p.Problem, Order: idx})
+		}
+		return out, nil
+	}
+
 	rows, err := r.pool.Query(ctx, `
 		SELECT li.order_index,
 		       p.id::text, p.url, p.platform, p.title, p.difficulty, p.topics
@@ -123,6 +162,118 @@ func (r *Repository) Get(ctx context.Context, userID string, listID string) (Lis
 	return out, nil
 }
 
+// Evaluate runs a smart-list filter against the user's library as of now.
+func (r *Repository) Evaluate(ctx context.Context, userID string, f Filter, now time.Time) ([]problems.ProblemWithState, error) {
+	f, err := f.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	where, args := f.sqlWhere(now)
+	rows, err := r.pool.Query(ctx, `
+		SELECT p.id::text, p.platform, p.url, p.title, p.difficulty, p.topics,
+		       s.reps, s.interval_days, s.ease, s.due_at, s.last_review_at, s.last_grade, s.is_active
+		FROM problems p
+		JOIN user_problem_state s ON s.problem_id = p.id
+		WHERE `+where+`
+		ORDER BY s.due_at ASC
+	`, append([]any{userID}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	all := make([]problems.ProblemWithState, 0)
+	for rows.Next() {
+		var p problems.ProblemWithState
+		if err := rows.Scan(
+			&p.ID, &p.Platform, &p.URL, &p.Title, &p.Difficulty, &p.Topics,
+			&p.State.Reps, &p.State.IntervalDays, &p.State.Ease, &p.State.DueAt, &p.State.LastReviewAt, &p.State.LastGrade, &p.State.IsActive,
+		); err != nil {
+			return nil, err
+		}
+		all = append(all, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	var grades map[string][]int
+	if f.usesMastery() {
+		ids := make([]string, 0, len(all))
+		for _, p := range all {
+			ids = append(ids, p.ID)
+		}
+		if grades, err = r.mastery.RecentGrades(ctx, userID, ids); err != nil {
+			return nil, err
+		}
+	}
+	return f.apply(now, all, grades), nil
+}
+
+// ProblemIDs resolves a list to its problem ids in list order, evaluating smart
+// lists on the fly. It is the entry point for features that accept a list_id.
+func (r *Repository) ProblemIDs(ctx context.Context, userID string, listID string) ([]string, error) {
+	l, err := r.Get(ctx, userID, listID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, 0, len(l.Items))
+	for _, it := range l.Items {
+		out = append(out, it.Problem.ID)
+	}
+	return out, nil
+}
+
+type Patch struct {
+	Name        *string
+	Description *string
+	Filter      *Filter
+}
+
+func (r *Repository) Update(ctx context.Context, userID string, listID string, patch Patch) (List, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return List{}, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	sourceType, err := r.SourceTypeTx(ctx, tx, userID, listID)
+	if err != nil {
+		return List{}, err
+	}
+	if patch.Filter != nil && sourceType != "smart" {
+		return List{}, ErrFilterOnStaticList
+	}
+
+	var out List
+	err = tx.QueryRow(ctx, `
+		UPDATE lists
+		SET name = COALESCE($3, name),
+		    description = COALESCE($4, description),
+		    filter = COALESCE($5, filter),
+		    updated_at = now()
+		WHERE id = $1 AND owner_user_id = $2
+		RETURNING id::text, name, description, source_type, source_key, version, filter, created_at
+	`, listID, userID, patch.Name, patch.Description, patch.Filter).Scan(
+		&out.ID, &out.Name, &out.Description, &out.SourceType, &out.SourceKey, &out.Version, &out.Filter, &out.CreatedAt,
+	)
+	if err != nil {
+		return List{}, err
+	}
+	return out, tx.Commit(ctx)
+}
+
+// SourceTypeTx returns the list's source_type after verifying ownership.
+func (r *Repository) SourceTypeTx(ctx context.Context, tx pgx.Tx, userID string, listID string) (string, error) {
+	var sourceType string
+	err := tx.QueryRow(ctx, `SELECT source_type FROM lists WHERE id = $1 AND owner_user_id = $2`, listID, userID).Scan(&sourceType)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", db.ErrNotFound
+		}
+		return "", err
+	}
+	return sourceType, nil
+}
+
 func (r *Repository) NextOrderIndexTx(ctx context.Context, tx pgx.Tx, listID string) (int, error) {
 	var next int
 	if err := tx.QueryRow(ctx, `SELECT COALESCE(MAX(order_index), -1) + 1 FROM list_items WHERE list_id = $1`, listID).Scan(&next); err != nil {
@@ -143,13 +294,16 @@ func (r *Repository) AddItemTx(ctx context.Context, tx pgx.Tx, listID string, pr
 
 func (r *Repository) Reorder(ctx context.Context, userID string, listID string, orderedProblemIDs []string) error {
 	// Verify ownership.
-	var ok bool
-	if err := r.pool.QueryRow(ctx, `SELECT true FROM lists WHERE id = $1 AND owner_user_id = $2`, listID, userID).Scan(&ok); err != nil {
+	var sourceType string
+	if err := r.pool.QueryRow(ctx, `SELECT source_type FROM lists WHERE id = $1 AND owner_user_id = $2`, listID, userID).Scan(&sourceType); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return db.ErrNotFound
 		}
 		return err
 	}
+	if sourceType == "smart" {
+		return ErrSmartListReadOnly
+	}
 	if len(orderedProblemIDs) == 0 {
 		return nil
 	}
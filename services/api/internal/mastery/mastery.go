@@ -0,0 +1,179 @@
+// Package mastery estimates how well a user knows a problem, and a topic, from
+// their SM-2 state and the outcomes of their recent reviews. It is the single
+// mastery model used by stats, contest selection and smart lists.
+package mastery
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/problems"
+)
+
+const (
+	// HistoryLen bounds how many recent reviews feed an estimate per problem.
+	HistoryLen = 10
+	// decay is the weight multiplier applied to each older review.
+	decay = 0.8
+	// priorWeight is how many reviews' worth of evidence the SM-2 state counts for.
+	priorWeight = 2.0
+	// z is the normal quantile for a 95% interval.
+	z = 1.96
+)
+
+// Estimate is a 0..100 mastery score with a 95% interval. Reviews is the number
+// of logged reviews the estimate saw; with none the interval is wide.
+type Estimate struct {
+	Score   float64 `json:"mastery"`
+	Low     float64 `json:"mastery_low"`
+	High    float64 `json:"mastery_high"`
+	Reviews int     `json:"reviews"`
+}
+
+// stateScore is the 0..1 prior implied by SM-2 state alone: more repetitions and
+// a higher ease mean the scheduler has seen the problem recalled more often.
+func stateScore(s problems.UserState) float64 {
+	m := (20*math.Log2(float64(s.Reps)+1) + 25*(s.Ease-1.3)) / 100
+	return clamp(m, 0, 1)
+}
+
+// retrievability is the usual SM-2 assumption that recall is ~90% on the due
+// date and decays geometrically as the review gets more overdue.
+func retrievability(s problems.UserState, now time.Time) float64 {
+	interval := float64(s.IntervalDays)
+	if interval < 1 {
+		interval = 1
+	}
+	var elapsed float64
+	if s.LastReviewAt != nil {
+		elapsed = now.Sub(*s.LastReviewAt).Hours() / 24
+	} else {
+		elapsed = interval - s.DueAt.Sub(now).Hours()/24
+	}
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	return math.Pow(0.9, elapsed/interval)
+}
+
+// Problem estimates mastery of one problem. grades are the most recent review
+// grades, newest first (see Repository.RecentGrades); when none were loaded the
+// state's last grade stands in.
+//
+// The recall rate is the mean of a Beta posterior: the state prior counts as
+// priorWeight reviews, and each logged review adds a recall (grade >= 2) or a
+// lapse with weight decay^age. The rate is then scaled by retrievability. A
+// problem that was never reviewed has score 0 and the full 0..100 interval.
+func Problem(s problems.UserState, grades []int, now time.Time) Estimate {
+	if len(grades) == 0 && s.LastGrade != nil {
+		grades = []int{*s.LastGrade}
+	}
+	if len(grades) == 0 && s.Reps == 0 {
+		return Estimate{Score: 0, Low: 0, High: 100}
+	}
+	p0 := stateScore(s)
+	alpha, beta := 1+priorWeight*p0, 1+priorWeight*(1-p0)
+	w := 1.0
+	for i, g := range grades {
+		if i >= HistoryLen {
+			break
+		}
+		if g >= 2 {
+			alpha += w
+		} else {
+			beta += w
+		}
+		w *= decay
+	}
+	n := alpha + beta
+	mean := alpha / n
+	sd := math.Sqrt(alpha * beta / (n * n * (n + 1)))
+	r := retrievability(s, now)
+	return Estimate{
+		Score:   round1(100 * mean * r),
+		Low:     round1(100 * clamp(mean-z*sd, 0, 1) * r),
+		High:    round1(100 * clamp(mean+z*sd, 0, 1) * r),
+		Reviews: min(len(grades), HistoryLen),
+	}
+}
+
+// TopicEstimate is the mastery of a topic across its problems.
+type TopicEstimate struct {
+	Topic    string `json:"topic"`
+	Problems int    `json:"count"`
+	Estimate
+}
+
+// Topic averages problem estimates. The interval bounds are averaged too rather
+// than narrowed: the problems share one learner, so their errors are correlated.
+func Topic(topic string, estimates []Estimate) TopicEstimate {
+	out := TopicEstimate{Topic: topic, Problems: len(estimates)}
+	if len(estimates) == 0 {
+		return out
+	}
+	var score, low, high float64
+	for _, e := range estimates {
+		score += e.Score
+		low += e.Low
+		high += e.High
+		out.Reviews += e.Reviews
+	}
+	n := float64(len(estimates))
+	out.Score, out.Low, out.High = round1(score/n), round1(low/n), round1(high/n)
+	return out
+}
+
+// Tagged is a problem's estimate together with the problem's topics.
+type Tagged struct {
+	Topics   []string
+	Estimate Estimate
+}
+
+// ByTopic groups problem estimates by normalized topic and returns the topic
+// estimates weakest first, ties by name.
+func ByTopic(ps []Tagged) []TopicEstimate {
+	byTopic := map[string][]Estimate{}
+	for _, p := range ps {
+		seen := map[string]bool{}
+		for _, t := range p.Topics {
+			t = NormalizeTopic(t)
+			if t == "" || seen[t] {
+				continue
+			}
+			seen[t] = true
+			byTopic[t] = append(byTopic[t], p.Estimate)
+		}
+	}
+	out := make([]TopicEstimate, 0, len(byTopic))
+	for t, es := range byTopic {
+		out = append(out, Topic(t, es))
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Score == out[j].Score {
+			return out[i].Topic < out[j].Topic
+		}
+		return out[i].Score < out[j].Score
+	})
+	return out
+}
+
+// NormalizeTopic is the topic key estimates are grouped by.
+func NormalizeTopic(t string) string {
+	return strings.TrimSpace(strings.ToLower(t))
+}
+
+func clamp(v, lo, hi float64) float64 {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+func round1(v float64) float64 {
+	return math.Round(v*10) / 10
+}
@@ -0,0 +1,76 @@
+package mastery
+
+import (
+	"testing"
+	"time"
+
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/problems"
+)
+
+func TestProblemUsesOutcomesAndDecay(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	justNow := now
+	state := problems.UserState{Reps: 3, IntervalDays: 10, Ease: 2.5, DueAt: now.AddDate(0, 0, 10), LastReviewAt: &justNow}
+
+	if e := Problem(problems.UserState{Ease: 2.5, DueAt: now}, nil, now); e.Score != 0 || e.Low != 0 || e.High != 100 {
+		t.Fatalf("expected never-reviewed problem to score 0 with a full interval, got %+v", e)
+	}
+	strong := Problem(state, []int{4, 4, 3, 4}, now)
+	weak := Problem(state, []int{1, 0, 1, 4}, now)
+	if strong.Score <= weak.Score {
+		t.Fatalf("expected recalls to beat lapses: strong=%v weak=%v", strong.Score, weak.Score)
+	}
+	if strong.Low > strong.Score || strong.High < strong.Score || strong.Reviews != 4 {
+		t.Fatalf("expected score inside its interval: %+v", strong)
+	}
+	recentLapse := Problem(state, []int{0, 4, 4, 4}, now)
+	oldLapse := Problem(state, []int{4, 4, 4, 0}, now)
+	if recentLapse.Score >= oldLapse.Score {
+		t.Fatalf("expected a recent lapse to hurt more: recent=%v old=%v", recentLapse.Score, oldLapse.Score)
+	}
+	overdueAt := now.AddDate(0, 0, -30)
+	overdue := state
+	overdue.LastReviewAt = &overdueAt
+	if e := Problem(overdue, []int{4, 4, 3, 4}, now); e.Score >= strong.Score {
+		t.Fatalf("expected an overdue review to lower mastery, got %v >= %v", e.Score, strong.Score)
+	}
+}
+
+func TestProblemIntervalNarrowsWithEvidence(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	state := problems.UserState{Reps: 2, IntervalDays: 6, Ease: 2.5, DueAt: now.AddDate(0, 0, 6)}
+	one := Problem(state, []int{3}, now)
+	many := Problem(state, []int{3, 3, 3, 3, 3, 3, 3, 3}, now)
+	if many.High-many.Low >= one.High-one.Low {
+		t.Fatalf("expected more reviews to narrow the interval: one=%+v many=%+v", one, many)
+	}
+	g := 3
+	state.LastGrade = &g
+	if fallback := Problem(state, nil, now); fallback != one {
+		t.Fatalf("expected last_grade to stand in for missing logs: %+v vs %+v", fallback, one)
+	}
+}
+
+func TestTopicAverages(t *testing.T) {
+	got := Topic("graphs", []Estimate{{Score: 40, Low: 20, High: 60, Reviews: 2}, {Score: 80, Low: 70, High: 90, Reviews: 5}})
+	if got.Problems != 2 || got.Score != 60 || got.Low != 45 || got.High != 75 || got.Reviews != 7 {
+		t.Fatalf("unexpected topic estimate: %+v", got)
+	}
+	if empty := Topic("dp", nil); empty.Problems != 0 || empty.Score != 0 {
+		t.Fatalf("unexpected empty topic: %+v", empty)
+	}
+}
+
+func TestByTopicGroupsWeakestFirst(t *testing.T) {
+	got := ByTopic([]Tagged{
+		{Topics: []string{"Graphs", "graphs ", "dp"}, Estimate: Estimate{Score: 40}},
+		{Topics: []string{"graphs"}, Estimate: Estimate{Score: 60}},
+		{Topics: []string{"arrays", ""}, Estimate: Estimate{Score: 40}},
+	})
+	if len(got) != 3 || got[0].Topic != "arrays" || got[1].Topic != "dp" || got[2].Topic != "graphs" {
+		t.Fatalf("unexpected order: %+v", got)
+	}
+	if got[2].Problems != 2 || got[2].Score != 50 {
+		t.Fatalf("expected a problem to count once per topic: %+v", got[2])
+	}
+}
@@ -0,0 +1,39 @@
+package mastery
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Repository struct {
+	pool *pgxpool.Pool
+}
+
+func NewRepository(pool *pgxpool.Pool) *Repository { return &Repository{pool: pool} }
+
+// RecentGrades returns up to HistoryLen grades per problem, newest first, keyed
+// by problem id. A nil problemIDs loads every problem in the user's library.
+// The grades are maintained on user_problem_state by activity.RecordReviewTx.
+func (r *Repository) RecentGrades(ctx context.Context, userID string, problemIDs []string) (map[string][]int, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT problem_id::text, recent_grades
+		FROM user_problem_state
+		WHERE user_id = $1 AND ($2::uuid[] IS NULL OR problem_id = ANY($2::uuid[]))
+		  AND cardinality(recent_grades) > 0
+	`, userID, problemIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string][]int{}
+	for rows.Next() {
+		var id string
+		var grades []int
+		if err := rows.Scan(&id, &grades); err != nil {
+			return nil, err
+		}
+		out[id] = grades
+	}
+	return out, rows.Err()
+}
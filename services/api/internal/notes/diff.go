@@ -0,0 +1,150 @@
+package notes
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContextLines is how many unchanged lines surround each hunk.
+const diffContextLines = 3
+
+// maxDiffCells bounds the LCS table. Past it, the differing middle of the two
+// texts (after their common prefix and suffix) is shown as removed and re-added
+// rather than aligned line by line.
+const maxDiffCells = 4_000_000
+
+type diffLine struct {
+	Op   byte // ' ' unchanged, '-' removed, '+' added
+	Text string
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// diffLines aligns a and b on a longest common subsequence of lines.
+func diffLines(a []string, b []string) []diffLine {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	out := make([]diffLine, 0, len(a)+len(b))
+	for _, l := range a[:prefix] {
+		out = append(out, diffLine{' ', l})
+	}
+	am, bm := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if (len(am)+1)*(len(bm)+1) > maxDiffCells {
+		for _, l := range am {
+			out = append(out, diffLine{'-', l})
+		}
+		for _, l := range bm {
+			out = append(out, diffLine{'+', l})
+		}
+	} else {
+		// lcs[i*w+j] is the LCS length of am[i:] and bm[j:].
+		w := len(bm) + 1
+		lcs := make([]int32, (len(am)+1)*w)
+		for i := len(am) - 1; i >= 0; i-- {
+			for j := len(bm) - 1; j >= 0; j-- {
+				if am[i] == bm[j] {
+					lcs[i*w+j] = lcs[(i+1)*w+j+1] + 1
+				} else {
+					lcs[i*w+j] = max(lcs[(i+1)*w+j], lcs[i*w+j+1])
+				}
+			}
+		}
+		i, j := 0, 0
+		for i < len(am) || j < len(bm) {
+			switch {
+			case i < len(am) && j < len(bm) && am[i] == bm[j]:
+				out = append(out, diffLine{' ', am[i]})
+				i++
+				j++
+			case j == len(bm) || (i < len(am) && lcs[(i+1)*w+j] >= lcs[i*w+j+1]):
+				out = append(out, diffLine{'-', am[i]})
+				i++
+			default:
+				out = append(out, diffLine{'+', bm[j]})
+				j++
+			}
+		}
+	}
+	for _, l := range a[len(a)-suffix:] {
+		out = append(out, diffLine{' ', l})
+	}
+	return out
+}
+
+// unifiedDiff renders the lines as a unified diff between the named versions,
+// or "" when nothing changed.
+func unifiedDiff(fromName string, toName string, lines []diffLine) string {
+	// Hunks are [start, end) ranges of lines: each change with its context,
+	// merged when they touch.
+	var hunks [][2]int
+	for i, l := range lines {
+		if l.Op == ' ' {
+			continue
+		}
+		start, end := max(0, i-diffContextLines), min(len(lines), i+diffContextLines+1)
+		if n := len(hunks); n > 0 && start <= hunks[n-1][1] {
+			hunks[n-1][1] = end
+		} else {
+			hunks = append(hunks, [2]int{start, end})
+		}
+	}
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+	aLine, bLine, next := 1, 1, 0
+	for _, h := range hunks {
+		for ; next < h[0]; next++ {
+			aLine, bLine = advance(lines[next].Op, aLine, bLine)
+		}
+		aCount, bCount := 0, 0
+		for _, l := range lines[h[0]:h[1]] {
+			if l.Op != '+' {
+				aCount++
+			}
+			if l.Op != '-' {
+				bCount++
+			}
+		}
+		// An empty side names the line before the hunk, as diff(1) does.
+		aStart, bStart := aLine, bLine
+		if aCount == 0 {
+			aStart--
+		}
+		if bCount == 0 {
+			bStart--
+		}
+		fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", aStart, aCount, bStart, bCount)
+		for ; next < h[1]; next++ {
+			sb.WriteByte(lines[next].Op)
+			sb.WriteString(lines[next].Text)
+			sb.WriteByte('\n')
+			aLine, bLine = advance(lines[next].Op, aLine, bLine)
+		}
+	}
+	return sb.String()
+}
+
+func advance(op byte, aLine int, bLine int) (int, int) {
+	if op != '+' {
+		aLine++
+	}
+	if op != '-' {
+		bLine++
+	}
+	return aLine, bLine
+}
@@ -0,0 +1,63 @@
+package notes
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	a := "# Two Sum\n\nUse a hash map.\nStore value -> index.\nO(n) time.\n\n## Pitfalls\n- same index twice\n"
+	b := "# Two Sum\n\nUse a hash map.\nStore value -> index while scanning.\nO(n) time.\n\n## Pitfalls\n- same index twice\n- negative numbers\n"
+	got := unifiedDiff("rev 1", "rev 2", diffLines(splitLines(a), splitLines(b)))
+	want := strings.Join([]string{
+		"--- rev 1",
+		"+++ rev 2",
+		"@@ -1,8 +1,9 @@",
+		" # Two Sum",
+		" ",
+		" Use a hash map.",
+		"-Store value -> index.",
+		"+Store value -> index while scanning.",
+		" O(n) time.",
+		" ",
+		" ## Pitfalls",
+		" - same index twice",
+		"+- negative numbers",
+		"",
+	}, "\n")
+	if got != want {
+		t.Fatalf("diff =\n%s\nwant\n%s", got, want)
+	}
+
+	// Changes more than twice the context apart get their own hunks.
+	long := []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10"}
+	edited := []string{"one", "2", "3", "4", "5", "6", "7", "8", "9"}
+	got = unifiedDiff("rev 1", "rev 2", diffLines(long, edited))
+	want = "--- rev 1\n+++ rev 2\n@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n@@ -7,4 +7,3 @@\n 7\n 8\n 9\n-10\n"
+	if got != want {
+		t.Fatalf("diff =\n%s\nwant\n%s", got, want)
+	}
+
+	if got := unifiedDiff("rev 1", "rev 1", diffLines(splitLines(a), splitLines(a))); got != "" {
+		t.Fatalf("identical revisions should have no diff, got %q", got)
+	}
+	if got := unifiedDiff("rev 1", "rev 2", diffLines(nil, []string{"x"})); got != "--- rev 1\n+++ rev 2\n@@ -0,0 +1,1 @@\n+x\n" {
+		t.Fatalf("diff from empty = %q", got)
+	}
+}
+
+func TestDiffLinesAlignsMovedBlocks(t *testing.T) {
+	a := []string{"a", "b", "c", "d", "e"}
+	b := []string{"a", "c", "d", "b", "e"}
+	var ops strings.Builder
+	kept := 0
+	for _, l := range diffLines(a, b) {
+		ops.WriteByte(l.Op)
+		if l.Op == ' ' {
+			kept++
+		}
+	}
+	if kept != 4 || ops.String() != " -  + " {
+		t.Fatalf("ops = %q, kept %d", ops.String(), kept)
+	}
+}
@@ -0,0 +1,217 @@
+package notes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"strings"
+)
+
+// The web editor's schema: TipTap's StarterKit (headings 1-3, its own code
+// block replaced by the lowlight one, which keeps the same node), Link, and the
+// codeTabs atom holding one solution in several languages. Anything else is
+// rejected so content_md can always be derived from content_json.
+
+const (
+	groupBlock  = "block"
+	groupInline = "inline"
+)
+
+type nodeSpec struct {
+	group   string // what the node is; "" for doc and listItem, which only fit one parent
+	content string // what it holds: a group, a node type, or "" for a leaf
+	min     int    // fewest children
+}
+
+var nodeSpecs = map[string]nodeSpec{
+	"doc":            {content: groupBlock},
+	"paragraph":      {group: groupBlock, content: groupInline},
+	"heading":        {group: groupBlock, content: groupInline},
+	"blockquote":     {group: groupBlock, content: groupBlock, min: 1},
+	"bulletList":     {group: groupBlock, content: "listItem", min: 1},
+	"orderedList":    {group: groupBlock, content: "listItem", min: 1},
+	"listItem":       {content: groupBlock, min: 1},
+	"codeBlock":      {group: groupBlock, content: "text"},
+	"horizontalRule": {group: groupBlock},
+	"codeTabs":       {group: groupBlock},
+	"text":           {group: groupInline},
+	"hardBreak":      {group: groupInline},
+}
+
+var markTypes = map[string]bool{"bold": true, "italic": true, "strike": true, "code": true, "underline": true, "link": true}
+
+// Limits on top of the request body limit. Depth bounds the recursion of
+// validation and rendering.
+const (
+	maxDocDepth = 64
+	maxCodeTabs = 20
+)
+
+type docNode struct {
+	Type    string         `json:"type"`
+	Attrs   map[string]any `json:"attrs,omitempty"`
+	Content []docNode      `json:"content,omitempty"`
+	Text    *string        `json:"text,omitempty"`
+	Marks   []docMark      `json:"marks,omitempty"`
+}
+
+type docMark struct {
+	Type  string         `json:"type"`
+	Attrs map[string]any `json:"attrs,omitempty"`
+}
+
+// parseDoc decodes and validates a content_json document. Errors name the
+// offending node by its path, e.g. content[2].content[0].
+func parseDoc(raw json.RawMessage) (docNode, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	var doc docNode
+	if err := dec.Decode(&doc); err != nil {
+		return docNode{}, fmt.Errorf("not a document: %v", err)
+	}
+	if dec.More() {
+		return docNode{}, fmt.Errorf("not a document: trailing data")
+	}
+	if doc.Type != "doc" {
+		return docNode{}, fmt.Errorf("root must be a doc node")
+	}
+	if err := validateNode(doc, "doc", 0); err != nil {
+		return docNode{}, err
+	}
+	return doc, nil
+}
+
+func validateNode(n docNode, path string, depth int) error {
+	if depth > maxDocDepth {
+		return fmt.Errorf("%s: nested deeper than %d", path, maxDocDepth)
+	}
+	spec, ok := nodeSpecs[n.Type]
+	if !ok {
+		return fmt.Errorf("%s: unknown node type %q", path, n.Type)
+	}
+	if n.Type == "text" {
+		if n.Text == nil || *n.Text == "" {
+			return fmt.Errorf("%s: text nodes need text", path)
+		}
+	} else if n.Text != nil {
+		return fmt.Errorf("%s: only text nodes have text", path)
+	}
+	if n.Type != "text" && len(n.Marks) > 0 {
+		return fmt.Errorf("%s: only text nodes have marks", path)
+	}
+	for i, m := range n.Marks {
+		if err := validateMark(m); err != nil {
+			return fmt.Errorf("%s.marks[%d]: %v", path, i, err)
+		}
+	}
+	if err := validateAttrs(n); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+
+	if spec.content == "" && len(n.Content) > 0 {
+		return fmt.Errorf("%s: %s nodes have no content", path, n.Type)
+	}
+	if len(n.Content) < spec.min {
+		return fmt.Errorf("%s: %s needs at least %d child node(s)", path, n.Type, spec.min)
+	}
+	for i, c := range n.Content {
+		cpath := fmt.Sprintf("%s.content[%d]", path, i)
+		child, ok := nodeSpecs[c.Type]
+		if !ok {
+			return fmt.Errorf("%s: unknown node type %q", cpath, c.Type)
+		}
+		if c.Type != spec.content && child.group != spec.content {
+			return fmt.Errorf("%s: %s cannot contain %s", cpath, n.Type, c.Type)
+		}
+		if n.Type == "listItem" && i == 0 && c.Type != "paragraph" {
+			return fmt.Errorf("%s: a list item must start with a paragraph", cpath)
+		}
+		if n.Type == "codeBlock" && len(c.Marks) > 0 {
+			return fmt.Errorf("%s: code block text has no marks", cpath)
+		}
+		if err := validateNode(c, cpath, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateAttrs(n docNode) error {
+	switch n.Type {
+	case "heading":
+		level, ok := intAttr(n.Attrs, "level")
+		if !ok || level < 1 || level > 3 {
+			return fmt.Errorf("heading level must be 1..3")
+		}
+	case "orderedList":
+		if _, set := n.Attrs["start"]; set {
+			if start, ok := intAttr(n.Attrs, "start"); !ok || start < 0 {
+				return fmt.Errorf("ordered list start must be a non-negative integer")
+			}
+		}
+	case "codeBlock":
+		if v, set := n.Attrs["language"]; set && v != nil {
+			if _, ok := v.(string); !ok {
+				return fmt.Errorf("code block language must be a string")
+			}
+		}
+	case "codeTabs":
+		tabs, ok := n.Attrs["tabs"].([]any)
+		if !ok {
+			return fmt.Errorf("code tabs need a tabs array")
+		}
+		if len(tabs) > maxCodeTabs {
+			return fmt.Errorf("at most %d code tabs", maxCodeTabs)
+		}
+		for i, t := range tabs {
+			tab, ok := t.(map[string]any)
+			if !ok {
+				return fmt.Errorf("tabs[%d] must be an object", i)
+			}
+			for _, k := range []string{"language", "code"} {
+				if _, ok := tab[k].(string); !ok {
+					return fmt.Errorf("tabs[%d].%s must be a string", i, k)
+				}
+			}
+		}
+		if _, set := n.Attrs["activeIndex"]; set {
+			if _, ok := intAttr(n.Attrs, "activeIndex"); !ok {
+				return fmt.Errorf("activeIndex must be an integer")
+			}
+		}
+	}
+	return nil
+}
+
+func validateMark(m docMark) error {
+	if !markTypes[m.Type] {
+		return fmt.Errorf("unknown mark type %q", m.Type)
+	}
+	if m.Type != "link" {
+		return nil
+	}
+	href, ok := m.Attrs["href"].(string)
+	if !ok || strings.TrimSpace(href) == "" {
+		return fmt.Errorf("links need an href")
+	}
+	u, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return fmt.Errorf("invalid link href")
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "", "http", "https", "mailto":
+		return nil
+	}
+	return fmt.Errorf("link scheme %q is not allowed", u.Scheme)
+}
+
+// intAttr reads a whole-number attribute (JSON numbers decode as float64).
+func intAttr(attrs map[string]any, key string) (int, bool) {
+	f, ok := attrs[key].(float64)
+	if !ok || f != math.Trunc(f) || math.Abs(f) > 1e9 {
+		return 0, false
+	}
+	return int(f), true
+}
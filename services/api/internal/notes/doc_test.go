@@ -0,0 +1,90 @@
+package notes
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParseDocRejects(t *testing.T) {
+	cases := []struct {
+		name string
+		doc  string
+		want string
+	}{
+		{"not json", `{"type":`, "not a document"},
+		{"root", `{"type":"paragraph"}`, "root must be a doc node"},
+		{"unknown field", `{"type":"doc","content":[],"foo":1}`, "not a document"},
+		{"unknown node", `{"type":"doc","content":[{"type":"table"}]}`, `doc.content[0]: unknown node type "table"`},
+		{"heading level", `{"type":"doc","content":[{"type":"heading","attrs":{"level":4}}]}`, "heading level must be 1..3"},
+		{"inline in doc", `{"type":"doc","content":[{"type":"text","text":"x"}]}`, "doc cannot contain text"},
+		{"empty text", `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":""}]}]}`, "text nodes need text"},
+		{"list item start", `{"type":"doc","content":[{"type":"bulletList","content":[{"type":"listItem","content":[{"type":"codeBlock"}]}]}]}`, "must start with a paragraph"},
+		{"empty list", `{"type":"doc","content":[{"type":"bulletList","content":[]}]}`, "needs at least 1"},
+		{"javascript link", `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"x","marks":[{"type":"link","attrs":{"href":"javascript:alert(1)"}}]}]}]}`, `link scheme "javascript" is not allowed`},
+		{"unknown mark", `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"x","marks":[{"type":"highlight"}]}]}]}`, `unknown mark type "highlight"`},
+		{"code tabs", `{"type":"doc","content":[{"type":"codeTabs","attrs":{"tabs":[{"language":"go"}]}}]}`, "tabs[0].code must be a string"},
+	}
+	for _, tc := range cases {
+		_, err := parseDoc(json.RawMessage(tc.doc))
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: err = %v, want %q", tc.name, err, tc.want)
+		}
+	}
+	if _, err := parseDoc(defaultDoc()); err != nil {
+		t.Fatalf("default doc: %v", err)
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	text := func(s string, marks ...string) string {
+		ms := make([]string, 0, len(marks))
+		for _, m := range marks {
+			if strings.HasPrefix(m, "http") {
+				ms = append(ms, `{"type":"link","attrs":{"href":"`+m+`"}}`)
+			} else {
+				ms = append(ms, `{"type":"`+m+`"}`)
+			}
+		}
+		b, _ := json.Marshal(s)
+		return `{"type":"text","text":` + string(b) + `,"marks":[` + strings.Join(ms, ",") + `]}`
+	}
+	para := func(inline ...string) string {
+		return `{"type":"paragraph","content":[` + strings.Join(inline, ",") + `]}`
+	}
+	item := func(blocks ...string) string {
+		return `{"type":"listItem","content":[` + strings.Join(blocks, ",") + `]}`
+	}
+	cases := []struct {
+		name   string
+		blocks []string
+		want   string
+	}{
+		{"empty", nil, ""},
+		{"heading", []string{`{"type":"heading","attrs":{"level":2},"content":[` + text("Two pointers") + `]}`}, "## Two pointers\n"},
+		{"marks merge", []string{para(text("a "), text("bold ", "bold"), text("and italic", "bold", "italic"), text(" end"))}, "a **bold *and italic*** end\n"},
+		{"link", []string{para(text("see "), text("docs", "https://go.dev/doc"))}, "see [docs](https://go.dev/doc)\n"},
+		{"code span", []string{para(text("use "), text("a`b", "code"))}, "use ``a`b``\n"},
+		{"escaping", []string{para(text("# not a heading *x* [y] snake_case _z_"))}, "\\# not a heading \\*x\\* \\[y\\] snake_case \\_z\\_\n"},
+		{"ordered start", []string{para(text("1. not a list"))}, "1\\. not a list\n"},
+		{"hard break", []string{para(text("a"), `{"type":"hardBreak"}`, text("b"))}, "a  \nb\n"},
+		{"blank paragraph dropped", []string{para(text("a")), para(), para(text("b"))}, "a\n\nb\n"},
+		{"nested list", []string{`{"type":"bulletList","content":[` +
+			item(para(text("one")), `{"type":"orderedList","attrs":{"start":3},"content":[`+item(para(text("three")))+`,`+item(para(text("four")))+`]}`) + `,` +
+			item(para(text("two"))) + `]}`}, "- one\n  3. three\n  4. four\n- two\n"},
+		{"blockquote", []string{`{"type":"blockquote","content":[` + para(text("a")) + `,` + para(text("b")) + `]}`}, "> a\n>\n> b\n"},
+		{"code block", []string{`{"type":"codeBlock","attrs":{"language":"go"},"content":[` + text("x := \"```\"\n") + `]}`}, "````go\nx := \"```\"\n````\n"},
+		{"code tabs", []string{`{"type":"codeTabs","attrs":{"activeIndex":0,"tabs":[{"language":"Python 3","code":"print(1)\n"},{"language":"","code":"  "},{"language":"","code":"x"}]}}`}, "```python\nprint(1)\n```\n\n```text\nx\n```\n"},
+		{"rule", []string{para(text("a")), `{"type":"horizontalRule"}`, para(text("b"))}, "a\n\n---\n\nb\n"},
+	}
+	for _, tc := range cases {
+		raw := `{"type":"doc","content":[` + strings.Join(tc.blocks, ",") + `]}`
+		doc, err := parseDoc(json.RawMessage(raw))
+		if err != nil {
+			t.Fatalf("%s: parseDoc: %v", tc.name, err)
+		}
+		if got := doc.markdown(); got != tc.want {
+			t.Errorf("%s: markdown =\n%q\nwant\n%q", tc.name, got, tc.want)
+		}
+	}
+}
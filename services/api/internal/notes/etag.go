@@ -0,0 +1,60 @@
+package notes
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrConflict means the note changed since the revision the client edited.
+var ErrConflict = errors.New("notes changed since they were loaded")
+
+// etag is the note's entity tag: its revision number, "0" before the first save.
+func etag(rev int) string {
+	return `"` + strconv.Itoa(rev) + `"`
+}
+
+// IfMatch is a parsed If-Match header. Any ("*") accepts any existing note;
+// otherwise the save may only replace one of Revs, where 0 means no note yet.
+type IfMatch struct {
+	Any  bool
+	Revs []int
+}
+
+// allows reports whether a save may replace revision prev (0 when the note
+// did not exist).
+func (m IfMatch) allows(prev int) bool {
+	if m.Any {
+		return prev > 0
+	}
+	for _, r := range m.Revs {
+		if r == prev {
+			return true
+		}
+	}
+	return false
+}
+
+// parseIfMatch reads an If-Match header, or returns nil when there is none.
+// Weak and unrecognized tags never match, as If-Match compares strongly.
+func parseIfMatch(header string) *IfMatch {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return nil
+	}
+	m := &IfMatch{}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			m.Any = true
+			continue
+		}
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			continue
+		}
+		if rev, err := strconv.Atoi(tag[1 : len(tag)-1]); err == nil && rev >= 0 {
+			m.Revs = append(m.Revs, rev)
+		}
+	}
+	return m
+}
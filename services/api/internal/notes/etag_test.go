@@ -0,0 +1,33 @@
+package notes
+
+import "testing"
+
+func TestParseIfMatch(t *testing.T) {
+	if parseIfMatch("  ") != nil {
+		t.Fatalf("no header should mean no precondition")
+	}
+	cases := []struct {
+		header string
+		prev   int
+		want   bool
+	}{
+		{`"3"`, 3, true},
+		{`"3"`, 4, false},
+		{`"0"`, 0, true},      // create only
+		{`"0"`, 1, false},     // someone created it first
+		{`"2", "5"`, 5, true}, // any listed revision
+		{`*`, 7, true},        // any existing note
+		{`*`, 0, false},       // but not a missing one
+		{`W/"3"`, 3, false},   // weak tags never match
+		{`3`, 3, false},       // unquoted is not an entity tag
+		{`"abc"`, 0, false},   // neither is a foreign one
+	}
+	for _, c := range cases {
+		if got := parseIfMatch(c.header).allows(c.prev); got != c.want {
+			t.Fatalf("If-Match %s over rev %d: allows = %v, want %v", c.header, c.prev, got, c.want)
+		}
+	}
+	if etag(4) != `"4"` || !parseIfMatch(etag(4)).allows(4) {
+		t.Fatalf("an ETag from Get should match its own revision")
+	}
+}
@@ -2,7 +2,10 @@ package notes
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/go-chi/chi/v5"
@@ -24,6 +27,7 @@ type getResponse struct {
 	ProblemID   string          `json:"problem_id"`
 	ContentMD   string          `json:"content_md"`
 	ContentJSON json.RawMessage `json:"content_json"`
+	Rev         int             `json:"rev"`
 	UpdatedAt   *string         `json:"updated_at,omitempty"`
 }
 
@@ -47,46 +51,81 @@ func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	resp, err := h.current(r, userID, problemID)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load notes")
+		return
+	}
+	w.Header().Set("ETag", etag(resp.Rev))
+	httpx.WriteJSON(w, http.StatusOK, resp)
+}
+
+// current loads the note as Get returns it.
+func (h *Handler) current(r *http.Request, userID string, problemID string) (getResponse, error) {
 	n, err := h.repo.Get(r.Context(), userID, problemID)
 	if err != nil {
 		if err == db.ErrNotFound {
 			// Return an empty doc so the editor can always render.
-			resp := getResponse{
+			return getResponse{
 				Exists:      false,
 				ProblemID:   problemID,
 				ContentMD:   "",
 				ContentJSON: defaultDoc(),
-			}
-			httpx.WriteJSON(w, http.StatusOK, resp)
-			return
+			}, nil
 		}
-		httpx.WriteError(w, http.StatusInternalServerError, "failed to load notes")
-		return
+		return getResponse{}, err
 	}
 	ts := n.UpdatedAt.UTC().Format(timeRFC3339Milli)
-	httpx.WriteJSON(w, http.StatusOK, getResponse{
+	return getResponse{
 		Exists:      true,
 		ProblemID:   n.ProblemID,
 		ContentMD:   n.ContentMD,
 		ContentJSON: n.ContentJSON,
+		Rev:         n.Rev,
 		UpdatedAt:   &ts,
-	})
+	}, nil
+}
+
+type conflictResponse struct {
+	Error   string      `json:"error"`
+	Current getResponse `json:"current"`
+}
+
+// writeConflict answers a failed If-Match with the server's current version,
+// so the client can merge or ask the user.
+func (h *Handler) writeConflict(w http.ResponseWriter, r *http.Request, userID string, problemID string) {
+	cur, err := h.current(r, userID, problemID)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load notes")
+		return
+	}
+	w.Header().Set("ETag", etag(cur.Rev))
+	httpx.WriteJSON(w, http.StatusConflict, conflictResponse{Error: ErrConflict.Error(), Current: cur})
 }
 
 const timeRFC3339Milli = "2006-01-02T15:04:05.000Z07:00"
 
+// putRequest carries the editor document. content_md is accepted for older
+// clients but ignored: the stored markdown is always rendered from content_json.
 type putRequest struct {
 	ContentMD   string          `json:"content_md"`
 	ContentJSON json.RawMessage `json:"content_json"`
 }
 
 type putResponse struct {
-	ProblemID string  `json:"problem_id"`
-	UpdatedAt string  `json:"updated_at"`
-	Exists    bool    `json:"exists"`
-	Bytes     int     `json:"bytes"`
+	ProblemID    string  `json:"problem_id"`
+	UpdatedAt    string  `json:"updated_at"`
+	Exists       bool    `json:"exists"`
+	Bytes        int     `json:"bytes"`
+	Rev          int     `json:"rev"`
+	RestoredFrom *int    `json:"restored_from,omitempty"`
+	ContentMD    string  `json:"content_md"`
 }
 
+// Put validates content_json against the editor schema and saves it, with the
+// markdown derived from it, as a new revision. With If-Match (the ETag from
+// Get, or "0" when creating), a save over any other revision fails with 409
+// and the current version.
 func (h *Handler) Put(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPut {
 		httpx.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
@@ -110,20 +149,225 @@ func (h *Handler) Put(w http.ResponseWriter, r *http.Request) {
 		httpx.WriteError(w, http.StatusBadRequest, "invalid json body")
 		return
 	}
-	if len(req.ContentJSON) == 0 {
+	if len(req.ContentJSON) == 0 || string(req.ContentJSON) == "null" {
+		if strings.TrimSpace(req.ContentMD) != "" {
+			httpx.WriteError(w, http.StatusBadRequest, "content_json required; content_md is derived from it")
+			return
+		}
 		req.ContentJSON = defaultDoc()
 	}
+	doc, err := parseDoc(req.ContentJSON)
+	if err != nil {
+		httpx.WriteError(w, http.StatusBadRequest, "invalid content_json: "+err.Error())
+		return
+	}
 
-	n, err := h.repo.Upsert(r.Context(), userID, problemID, req.ContentMD, req.ContentJSON)
+	n, err := h.repo.Upsert(r.Context(), userID, problemID, doc.markdown(), req.ContentJSON, parseIfMatch(r.Header.Get("If-Match")))
 	if err != nil {
+		if errors.Is(err, ErrConflict) {
+			h.writeConflict(w, r, userID, problemID)
+			return
+		}
 		httpx.WriteError(w, http.StatusInternalServerError, "failed to save notes")
 		return
 	}
 
+	w.Header().Set("ETag", etag(n.Rev))
 	httpx.WriteJSON(w, http.StatusOK, putResponse{
 		ProblemID: n.ProblemID,
 		UpdatedAt: n.UpdatedAt.UTC().Format(timeRFC3339Milli),
 		Exists:    true,
-		Bytes:     len(req.ContentMD) + len(req.ContentJSON),
+		Bytes:     len(n.ContentMD) + len(n.ContentJSON),
+		Rev:       n.Rev,
+		ContentMD: n.ContentMD,
+	})
+}
+
+type revisionItem struct {
+	Rev          int    `json:"rev"`
+	Bytes        int    `json:"bytes"`
+	RestoredFrom *int   `json:"restored_from,omitempty"`
+	CreatedAt    string `json:"created_at"`
+}
+
+type revisionsResponse struct {
+	ProblemID     string         `json:"problem_id"`
+	KeepRevisions int            `json:"keep_revisions"`
+	Revisions     []revisionItem `json:"revisions"`
+}
+
+// Revisions lists the retained revisions of the note, newest first. Only the
+// latest KeepRevisions saves are kept.
+func (h *Handler) Revisions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httpx.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID, ok := reqctx.UserIDFromContext(r.Context())
+	if !ok {
+		httpx.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	problemID := strings.TrimSpace(chi.URLParam(r, "id"))
+	if problemID == "" {
+		httpx.WriteError(w, http.StatusBadRequest, "id required")
+		return
+	}
+
+	revs, err := h.repo.Revisions(r.Context(), userID, problemID)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load revisions")
+		return
+	}
+	out := revisionsResponse{ProblemID: problemID, KeepRevisions: h.repo.KeepRevisions(), Revisions: make([]revisionItem, 0, len(revs))}
+	for _, rv := range revs {
+		out.Revisions = append(out.Revisions, revisionItem{
+			Rev:          rv.Rev,
+			Bytes:        rv.Bytes,
+			RestoredFrom: rv.RestoredFrom,
+			CreatedAt:    rv.CreatedAt.UTC().Format(timeRFC3339Milli),
+		})
+	}
+	httpx.WriteJSON(w, http.StatusOK, out)
+}
+
+type diffResponse struct {
+	ProblemID string `json:"problem_id"`
+	From      int    `json:"from"`
+	To        int    `json:"to"`
+	Added     int    `json:"added"`
+	Removed   int    `json:"removed"`
+	Diff      string `json:"diff"`
+}
+
+func parseRev(v string) (int, bool) {
+	n, err := strconv.Atoi(strings.TrimSpace(v))
+	return n, err == nil && n >= 1
+}
+
+// Diff compares the markdown of two revisions line by line and returns a
+// unified diff. to defaults to the current revision.
+func (h *Handler) Diff(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httpx.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID, ok := reqctx.UserIDFromContext(r.Context())
+	if !ok {
+		httpx.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	problemID := strings.TrimSpace(chi.URLParam(r, "id"))
+	if problemID == "" {
+		httpx.WriteError(w, http.StatusBadRequest, "id required")
+		return
+	}
+	from, ok := parseRev(r.URL.Query().Get("from"))
+	if !ok {
+		httpx.WriteError(w, http.StatusBadRequest, "from must be a revision number")
+		return
+	}
+	var to int
+	if v := r.URL.Query().Get("to"); v != "" {
+		if to, ok = parseRev(v); !ok {
+			httpx.WriteError(w, http.StatusBadRequest, "to must be a revision number")
+			return
+		}
+	} else {
+		n, err := h.repo.Get(r.Context(), userID, problemID)
+		if err != nil {
+			if err == db.ErrNotFound {
+				httpx.WriteError(w, http.StatusNotFound, "notes not found")
+				return
+			}
+			httpx.WriteError(w, http.StatusInternalServerError, "failed to load notes")
+			return
+		}
+		to = n.Rev
+	}
+
+	load := func(rev int) (Revision, bool) {
+		rv, err := h.repo.GetRevision(r.Context(), userID, problemID, rev)
+		if err != nil {
+			if err == db.ErrNotFound {
+				httpx.WriteError(w, http.StatusNotFound, fmt.Sprintf("revision %d not found", rev))
+				return Revision{}, false
+			}
+			httpx.WriteError(w, http.StatusInternalServerError, "failed to load revision")
+			return Revision{}, false
+		}
+		return rv, true
+	}
+	a, ok := load(from)
+	if !ok {
+		return
+	}
+	b, ok := load(to)
+	if !ok {
+		return
+	}
+	lines := diffLines(splitLines(a.ContentMD), splitLines(b.ContentMD))
+	out := diffResponse{
+		ProblemID: problemID,
+		From:      from,
+		To:        to,
+		Diff:      unifiedDiff(fmt.Sprintf("rev %d", from), fmt.Sprintf("rev %d", to), lines),
+	}
+	for _, l := range lines {
+		switch l.Op {
+		case '+':
+			out.Added++
+		case '-':
+			out.Removed++
+		}
+	}
+	httpx.WriteJSON(w, http.StatusOK, out)
+}
+
+// Restore makes an old revision current again by saving its content as a new
+// revision, so the restore itself can be undone. It honors If-Match like Put.
+func (h *Handler) Restore(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httpx.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID, ok := reqctx.UserIDFromContext(r.Context())
+	if !ok {
+		httpx.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	problemID := strings.TrimSpace(chi.URLParam(r, "id"))
+	if problemID == "" {
+		httpx.WriteError(w, http.StatusBadRequest, "id required")
+		return
+	}
+	rev, ok := parseRev(chi.URLParam(r, "rev"))
+	if !ok {
+		httpx.WriteError(w, http.StatusBadRequest, "rev must be a revision number")
+		return
+	}
+
+	n, err := h.repo.Restore(r.Context(), userID, problemID, rev, parseIfMatch(r.Header.Get("If-Match")))
+	if err != nil {
+		if err == db.ErrNotFound {
+			httpx.WriteError(w, http.StatusNotFound, "revision not found")
+			return
+		}
+		if errors.Is(err, ErrConflict) {
+			h.writeConflict(w, r, userID, problemID)
+			return
+		}
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to restore notes")
+		return
+	}
+	w.Header().Set("ETag", etag(n.Rev))
+	httpx.WriteJSON(w, http.StatusOK, putResponse{
+		ProblemID:    n.ProblemID,
+		UpdatedAt:    n.UpdatedAt.UTC().Format(timeRFC3339Milli),
+		Exists:       true,
+		Bytes:        len(n.ContentMD) + len(n.ContentJSON),
+		Rev:          n.Rev,
+		RestoredFrom: &rev,
+		ContentMD:    n.ContentMD,
 	})
 }
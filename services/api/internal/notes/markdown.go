@@ -0,0 +1,331 @@
+package notes
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// markdown renders a validated document as canonical CommonMark (with GFM
+// strikethrough): blocks separated by a blank line, "-" bullets, fenced code,
+// and text escaped so it reads back as the same document. Underline has no
+// markdown form and renders as plain text; codeTabs become one fenced block
+// per tab.
+func (n docNode) markdown() string {
+	blocks := renderBlocks(n.Content)
+	if len(blocks) == 0 {
+		return ""
+	}
+	return strings.Join(blocks, "\n\n") + "\n"
+}
+
+// renderBlocks renders each block, dropping empty ones such as blank paragraphs.
+func renderBlocks(nodes []docNode) []string {
+	out := make([]string, 0, len(nodes))
+	for _, c := range nodes {
+		if s := renderBlock(c); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func renderBlock(n docNode) string {
+	switch n.Type {
+	case "paragraph":
+		return escapeLineStarts(renderInline(n.Content))
+	case "heading":
+		text := strings.ReplaceAll(renderInline(n.Content), hardBreakMD, " ")
+		if text == "" {
+			return ""
+		}
+		level, _ := intAttr(n.Attrs, "level")
+		return strings.Repeat("#", level) + " " + text
+	case "blockquote":
+		return prefixLines(strings.Join(renderBlocks(n.Content), "\n\n"), "> ", ">")
+	case "bulletList", "orderedList":
+		start, ok := intAttr(n.Attrs, "start")
+		if !ok {
+			start = 1
+		}
+		items := make([]string, 0, len(n.Content))
+		for i, item := range n.Content {
+			marker := "- "
+			if n.Type == "orderedList" {
+				marker = fmt.Sprintf("%d. ", start+i)
+			}
+			items = append(items, renderListItem(item, marker))
+		}
+		return strings.Join(items, "\n")
+	case "codeBlock":
+		lang, _ := n.Attrs["language"].(string)
+		var sb strings.Builder
+		for _, c := range n.Content {
+			sb.WriteString(*c.Text)
+		}
+		return fencedCode(strings.TrimSpace(lang), sb.String())
+	case "codeTabs":
+		tabs, _ := n.Attrs["tabs"].([]any)
+		out := make([]string, 0, len(tabs))
+		for _, t := range tabs {
+			tab := t.(map[string]any)
+			code := strings.TrimRight(tab["code"].(string), " \t\n")
+			if code == "" {
+				continue
+			}
+			out = append(out, fencedCode(tabLanguage(tab["language"].(string)), code))
+		}
+		return strings.Join(out, "\n\n")
+	case "horizontalRule":
+		return "---"
+	}
+	return ""
+}
+
+// renderListItem puts the marker on the item's first line and indents the
+// rest under it. A nested list follows its paragraph directly, keeping the
+// list tight.
+func renderListItem(item docNode, marker string) string {
+	var sb strings.Builder
+	for i, c := range item.Content {
+		s := renderBlock(c)
+		if s == "" {
+			continue
+		}
+		if sb.Len() > 0 {
+			if i > 0 && (c.Type == "bulletList" || c.Type == "orderedList") {
+				sb.WriteString("\n")
+			} else {
+				sb.WriteString("\n\n")
+			}
+		}
+		sb.WriteString(s)
+	}
+	if sb.Len() == 0 {
+		return strings.TrimRight(marker, " ")
+	}
+	pad := strings.Repeat(" ", len(marker))
+	lines := strings.Split(sb.String(), "\n")
+	for i, l := range lines {
+		switch {
+		case i == 0:
+			lines[i] = marker + l
+		case l != "":
+			lines[i] = pad + l
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func prefixLines(s string, prefix string, blank string) string {
+	if s == "" {
+		return ""
+	}
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		if l == "" {
+			lines[i] = blank
+		} else {
+			lines[i] = prefix + l
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// tabLanguage matches the editor's reading of a tab language: the first word,
+// lower case, "text" when empty.
+func tabLanguage(lang string) string {
+	f := strings.Fields(strings.ToLower(lang))
+	if len(f) == 0 {
+		return "text"
+	}
+	if l := strings.TrimSuffix(f[0], "[]"); l != "" {
+		return l
+	}
+	return "text"
+}
+
+// longestRun is the length of the longest run of c in s.
+func longestRun(s string, c rune) int {
+	best, cur := 0, 0
+	for _, r := range s {
+		if r == c {
+			cur++
+			best = max(best, cur)
+		} else {
+			cur = 0
+		}
+	}
+	return best
+}
+
+func fencedCode(lang string, code string) string {
+	fence := strings.Repeat("`", max(3, longestRun(code, '`')+1))
+	if code == "" {
+		return fence + lang + "\n" + fence
+	}
+	return fence + lang + "\n" + strings.TrimSuffix(code, "\n") + "\n" + fence
+}
+
+func codeSpan(code string) string {
+	ticks := strings.Repeat("`", longestRun(code, '`')+1)
+	if strings.HasPrefix(code, "`") || strings.HasSuffix(code, "`") || strings.HasPrefix(code, " ") || strings.HasSuffix(code, " ") {
+		code = " " + code + " "
+	}
+	return ticks + code + ticks
+}
+
+const hardBreakMD = "  \n"
+
+// Marks open outermost first; code is innermost and written as a span.
+var markRank = map[string]int{"link": 0, "bold": 1, "italic": 2, "strike": 3}
+
+func markOpen(m docMark) string {
+	switch m.Type {
+	case "link":
+		return "["
+	case "bold":
+		return "**"
+	case "italic":
+		return "*"
+	}
+	return "~~"
+}
+
+func markClose(m docMark) string {
+	switch m.Type {
+	case "link":
+		href, _ := m.Attrs["href"].(string)
+		href = strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29").Replace(strings.TrimSpace(href))
+		return "](" + href + ")"
+	case "bold":
+		return "**"
+	case "italic":
+		return "*"
+	}
+	return "~~"
+}
+
+func sameMark(a docMark, b docMark) bool {
+	if a.Type != b.Type {
+		return false
+	}
+	if a.Type != "link" {
+		return true
+	}
+	ah, _ := a.Attrs["href"].(string)
+	bh, _ := b.Attrs["href"].(string)
+	return ah == bh
+}
+
+// renderInline writes inline nodes, keeping a mark open across adjacent text
+// that shares it and moving whitespace outside delimiters, since "**a **" is
+// not bold in CommonMark.
+func renderInline(nodes []docNode) string {
+	var sb strings.Builder
+	var open []docMark
+	pending := "" // trailing whitespace held back until the next delimiters are written
+	closeFrom := func(k int) {
+		for i := len(open) - 1; i >= k; i-- {
+			sb.WriteString(markClose(open[i]))
+		}
+		open = open[:k]
+	}
+	for _, n := range nodes {
+		if n.Type == "hardBreak" {
+			sb.WriteString(hardBreakMD)
+			pending = ""
+			continue
+		}
+		text := *n.Text
+		marks := make([]docMark, 0, len(n.Marks))
+		code := false
+		for _, m := range n.Marks {
+			if m.Type == "code" {
+				code = true
+			} else if _, ok := markRank[m.Type]; ok {
+				marks = append(marks, m)
+			}
+		}
+		sort.SliceStable(marks, func(i, j int) bool { return markRank[marks[i].Type] < markRank[marks[j].Type] })
+
+		lead, core, trail := "", text, ""
+		if !code {
+			core = strings.TrimLeftFunc(text, unicode.IsSpace)
+			lead = text[:len(text)-len(core)]
+			core = strings.TrimRightFunc(core, unicode.IsSpace)
+			trail = text[len(lead)+len(core):]
+			if core == "" {
+				pending += text
+				continue
+			}
+		}
+		k := 0
+		for k < len(open) && k < len(marks) && sameMark(open[k], marks[k]) {
+			k++
+		}
+		closeFrom(k)
+		sb.WriteString(pending + lead)
+		pending = ""
+		for _, m := range marks[k:] {
+			sb.WriteString(markOpen(m))
+			open = append(open, m)
+		}
+		if code {
+			sb.WriteString(codeSpan(core))
+		} else {
+			sb.WriteString(escapeText(core))
+		}
+		pending = trail
+	}
+	closeFrom(0)
+	return strings.TrimRightFunc(sb.String(), unicode.IsSpace)
+}
+
+// escapeText backslash-escapes characters that would start markup. Underscores
+// inside words are left alone, since they cannot open emphasis there.
+func escapeText(s string) string {
+	rs := []rune(s)
+	var sb strings.Builder
+	for i, r := range rs {
+		switch r {
+		case '\\', '`', '*', '[', ']', '<', '~':
+			sb.WriteByte('\\')
+		case '_':
+			inWord := i > 0 && i < len(rs)-1 && isWordRune(rs[i-1]) && isWordRune(rs[i+1])
+			if !inWord {
+				sb.WriteByte('\\')
+			}
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+func isWordRune(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }
+
+var (
+	blockMarkerRe = regexp.MustCompile(`^(#|>|[-+](\s|$))`)
+	orderedRe     = regexp.MustCompile(`^\d+[.)](\s|$)`)
+	ruleRe        = regexp.MustCompile(`^(-+|=+)\s*$`)
+)
+
+// escapeLineStarts keeps paragraph lines from reading as headings, quotes,
+// list items or rules.
+func escapeLineStarts(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		body := strings.TrimLeft(l, " ")
+		indent := l[:len(l)-len(body)]
+		switch {
+		case blockMarkerRe.MatchString(body), ruleRe.MatchString(body):
+			lines[i] = indent + `\` + body
+		case orderedRe.MatchString(body):
+			j := strings.IndexAny(body, ".)")
+			lines[i] = indent + body[:j] + `\` + body[j:]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
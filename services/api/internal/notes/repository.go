@@ -16,25 +16,52 @@ type Note struct {
 	ProblemID   string          `json:"problem_id"`
 	ContentMD   string          `json:"content_md"`
 	ContentJSON json.RawMessage `json:"content_json"`
+	Rev         int             `json:"rev"`
 	CreatedAt   time.Time       `json:"created_at"`
 	UpdatedAt   time.Time       `json:"updated_at"`
 }
 
+// Revision is one saved version of a note. RestoredFrom is set when the save
+// restored an older revision.
+type Revision struct {
+	Rev          int
+	ContentMD    string
+	ContentJSON  json.RawMessage
+	RestoredFrom *int
+	CreatedAt    time.Time
+}
+
+// RevisionInfo describes a revision without its content.
+type RevisionInfo struct {
+	Rev          int
+	Bytes        int
+	RestoredFrom *int
+	CreatedAt    time.Time
+}
+
 type Repository struct {
-	pool *pgxpool.Pool
+	pool          *pgxpool.Pool
+	keepRevisions int
 }
 
-func NewRepository(pool *pgxpool.Pool) *Repository {
-	return &Repository{pool: pool}
+// NewRepository keeps the latest keepRevisions revisions of each note (at least one).
+func NewRepository(pool *pgxpool.Pool, keepRevisions int) *Repository {
+	if keepRevisions < 1 {
+		keepRevisions = 1
+	}
+	return &Repository{pool: pool, keepRevisions: keepRevisions}
 }
 
+// KeepRevisions is how many revisions each note retains.
+func (r *Repository) KeepRevisions() int { return r.keepRevisions }
+
 func (r *Repository) Get(ctx context.Context, userID string, problemID string) (Note, error) {
 	var n Note
 	err := r.pool.QueryRow(ctx, `
-		SELECT user_id::text, problem_id::text, content_md, content_json, created_at, updated_at
+		SELECT user_id::text, problem_id::text, content_md, content_json, rev, created_at, updated_at
 		FROM problem_notes
 		WHERE user_id = $1 AND problem_id = $2
-	`, userID, problemID).Scan(&n.UserID, &n.ProblemID, &n.ContentMD, &n.ContentJSON, &n.CreatedAt, &n.UpdatedAt)
+	`, userID, problemID).Scan(&n.UserID, &n.ProblemID, &n.ContentMD, &n.ContentJSON, &n.Rev, &n.CreatedAt, &n.UpdatedAt)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return Note{}, db.ErrNotFound
@@ -44,20 +71,183 @@ func (r *Repository) Get(ctx context.Context, userID string, problemID string) (
 	return n, nil
 }
 
-func (r *Repository) Upsert(ctx context.Context, userID string, problemID string, md string, content json.RawMessage) (Note, error) {
+// Upsert saves the note and appends it as a new revision. With ifMatch set, it
+// returns ErrConflict unless the note it replaces is one ifMatch allows.
+func (r *Repository) Upsert(ctx context.Context, userID string, problemID string, md string, content json.RawMessage, ifMatch *IfMatch) (Note, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return Note{}, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	n, err := r.saveTx(ctx, tx, userID, problemID, md, content, nil, ifMatch)
+	if err != nil {
+		return Note{}, err
+	}
+	return n, tx.Commit(ctx)
+}
+
+// Restore saves the content of revision rev as a new revision. It returns
+// db.ErrNotFound when the revision does not exist or was pruned, and checks
+// ifMatch like Upsert.
+func (r *Repository) Restore(ctx context.Context, userID string, problemID string, rev int, ifMatch *IfMatch) (Note, error) {
+	old, err := r.GetRevision(ctx, userID, problemID, rev)
+	if err != nil {
+		return Note{}, err
+	}
+	// Revisions saved before content_json was validated keep their markdown
+	// if they do not parse.
+	md := old.ContentMD
+	if doc, err := parseDoc(old.ContentJSON); err == nil {
+		md = doc.markdown()
+	}
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return Note{}, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	n, err := r.saveTx(ctx, tx, userID, problemID, md, old.ContentJSON, &rev, ifMatch)
+	if err != nil {
+		return Note{}, err
+	}
+	return n, tx.Commit(ctx)
+}
+
+// saveTx writes the note, bumping its rev, records the revision and prunes
+// revisions beyond the retention count. The upsert locks the row, so the rev
+// it returns tells which revision was replaced; a mismatch with ifMatch fails
+// the save and the caller rolls back.
+func (r *Repository) saveTx(ctx context.Context, tx pgx.Tx, userID string, problemID string, md string, content json.RawMessage, restoredFrom *int, ifMatch *IfMatch) (Note, error) {
 	var n Note
-	err := r.pool.QueryRow(ctx, `
+	err := tx.QueryRow(ctx, `
 		INSERT INTO problem_notes (user_id, problem_id, content_md, content_json)
 		VALUES ($1, $2, $3, $4)
 		ON CONFLICT (user_id, problem_id) DO UPDATE
 		SET content_md = EXCLUDED.content_md,
 		    content_json = EXCLUDED.content_json,
+		    rev = problem_notes.rev + 1,
 		    updated_at = now()
-		RETURNING user_id::text, problem_id::text, content_md, content_json, created_at, updated_at
-	`, userID, problemID, md, content).Scan(&n.UserID, &n.ProblemID, &n.ContentMD, &n.ContentJSON, &n.CreatedAt, &n.UpdatedAt)
+		RETURNING user_id::text, problem_id::text, content_md, content_json, rev, created_at, updated_at
+	`, userID, problemID, md, content).Scan(&n.UserID, &n.ProblemID, &n.ContentMD, &n.ContentJSON, &n.Rev, &n.CreatedAt, &n.UpdatedAt)
 	if err != nil {
 		return Note{}, err
 	}
+	if ifMatch != nil && !ifMatch.allows(n.Rev-1) {
+		return Note{}, ErrConflict
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO problem_note_revisions (user_id, problem_id, rev, content_md, content_json, restored_from, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, userID, problemID, n.Rev, md, content, restoredFrom, n.UpdatedAt); err != nil {
+		return Note{}, err
+	}
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM problem_note_revisions
+		WHERE user_id = $1 AND problem_id = $2 AND rev <= $3
+	`, userID, problemID, n.Rev-r.keepRevisions); err != nil {
+		return Note{}, err
+	}
 	return n, nil
 }
 
+// Revisions lists the retained revisions of a note, newest first.
+func (r *Repository) Revisions(ctx context.Context, userID string, problemID string) ([]RevisionInfo, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT rev, octet_length(content_md), restored_from, created_at
+		FROM problem_note_revisions
+		WHERE user_id = $1 AND problem_id = $2
+		ORDER BY rev DESC
+	`, userID, problemID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]RevisionInfo, 0)
+	for rows.Next() {
+		var ri RevisionInfo
+		if err := rows.Scan(&ri.Rev, &ri.Bytes, &ri.RestoredFrom, &ri.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, ri)
+	}
+	return out, rows.Err()
+}
+
+// GetRevision loads one revision, or db.ErrNotFound.
+func (r *Repository) GetRevision(ctx context.Context, userID string, problemID string, rev int) (Revision, error) {
+	var rv Revision
+	err := r.pool.QueryRow(ctx, `
+		SELECT rev, content_md, content_json, restored_from, created_at
+		FROM problem_note_revisions
+		WHERE user_id = $1 AND problem_id = $2 AND rev = $3
+	`, userID, problemID, rev).Scan(&rv.Rev, &rv.ContentMD, &rv.ContentJSON, &rv.RestoredFrom, &rv.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Revision{}, db.ErrNotFound
+		}
+		return Revision{}, err
+	}
+	return rv, nil
+}
+
+// RenderMarkdown re-derives content_md from content_json for the user's notes
+// and their current revisions, for notes saved before the server rendered it.
+// Notes whose JSON does not validate are left alone and counted as skipped.
+func (r *Repository) RenderMarkdown(ctx context.Context, userID string) (updated int, skipped int, err error) {
+	type stored struct {
+		problemID string
+		rev       int
+		md        string
+		content   json.RawMessage
+	}
+	rows, err := r.pool.Query(ctx, `
+		SELECT problem_id::text, rev, content_md, content_json
+		FROM problem_notes
+		WHERE user_id = $1
+	`, userID)
+	if err != nil {
+		return 0, 0, err
+	}
+	var notes []stored
+	for rows.Next() {
+		var s stored
+		if err := rows.Scan(&s.problemID, &s.rev, &s.md, &s.content); err != nil {
+			rows.Close()
+			return 0, 0, err
+		}
+		notes = append(notes, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, 0, err
+	}
+
+	for _, s := range notes {
+		doc, err := parseDoc(s.content)
+		if err != nil {
+			skipped++
+			continue
+		}
+		md := doc.markdown()
+		if md == s.md {
+			continue
+		}
+		// Matching on rev leaves a note alone if it was saved meanwhile.
+		ct, err := r.pool.Exec(ctx, `
+			WITH n AS (
+				UPDATE problem_notes SET content_md = $4
+				WHERE user_id = $1 AND problem_id = $2 AND rev = $3
+				RETURNING user_id, problem_id, rev
+			)
+			UPDATE problem_note_revisions pr SET content_md = $4
+			FROM n
+			WHERE pr.user_id = n.user_id AND pr.problem_id = n.problem_id AND pr.rev = n.rev
+		`, userID, s.problemID, s.rev, md)
+		if err != nil {
+			return updated, skipped, err
+		}
+		if ct.RowsAffected() > 0 {
+			updated++
+		}
+	}
+	return updated, skipped, nil
+}
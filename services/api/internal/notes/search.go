@@ -0,0 +1,167 @@
+package notes
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/httpx"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/mastery"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/reqctx"
+)
+
+// ts_headline marks matches with these control characters, which cannot
+// collide with the escaping applied to the rest of the snippet.
+const (
+	markStart = "\x02"
+	markStop  = "\x03"
+)
+
+var headlineOptions = `StartSel="` + markStart + `", StopSel="` + markStop + `", MaxFragments=2, MaxWords=30, MinWords=10, FragmentDelimiter=" … "`
+
+const maxSearchQueryLen = 200
+
+type SearchFilter struct {
+	Query      string
+	Topic      string
+	Difficulty string
+	Limit      int
+}
+
+type SearchHit struct {
+	ProblemID  string
+	Title      string
+	URL        string
+	Difficulty string
+	Topics     []string
+	Rank       float64
+	Snippet    string
+	UpdatedAt  time.Time
+}
+
+// Search finds the user's notes matching f.Query as a web-style query (quoted
+// phrases, "or", "-" to exclude), best match first. Snippet is ts_headline
+// output with matches between markStart and markStop.
+func (r *Repository) Search(ctx context.Context, userID string, f SearchFilter) ([]SearchHit, error) {
+	args := []any{userID, f.Query, headlineOptions}
+	filters := ""
+	if f.Topic != "" {
+		args = append(args, f.Topic)
+		filters += fmt.Sprintf(" AND EXISTS (SELECT 1 FROM unnest(p.topics) AS t(topic) WHERE lower(trim(t.topic)) = $%d)", len(args))
+	}
+	if f.Difficulty != "" {
+		args = append(args, f.Difficulty)
+		filters += fmt.Sprintf(" AND lower(p.difficulty) = $%d", len(args))
+	}
+	args = append(args, f.Limit)
+
+	rows, err := r.pool.Query(ctx, `
+		WITH q AS (SELECT websearch_to_tsquery('english', $2) AS query)
+		SELECT p.id::text, p.title, p.url, p.difficulty, p.topics,
+		       ts_rank_cd(n.search_tsv, q.query)::float8 AS rank,
+		       ts_headline('english', n.content_md, q.query, $3),
+		       n.updated_at
+		FROM problem_notes n
+		CROSS JOIN q
+		JOIN problems p ON p.id = n.problem_id
+		WHERE n.user_id = $1 AND n.search_tsv @@ q.query`+filters+`
+		ORDER BY rank DESC, n.updated_at DESC, p.id
+		LIMIT $`+strconv.Itoa(len(args)), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]SearchHit, 0)
+	for rows.Next() {
+		var h SearchHit
+		if err := rows.Scan(&h.ProblemID, &h.Title, &h.URL, &h.Difficulty, &h.Topics, &h.Rank, &h.Snippet, &h.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, h)
+	}
+	return out, rows.Err()
+}
+
+// snippetHTML turns a headline into one line of HTML-escaped text with the
+// matches wrapped in <mark>.
+func snippetHTML(headline string) string {
+	s := html.EscapeString(strings.Join(strings.Fields(headline), " "))
+	return strings.NewReplacer(markStart, "<mark>", markStop, "</mark>").Replace(s)
+}
+
+type searchResult struct {
+	ProblemID  string   `json:"problem_id"`
+	Title      string   `json:"title"`
+	URL        string   `json:"url"`
+	Difficulty string   `json:"difficulty"`
+	Topics     []string `json:"topics"`
+	Rank       float64  `json:"rank"`
+	Snippet    string   `json:"snippet"`
+	UpdatedAt  string   `json:"updated_at"`
+}
+
+type searchResponse struct {
+	Query   string         `json:"query"`
+	Results []searchResult `json:"results"`
+}
+
+// Search runs a full-text search over the user's notes. topic and difficulty
+// filter on the problem.
+func (h *Handler) Search(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httpx.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID, ok := reqctx.UserIDFromContext(r.Context())
+	if !ok {
+		httpx.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	q := r.URL.Query()
+	f := SearchFilter{
+		Query:      strings.TrimSpace(q.Get("q")),
+		Topic:      mastery.NormalizeTopic(q.Get("topic")),
+		Difficulty: strings.ToLower(strings.TrimSpace(q.Get("difficulty"))),
+		Limit:      20,
+	}
+	if f.Query == "" {
+		httpx.WriteError(w, http.StatusBadRequest, "q required")
+		return
+	}
+	if len(f.Query) > maxSearchQueryLen {
+		httpx.WriteError(w, http.StatusBadRequest, fmt.Sprintf("q must be at most %d bytes", maxSearchQueryLen))
+		return
+	}
+	if v := strings.TrimSpace(q.Get("limit")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 50 {
+			httpx.WriteError(w, http.StatusBadRequest, "limit must be 1..50")
+			return
+		}
+		f.Limit = n
+	}
+
+	hits, err := h.repo.Search(r.Context(), userID, f)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to search notes")
+		return
+	}
+	out := searchResponse{Query: f.Query, Results: make([]searchResult, 0, len(hits))}
+	for _, hit := range hits {
+		out.Results = append(out.Results, searchResult{
+			ProblemID:  hit.ProblemID,
+			Title:      hit.Title,
+			URL:        hit.URL,
+			Difficulty: hit.Difficulty,
+			Topics:     hit.Topics,
+			Rank:       hit.Rank,
+			Snippet:    snippetHTML(hit.Snippet),
+			UpdatedAt:  hit.UpdatedAt.UTC().Format(timeRFC3339Milli),
+		})
+	}
+	httpx.WriteJSON(w, http.StatusOK, out)
+}
@@ -0,0 +1,11 @@
+package notes
+
+import "testing"
+
+func TestSnippetHTML(t *testing.T) {
+	headline := "Use a " + markStart + "heap" + markStop + " of <size k>\n\nand pop when\tit grows … keep the " + markStart + "heap" + markStop + " & sort"
+	want := "Use a <mark>heap</mark> of &lt;size k&gt; and pop when it grows … keep the <mark>heap</mark> &amp; sort"
+	if got := snippetHTML(headline); got != want {
+		t.Fatalf("snippetHTML = %q, want %q", got, want)
+	}
+}
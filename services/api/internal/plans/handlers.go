@@ -0,0 +1,316 @@
+package plans
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/db"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/httpx"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/lists"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/problems"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/reqctx"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/users"
+)
+
+type Handler struct {
+	pool     *pgxpool.Pool
+	repo     *Repository
+	lists    *lists.Repository
+	problems *problems.Repository
+	users    *users.Repository
+}
+
+func NewHandler(pool *pgxpool.Pool, repo *Repository, listsRepo *lists.Repository, problemsRepo *problems.Repository, usersRepo *users.Repository) *Handler {
+	return &Handler{pool: pool, repo: repo, lists: listsRepo, problems: problemsRepo, users: usersRepo}
+}
+
+// userClock carries what every plan endpoint needs to turn calendar days into due times.
+type userClock struct {
+	loc       *time.Location
+	today     time.Time
+	dueHour   int
+	dueMinute int
+}
+
+// introduceAt returns the UTC due timestamp for a plan day at the user's due time.
+func (c userClock) introduceAt(day time.Time) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), c.dueHour, c.dueMinute, 0, 0, c.loc).UTC()
+}
+
+func (h *Handler) clock(r *http.Request, userID string) (userClock, error) {
+	settings, err := h.users.GetSettings(r.Context(), userID)
+	if err != nil {
+		return userClock{}, err
+	}
+	loc, err := time.LoadLocation(settings.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	return userClock{
+		loc:       loc,
+		today:     civilDate(time.Now().UTC(), loc),
+		dueHour:   settings.DueHourLocal,
+		dueMinute: settings.DueMinuteLocal,
+	}, nil
+}
+
+type createPlanRequest struct {
+	ListID        string `json:"list_id"`
+	Name          string `json:"name"`
+	TargetDate    string `json:"target_date"`
+	StudyWeekdays []int  `json:"study_weekdays"`
+	MaxNewPerDay  int    `json:"max_new_per_day"`
+}
+
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httpx.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID, ok := reqctx.UserIDFromContext(r.Context())
+	if !ok {
+		httpx.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	var req createPlanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpx.WriteError(w, http.StatusBadRequest, "invalid json body")
+		return
+	}
+	req.ListID = strings.TrimSpace(req.ListID)
+	req.Name = strings.TrimSpace(req.Name)
+	if req.ListID == "" {
+		httpx.WriteError(w, http.StatusBadRequest, "list_id required")
+		return
+	}
+	target, err := parseDate(strings.TrimSpace(req.TargetDate))
+	if err != nil {
+		httpx.WriteError(w, http.StatusBadRequest, "target_date must be YYYY-MM-DD")
+		return
+	}
+	weekdays, err := normalizeWeekdays(req.StudyWeekdays)
+	if err != nil {
+		httpx.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.MaxNewPerDay == 0 {
+		req.MaxNewPerDay = 4
+	}
+	if req.MaxNewPerDay < 1 || req.MaxNewPerDay > 50 {
+		httpx.WriteError(w, http.StatusBadRequest, "max_new_per_day must be 1..50")
+		return
+	}
+
+	clk, err := h.clock(r, userID)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load user settings")
+		return
+	}
+	if target.Before(clk.today) {
+		httpx.WriteError(w, http.StatusBadRequest, "target_date must not be in the past")
+		return
+	}
+
+	list, err := h.lists.Get(r.Context(), userID, req.ListID)
+	if err != nil {
+		httpx.WriteError(w, http.StatusNotFound, "list not found")
+		return
+	}
+	if len(list.Items) == 0 {
+		httpx.WriteError(w, http.StatusBadRequest, "list has no items")
+		return
+	}
+	if req.Name == "" {
+		req.Name = list.Name + " plan"
+	}
+
+	ctx := r.Context()
+	tx, err := h.pool.Begin(ctx)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to start transaction")
+		return
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	ids := make([]string, 0, len(list.Items))
+	for _, it := range list.Items {
+		ids = append(ids, it.Problem.ID)
+	}
+	reviewed, err := h.repo.ReviewedProblemIDsTx(ctx, tx, userID, ids)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load review state")
+		return
+	}
+	pending := 0
+	for _, id := range ids {
+		if !reviewed[id] {
+			pending++
+		}
+	}
+	days := assignDays(clk.today, target, pending, weekdaySet(weekdays), req.MaxNewPerDay)
+
+	plan, err := h.repo.CreateTx(ctx, tx, userID, CreateInput{
+		ListID:        list.ID,
+		Name:          req.Name,
+		StartDate:     clk.today,
+		TargetDate:    target,
+		StudyWeekdays: weekdays,
+		MaxNewPerDay:  req.MaxNewPerDay,
+	})
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to create plan")
+		return
+	}
+
+	next := 0
+	for idx, problemID := range ids {
+		if reviewed[problemID] {
+			if err := h.repo.AddItemTx(ctx, tx, plan.ID, problemID, idx, nil); err != nil {
+				httpx.WriteError(w, http.StatusInternalServerError, "failed to add plan item")
+				return
+			}
+			continue
+		}
+		day := days[next]
+		next++
+		if err := h.repo.AddItemTx(ctx, tx, plan.ID, problemID, idx, &day); err != nil {
+			httpx.WriteError(w, http.StatusInternalServerError, "failed to add plan item")
+			return
+		}
+		dueAt := clk.introduceAt(day)
+		if err := h.problems.EnsureUserStateTx(ctx, tx, userID, problemID, dueAt); err != nil {
+			httpx.WriteError(w, http.StatusInternalServerError, "failed to init problem state")
+			return
+		}
+		if err := h.repo.IntroduceOnTx(ctx, tx, userID, problemID, dueAt); err != nil {
+			httpx.WriteError(w, http.StatusInternalServerError, "failed to schedule problem introduction")
+			return
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to commit")
+		return
+	}
+
+	out, _ := h.repo.GetWithItems(r.Context(), userID, plan.ID, clk.today)
+	httpx.WriteJSON(w, http.StatusCreated, out)
+}
+
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httpx.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID, ok := reqctx.UserIDFromContext(r.Context())
+	if !ok {
+		httpx.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	out, err := h.repo.List(r.Context(), userID)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to list plans")
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, out)
+}
+
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httpx.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID, ok := reqctx.UserIDFromContext(r.Context())
+	if !ok {
+		httpx.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	planID := strings.TrimSpace(chi.URLParam(r, "id"))
+	if planID == "" {
+		httpx.WriteError(w, http.StatusBadRequest, "id required")
+		return
+	}
+	clk, err := h.clock(r, userID)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load user settings")
+		return
+	}
+	out, err := h.repo.GetWithItems(r.Context(), userID, planID, clk.today)
+	if err != nil {
+		httpx.WriteError(w, http.StatusNotFound, "not found")
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, out)
+}
+
+func (h *Handler) Rebalance(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httpx.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID, ok := reqctx.UserIDFromContext(r.Context())
+	if !ok {
+		httpx.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	planID := strings.TrimSpace(chi.URLParam(r, "id"))
+	if planID == "" {
+		httpx.WriteError(w, http.StatusBadRequest, "id required")
+		return
+	}
+	clk, err := h.clock(r, userID)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load user settings")
+		return
+	}
+	if err := h.repo.Rebalance(r.Context(), userID, planID, clk.today, clk.introduceAt); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			httpx.WriteError(w, http.StatusNotFound, "not found")
+			return
+		}
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to rebalance plan")
+		return
+	}
+	out, err := h.repo.GetWithItems(r.Context(), userID, planID, clk.today)
+	if err != nil {
+		httpx.WriteError(w, http.StatusNotFound, "not found")
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, out)
+}
+
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		httpx.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID, ok := reqctx.UserIDFromContext(r.Context())
+	if !ok {
+		httpx.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	planID := strings.TrimSpace(chi.URLParam(r, "id"))
+	if planID == "" {
+		httpx.WriteError(w, http.StatusBadRequest, "id required")
+		return
+	}
+	clk, err := h.clock(r, userID)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load user settings")
+		return
+	}
+	if err := h.repo.Delete(r.Context(), userID, planID, clk.introduceAt(clk.today)); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			httpx.WriteError(w, http.StatusNotFound, "not found")
+			return
+		}
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to delete plan")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
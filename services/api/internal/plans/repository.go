@@ -0,0 +1,283 @@
+package plans
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/db"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/problems"
+)
+
+type Plan struct {
+	ID            string    `json:"id"`
+	ListID        string    `json:"list_id"`
+	Name          string    `json:"name"`
+	StartDate     string    `json:"start_date"`
+	TargetDate    string    `json:"target_date"`
+	StudyWeekdays []int     `json:"study_weekdays"`
+	MaxNewPerDay  int       `json:"max_new_per_day"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type Item struct {
+	Problem       problems.Problem `json:"problem"`
+	OrderIndex    int              `json:"order_index"`
+	ScheduledDate *string          `json:"scheduled_date,omitempty"`
+	Completed     bool             `json:"completed"`
+}
+
+type PlanWithItems struct {
+	Plan
+	Progress Progress `json:"progress"`
+	Items    []Item   `json:"items"`
+}
+
+type CreateInput struct {
+	ListID        string
+	Name          string
+	StartDate     time.Time
+	TargetDate    time.Time
+	StudyWeekdays []int
+	MaxNewPerDay  int
+}
+
+type Repository struct {
+	pool *pgxpool.Pool
+}
+
+func NewRepository(pool *pgxpool.Pool) *Repository { return &Repository{pool: pool} }
+
+const planColumns = `id::text, list_id::text, name, start_date::text, target_date::text, study_weekdays, max_new_per_day, created_at`
+
+func scanPlan(row pgx.Row, p *Plan) error {
+	return row.Scan(&p.ID, &p.ListID, &p.Name, &p.StartDate, &p.TargetDate, &p.StudyWeekdays, &p.MaxNewPerDay, &p.CreatedAt)
+}
+
+func (r *Repository) CreateTx(ctx context.Context, tx pgx.Tx, userID string, in CreateInput) (Plan, error) {
+	var out Plan
+	err := scanPlan(tx.QueryRow(ctx, `
+		INSERT INTO study_plans (user_id, list_id, name, start_date, target_date, study_weekdays, max_new_per_day)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING `+planColumns,
+		userID, in.ListID, in.Name, in.StartDate.Format(dateLayout), in.TargetDate.Format(dateLayout), in.StudyWeekdays, in.MaxNewPerDay,
+	), &out)
+	return out, err
+}
+
+func (r *Repository) AddItemTx(ctx context.Context, tx pgx.Tx, planID string, problemID string, orderIndex int, scheduledDate *time.Time) error {
+	var d *string
+	if scheduledDate != nil {
+		s := scheduledDate.Format(dateLayout)
+		d = &s
+	}
+	_, err := tx.Exec(ctx, `
+		INSERT INTO study_plan_items (plan_id, problem_id, order_index, scheduled_date)
+		VALUES ($1, $2, $3, $4::date)
+		ON CONFLICT (plan_id, problem_id) DO UPDATE
+		SET order_index = EXCLUDED.order_index,
+		    scheduled_date = EXCLUDED.scheduled_date
+	`, planID, problemID, orderIndex, d)
+	return err
+}
+
+// ReviewedProblemIDsTx returns the subset of problemIDs the user has reviewed at least once.
+func (r *Repository) ReviewedProblemIDsTx(ctx context.Context, tx pgx.Tx, userID string, problemIDs []string) (map[string]bool, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT problem_id::text
+		FROM user_problem_state
+		WHERE user_id = $1 AND problem_id::text = ANY($2) AND last_review_at IS NOT NULL
+	`, userID, problemIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string]bool{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		out[id] = true
+	}
+	return out, rows.Err()
+}
+
+// IntroduceOnTx moves a not-yet-reviewed problem's due_at to its planned introduction
+// time, so it enters the regular due queue on that day.
+func (r *Repository) IntroduceOnTx(ctx context.Context, tx pgx.Tx, userID string, problemID string, dueAt time.Time) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE user_problem_state
+		SET due_at = $3
+		WHERE user_id = $1 AND problem_id = $2 AND last_review_at IS NULL
+	`, userID, problemID, dueAt)
+	return err
+}
+
+func (r *Repository) List(ctx context.Context, userID string) ([]Plan, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+planColumns+`
+		FROM study_plans
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]Plan, 0)
+	for rows.Next() {
+		var p Plan
+		if err := scanPlan(rows, &p); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+func (r *Repository) Get(ctx context.Context, userID string, planID string) (Plan, error) {
+	var out Plan
+	err := scanPlan(r.pool.QueryRow(ctx, `
+		SELECT `+planColumns+`
+		FROM study_plans
+		WHERE id = $1 AND user_id = $2
+	`, planID, userID), &out)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Plan{}, db.ErrNotFound
+		}
+		return Plan{}, err
+	}
+	return out, nil
+}
+
+func (r *Repository) GetWithItems(ctx context.Context, userID string, planID string, today time.Time) (PlanWithItems, error) {
+	p, err := r.Get(ctx, userID, planID)
+	if err != nil {
+		return PlanWithItems{}, err
+	}
+	rows, err := r.pool.Query(ctx, `
+		SELECT pi.order_index, pi.scheduled_date::text,
+		       p.id::text, p.url, p.platform, p.title, p.difficulty, p.topics,
+		       COALESCE(s.last_review_at IS NOT NULL, false) AS completed
+		FROM study_plan_items pi
+		JOIN problems p ON p.id = pi.problem_id
+		LEFT JOIN user_problem_state s ON s.user_id = $2 AND s.problem_id = pi.problem_id
+		WHERE pi.plan_id = $1
+		ORDER BY pi.order_index ASC
+	`, planID, userID)
+	if err != nil {
+		return PlanWithItems{}, err
+	}
+	defer rows.Close()
+	out := PlanWithItems{Plan: p, Items: make([]Item, 0)}
+	for rows.Next() {
+		var it Item
+		if err := rows.Scan(
+			&it.OrderIndex, &it.ScheduledDate,
+			&it.Problem.ID, &it.Problem.URL, &it.Problem.Platform, &it.Problem.Title, &it.Problem.Difficulty, &it.Problem.Topics,
+			&it.Completed,
+		); err != nil {
+			return PlanWithItems{}, err
+		}
+		out.Items = append(out.Items, it)
+	}
+	out.Progress = computeProgress(today, out.Items)
+	return out, nil
+}
+
+// Rebalance reschedules every incomplete item from today onward, keeping list order,
+// the plan's study days and its daily cap. Missed days are absorbed by the remaining
+// study days up to target_date and spill past it only when the cap leaves no room.
+func (r *Repository) Rebalance(ctx context.Context, userID string, planID string, today time.Time, introduceAt func(day time.Time) time.Time) error {
+	p, err := r.Get(ctx, userID, planID)
+	if err != nil {
+		return err
+	}
+	target, err := parseDate(p.TargetDate)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	rows, err := tx.Query(ctx, `
+		SELECT pi.problem_id::text
+		FROM study_plan_items pi
+		LEFT JOIN user_problem_state s ON s.user_id = $2 AND s.problem_id = pi.problem_id
+		WHERE pi.plan_id = $1 AND s.last_review_at IS NULL
+		ORDER BY pi.order_index ASC
+	`, planID, userID)
+	if err != nil {
+		return err
+	}
+	pending := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		pending = append(pending, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	days := assignDays(today, target, len(pending), weekdaySet(p.StudyWeekdays), p.MaxNewPerDay)
+	for i, problemID := range pending {
+		if _, err := tx.Exec(ctx, `
+			UPDATE study_plan_items
+			SET scheduled_date = $3::date
+			WHERE plan_id = $1 AND problem_id = $2
+		`, planID, problemID, days[i].Format(dateLayout)); err != nil {
+			return err
+		}
+		if err := r.IntroduceOnTx(ctx, tx, userID, problemID, introduceAt(days[i])); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE study_plans SET updated_at = now() WHERE id = $1`, planID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// Delete removes a plan. Problems the plan was still holding back are released into
+// the due queue at releaseAt so nothing stays hidden behind a plan that no longer exists.
+func (r *Repository) Delete(ctx context.Context, userID string, planID string, releaseAt time.Time) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE user_problem_state s
+		SET due_at = LEAST(s.due_at, $3)
+		FROM study_plan_items pi
+		JOIN study_plans sp ON sp.id = pi.plan_id
+		WHERE sp.id = $1 AND sp.user_id = $2
+		  AND s.user_id = sp.user_id AND s.problem_id = pi.problem_id
+		  AND s.last_review_at IS NULL
+	`, planID, userID, releaseAt); err != nil {
+		return err
+	}
+	ct, err := tx.Exec(ctx, `DELETE FROM study_plans WHERE id = $1 AND user_id = $2`, planID, userID)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return db.ErrNotFound
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,151 @@
+package plans
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+var errInvalidWeekdays = errors.New("study_weekdays must be values 0..6 (0=Sunday)")
+
+// civilDate returns the calendar day of t in loc as midnight UTC, so dates can be
+// compared, stepped with AddDate and formatted without timezone drift.
+func civilDate(t time.Time, loc *time.Location) time.Time {
+	if loc == nil {
+		loc = time.UTC
+	}
+	lt := t.In(loc)
+	return time.Date(lt.Year(), lt.Month(), lt.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func parseDate(raw string) (time.Time, error) {
+	return time.Parse(dateLayout, raw)
+}
+
+func normalizeWeekdays(in []int) ([]int, error) {
+	if len(in) == 0 {
+		return []int{1, 2, 3, 4, 5}, nil
+	}
+	seen := map[int]bool{}
+	out := make([]int, 0, len(in))
+	for _, d := range in {
+		if d < 0 || d > 6 {
+			return nil, errInvalidWeekdays
+		}
+		if seen[d] {
+			continue
+		}
+		seen[d] = true
+		out = append(out, d)
+	}
+	sort.Ints(out)
+	return out, nil
+}
+
+func weekdaySet(days []int) map[time.Weekday]bool {
+	out := make(map[time.Weekday]bool, len(days))
+	for _, d := range days {
+		out[time.Weekday(d)] = true
+	}
+	return out
+}
+
+// assignDays spreads n problems (in order) over the study days between start and
+// target, both inclusive. The daily quota is the smallest even split that finishes
+// by target, capped at maxPerDay; when even maxPerDay cannot fit everything, the
+// remainder spills onto study days after target at maxPerDay each.
+func assignDays(start time.Time, target time.Time, n int, weekdays map[time.Weekday]bool, maxPerDay int) []time.Time {
+	if n <= 0 || len(weekdays) == 0 {
+		return nil
+	}
+	if maxPerDay < 1 {
+		maxPerDay = 1
+	}
+
+	studyDays := 0
+	for d := start; !d.After(target); d = d.AddDate(0, 0, 1) {
+		if weekdays[d.Weekday()] {
+			studyDays++
+		}
+	}
+	perDay := maxPerDay
+	if studyDays > 0 {
+		perDay = (n + studyDays - 1) / studyDays
+		if perDay > maxPerDay {
+			perDay = maxPerDay
+		}
+	}
+
+	out := make([]time.Time, 0, n)
+	for d := start; len(out) < n; d = d.AddDate(0, 0, 1) {
+		if !weekdays[d.Weekday()] {
+			continue
+		}
+		quota := perDay
+		if d.After(target) {
+			quota = maxPerDay
+		}
+		for i := 0; i < quota && len(out) < n; i++ {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// Progress summarizes how a plan is tracking against its schedule as of a local day.
+type Progress struct {
+	Total           int     `json:"total"`
+	Completed       int     `json:"completed"`
+	ExpectedByToday int     `json:"expected_by_today"`
+	Delta           int     `json:"delta"`
+	Status          string  `json:"status"` // ahead | on_track | behind
+	MissedCount     int     `json:"missed_count"`
+	ProjectedFinish *string `json:"projected_finish,omitempty"`
+}
+
+func computeProgress(today time.Time, items []Item) Progress {
+	var p Progress
+	var last time.Time
+	for _, it := range items {
+		p.Total++
+		if it.Completed {
+			p.Completed++
+		}
+		if it.ScheduledDate == nil {
+			// Already reviewed when the plan was created.
+			p.ExpectedByToday++
+			continue
+		}
+		d, err := parseDate(*it.ScheduledDate)
+		if err != nil {
+			continue
+		}
+		if !d.After(today) {
+			p.ExpectedByToday++
+		}
+		if !it.Completed {
+			if d.Before(today) {
+				p.MissedCount++
+			}
+			if d.After(last) {
+				last = d
+			}
+		}
+	}
+	p.Delta = p.Completed - p.ExpectedByToday
+	switch {
+	case p.Delta > 0:
+		p.Status = "ahead"
+	case p.Delta < 0:
+		p.Status = "behind"
+	default:
+		p.Status = "on_track"
+	}
+	if !last.IsZero() {
+		s := last.Format(dateLayout)
+		p.ProjectedFinish = &s
+	}
+	return p
+}
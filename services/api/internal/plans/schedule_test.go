@@ -0,0 +1,64 @@
+package plans
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAssignDaysSpreadsEvenlyOverStudyDays(t *testing.T) {
+	// Mon 2026-02-09 .. Fri 2026-02-13, weekdays only.
+	start := time.Date(2026, 2, 9, 0, 0, 0, 0, time.UTC)
+	target := time.Date(2026, 2, 13, 0, 0, 0, 0, time.UTC)
+	days := assignDays(start, target, 10, weekdaySet([]int{1, 2, 3, 4, 5}), 4)
+	if len(days) != 10 {
+		t.Fatalf("expected 10 days, got %d", len(days))
+	}
+	perDay := map[string]int{}
+	for _, d := range days {
+		perDay[d.Format(dateLayout)]++
+	}
+	for _, k := range []string{"2026-02-09", "2026-02-10", "2026-02-11", "2026-02-12", "2026-02-13"} {
+		if perDay[k] != 2 {
+			t.Fatalf("expected 2 on %s, got %d (%v)", k, perDay[k], perDay)
+		}
+	}
+}
+
+func TestAssignDaysSkipsNonStudyDaysAndSpillsPastTarget(t *testing.T) {
+	// Fri 2026-02-13 .. Mon 2026-02-16 with weekdays only: Fri + Mon are study days.
+	start := time.Date(2026, 2, 13, 0, 0, 0, 0, time.UTC)
+	target := time.Date(2026, 2, 16, 0, 0, 0, 0, time.UTC)
+	days := assignDays(start, target, 7, weekdaySet([]int{1, 2, 3, 4, 5}), 3)
+	if len(days) != 7 {
+		t.Fatalf("expected 7 days, got %d", len(days))
+	}
+	for _, d := range days {
+		if d.Weekday() == time.Saturday || d.Weekday() == time.Sunday {
+			t.Fatalf("scheduled on weekend: %s", d.Format(dateLayout))
+		}
+	}
+	if got := days[6].Format(dateLayout); got != "2026-02-17" {
+		t.Fatalf("expected overflow onto 2026-02-17, got %s", got)
+	}
+}
+
+func TestComputeProgressStatus(t *testing.T) {
+	today := time.Date(2026, 2, 11, 0, 0, 0, 0, time.UTC)
+	d := func(s string) *string { return &s }
+	items := []Item{
+		{ScheduledDate: nil, Completed: true},
+		{ScheduledDate: d("2026-02-10"), Completed: false},
+		{ScheduledDate: d("2026-02-11"), Completed: true},
+		{ScheduledDate: d("2026-02-12"), Completed: false},
+	}
+	p := computeProgress(today, items)
+	if p.ExpectedByToday != 3 || p.Completed != 2 {
+		t.Fatalf("unexpected counts: %+v", p)
+	}
+	if p.Status != "behind" || p.Delta != -1 || p.MissedCount != 1 {
+		t.Fatalf("expected behind by 1 with 1 missed, got %+v", p)
+	}
+	if p.ProjectedFinish == nil || *p.ProjectedFinish != "2026-02-12" {
+		t.Fatalf("unexpected projected finish: %v", p.ProjectedFinish)
+	}
+}
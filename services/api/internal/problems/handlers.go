@@ -10,6 +10,7 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/activity"
 	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/httpx"
 	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/reqctx"
 	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/scheduler"
@@ -151,6 +152,12 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 			httpx.WriteError(w, http.StatusInternalServerError, "failed to write initial review log")
 			return
 		}
+		if err := activity.RecordReviewTx(ctx, tx, userID, loc, activity.Review{
+			ProblemID: p.ID, Grade: req.Initial.Grade, ReviewedAtUTC: reviewedAt, TimeSpentSec: req.Initial.TimeSpentSec,
+		}); err != nil {
+			httpx.WriteError(w, http.StatusInternalServerError, "failed to record daily activity")
+			return
+		}
 
 		state, err := h.repo.GetStateForUpdate(ctx, tx, userID, p.ID)
 		if err != nil {
@@ -0,0 +1,81 @@
+package recommendations
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/httpx"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/reqctx"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/templates"
+)
+
+type Handler struct {
+	pool *pgxpool.Pool
+}
+
+func NewHandler(pool *pgxpool.Pool) *Handler {
+	return &Handler{pool: pool}
+}
+
+// Get suggests what to study next; see rank for the ordering.
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httpx.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID, ok := reqctx.UserIDFromContext(r.Context())
+	if !ok {
+		httpx.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	limit := 10
+	if v := strings.TrimSpace(r.URL.Query().Get("limit")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 50 {
+			httpx.WriteError(w, http.StatusBadRequest, "limit must be 1..50")
+			return
+		}
+		limit = n
+	}
+
+	rows, err := h.pool.Query(r.Context(), `
+		SELECT p.id::text, p.platform, p.url, p.title, p.difficulty, p.topics,
+		       s.reps, s.interval_days, s.ease, s.due_at, s.last_review_at, s.last_grade, s.is_active, s.recent_grades
+		FROM problems p
+		JOIN user_problem_state s ON s.problem_id = p.id
+		WHERE s.user_id = $1
+		ORDER BY p.id
+	`, userID)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load problems")
+		return
+	}
+	defer rows.Close()
+	library := make([]libraryProblem, 0)
+	for rows.Next() {
+		var p libraryProblem
+		if err := rows.Scan(
+			&p.ID, &p.Platform, &p.URL, &p.Title, &p.Difficulty, &p.Topics,
+			&p.State.Reps, &p.State.IntervalDays, &p.State.Ease, &p.State.DueAt, &p.State.LastReviewAt, &p.State.LastGrade, &p.State.IsActive,
+			&p.grades,
+		); err != nil {
+			httpx.WriteError(w, http.StatusInternalServerError, "failed to parse problems")
+			return
+		}
+		library = append(library, p)
+	}
+	if err := rows.Err(); err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load problems")
+		return
+	}
+
+	catalog, err := templates.Catalog()
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load template catalog")
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, rank(time.Now().UTC(), library, catalog, limit))
+}
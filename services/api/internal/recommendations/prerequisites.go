@@ -0,0 +1,61 @@
+package recommendations
+
+import (
+	"sort"
+
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/mastery"
+)
+
+// topicPrerequisites lists, for each topic, the topics it builds on directly.
+// Keys and values are normalized topic names as used by the templates; topics
+// not listed have no known prerequisites.
+var topicPrerequisites = map[string][]string{
+	"hashmap":          {"arrays"},
+	"hashset":          {"arrays"},
+	"sorting":          {"arrays"},
+	"strings":          {"arrays"},
+	"stack":            {"arrays"},
+	"queue":            {"arrays"},
+	"two-pointers":     {"arrays", "sorting"},
+	"sliding-window":   {"two-pointers", "hashmap"},
+	"binary-search":    {"arrays", "sorting"},
+	"prefix-sum":       {"arrays"},
+	"intervals":        {"sorting"},
+	"greedy":           {"sorting"},
+	"linked-list":      {"two-pointers"},
+	"tree":             {"recursion", "linked-list"},
+	"bst":              {"tree", "binary-search"},
+	"heap":             {"tree", "arrays"},
+	"trie":             {"tree", "strings"},
+	"bfs":              {"tree", "queue"},
+	"dfs":              {"tree", "recursion"},
+	"graphs":           {"bfs", "dfs", "hashmap"},
+	"topological-sort": {"graphs"},
+	"union-find":       {"graphs"},
+	"shortest-path":    {"graphs", "heap"},
+	"backtracking":     {"recursion", "dfs"},
+	"dp":               {"recursion", "arrays"},
+	"bit-manipulation": {"arrays"},
+}
+
+// prerequisites returns the direct prerequisite topics of a problem's topics,
+// sorted, leaving out topics the problem already has.
+func prerequisites(topics []string) []string {
+	own := map[string]bool{}
+	for _, t := range topics {
+		own[mastery.NormalizeTopic(t)] = true
+	}
+	seen := map[string]bool{}
+	out := make([]string, 0)
+	for t := range own {
+		for _, p := range topicPrerequisites[t] {
+			if own[p] || seen[p] {
+				continue
+			}
+			seen[p] = true
+			out = append(out, p)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,274 @@
+// Package recommendations suggests what to study next from the user's weakest
+// topics, their due reviews, the template catalog and recent failures.
+package recommendations
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/mastery"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/problems"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/templates"
+)
+
+const (
+	KindDue          = "due"
+	KindNew          = "new"
+	KindPrerequisite = "prerequisite"
+)
+
+const (
+	// weakTopicsLen is how many of the weakest topics drive suggestions.
+	weakTopicsLen = 3
+	// weakTopicMax is the mastery below which a topic counts as weak.
+	weakTopicMax = 60.0
+	// recentFailDays is how recent a failed review (grade 0 or 1) must be.
+	recentFailDays = 14
+	// failuresLen bounds the failed problems whose prerequisites are suggested.
+	failuresLen = 3
+	// perFailure bounds the prerequisite suggestions for one failed problem.
+	perFailure = 2
+	// newPerTopic bounds the catalog suggestions for one weak topic.
+	newPerTopic = 3
+)
+
+// Suggestion is one problem to study. ProblemID is empty for catalog problems
+// that are not in the user's library yet.
+type Suggestion struct {
+	Kind       string   `json:"kind"`
+	ProblemID  string   `json:"problem_id,omitempty"`
+	URL        string   `json:"url"`
+	Title      string   `json:"title"`
+	Difficulty string   `json:"difficulty"`
+	Topics     []string `json:"topics"`
+	Topic      string   `json:"topic"`
+	Score      float64  `json:"score"`
+	Reason     string   `json:"reason"`
+}
+
+type Response struct {
+	WeakTopics  []mastery.TopicEstimate `json:"weak_topics"`
+	Suggestions []Suggestion            `json:"suggestions"`
+}
+
+// libraryProblem is a problem in the user's library, active or not, with the
+// grades mastery reads.
+type libraryProblem struct {
+	problems.ProblemWithState
+	grades []int
+}
+
+// difficultyBonus favors easier problems, which are the better next step in a
+// weak topic.
+func difficultyBonus(d string) float64 {
+	switch strings.ToLower(strings.TrimSpace(d)) {
+	case "easy":
+		return 20
+	case "medium":
+		return 10
+	default:
+		return 0
+	}
+}
+
+// weakestTopic returns the weakest of the given topics that is in weak.
+func weakestTopic(topics []string, weak map[string]float64) (string, float64, bool) {
+	best, score, ok := "", 0.0, false
+	for _, t := range topics {
+		t = mastery.NormalizeTopic(t)
+		s, isWeak := weak[t]
+		if !isWeak {
+			continue
+		}
+		if !ok || s < score || (s == score && t < best) {
+			best, score, ok = t, s, true
+		}
+	}
+	return best, score, ok
+}
+
+func hasTopic(topics []string, topic string) bool {
+	for _, t := range topics {
+		if mastery.NormalizeTopic(t) == topic {
+			return true
+		}
+	}
+	return false
+}
+
+func recentlyFailed(s problems.UserState, now time.Time) bool {
+	return s.LastGrade != nil && s.LastReviewAt != nil && *s.LastGrade <= 1 &&
+		now.Sub(*s.LastReviewAt) <= recentFailDays*24*time.Hour
+}
+
+func round1(v float64) float64 { return math.Round(v*10) / 10 }
+
+// rank builds the suggestions deterministically: the same library, catalog and
+// now always give the same list.
+//
+// Due reviews in a weak topic score highest (100 + weakness + 3 per overdue
+// day, capped at 30 days), then easier problems in the prerequisite topics of
+// recent failures (80 + half the problem's weakness, or 70 + difficulty bonus
+// from the catalog when the library has none), then catalog problems in weak
+// topics (60 + weakness + difficulty bonus). A problem suggested twice keeps
+// its best score. Ties break by title, then URL.
+//
+// Only active problems count toward mastery and library suggestions, but no
+// catalog problem already in the library, active or not, is suggested; URLs
+// are compared normalized, as the library stores them.
+func rank(now time.Time, all []libraryProblem, catalog []templates.Item, limit int) Response {
+	owned := make(map[string]bool, len(all))
+	library := make([]libraryProblem, 0, len(all))
+	for _, p := range all {
+		owned[problems.NormalizeURL(p.URL)] = true
+		if p.State.IsActive {
+			library = append(library, p)
+		}
+	}
+	tagged := make([]mastery.Tagged, len(library))
+	estimates := make(map[string]mastery.Estimate, len(library))
+	for i, p := range library {
+		e := mastery.Problem(p.State, p.grades, now)
+		tagged[i] = mastery.Tagged{Topics: p.Topics, Estimate: e}
+		estimates[p.ID] = e
+	}
+
+	out := Response{WeakTopics: make([]mastery.TopicEstimate, 0, weakTopicsLen), Suggestions: make([]Suggestion, 0)}
+	weak := map[string]float64{}
+	for _, t := range mastery.ByTopic(tagged) {
+		if len(out.WeakTopics) == weakTopicsLen || t.Score >= weakTopicMax {
+			break
+		}
+		out.WeakTopics = append(out.WeakTopics, t)
+		weak[t.Topic] = t.Score
+	}
+
+	best := map[string]Suggestion{}
+	add := func(s Suggestion) {
+		s.Score = round1(s.Score)
+		key := s.ProblemID
+		if key == "" {
+			key = s.URL
+		}
+		if cur, ok := best[key]; !ok || s.Score > cur.Score {
+			best[key] = s
+		}
+	}
+	fromLibrary := func(p libraryProblem, kind string, topic string, score float64, reason string) Suggestion {
+		return Suggestion{Kind: kind, ProblemID: p.ID, URL: p.URL, Title: p.Title, Difficulty: p.Difficulty,
+			Topics: p.Topics, Topic: topic, Score: score, Reason: reason}
+	}
+	fromCatalog := func(it templates.Item, kind string, topic string, score float64, reason string) Suggestion {
+		return Suggestion{Kind: kind, URL: it.URL, Title: it.Title, Difficulty: strings.ToLower(it.Difficulty),
+			Topics: it.Topics, Topic: topic, Score: score, Reason: reason}
+	}
+
+	// Due reviews in weak topics.
+	for _, p := range library {
+		if p.State.DueAt.After(now) {
+			continue
+		}
+		topic, ts, ok := weakestTopic(p.Topics, weak)
+		if !ok {
+			continue
+		}
+		overdue := int(now.Sub(p.State.DueAt).Hours() / 24)
+		reason := fmt.Sprintf("Due now in %q, one of your weakest topics (mastery %.0f)", topic, ts)
+		if overdue > 0 {
+			reason = fmt.Sprintf("Overdue by %d days in %q, one of your weakest topics (mastery %.0f)", overdue, topic, ts)
+		}
+		add(fromLibrary(p, KindDue, topic, 100+(100-ts)+3*float64(min(overdue, 30)), reason))
+	}
+
+	// Prerequisites of recent failures, most recent failure first.
+	failed := make([]libraryProblem, 0)
+	for _, p := range library {
+		if recentlyFailed(p.State, now) {
+			failed = append(failed, p)
+		}
+	}
+	sort.SliceStable(failed, func(i, j int) bool {
+		a, b := *failed[i].State.LastReviewAt, *failed[j].State.LastReviewAt
+		if a.Equal(b) {
+			return failed[i].Title < failed[j].Title
+		}
+		return a.After(b)
+	})
+	if len(failed) > failuresLen {
+		failed = failed[:failuresLen]
+	}
+	for _, f := range failed {
+		cands := make([]Suggestion, 0)
+		for _, topic := range prerequisites(f.Topics) {
+			reason := fmt.Sprintf("Practices %q, a prerequisite of %q, which you failed recently", topic, f.Title)
+			found := false
+			for _, p := range library {
+				if p.ID == f.ID || !hasTopic(p.Topics, topic) {
+					continue
+				}
+				found = true
+				cands = append(cands, fromLibrary(p, KindPrerequisite, topic, 80+(100-estimates[p.ID].Score)/2+difficultyBonus(p.Difficulty)/2, reason))
+			}
+			if found {
+				continue
+			}
+			for _, it := range catalog {
+				if owned[problems.NormalizeURL(it.URL)] || !hasTopic(it.Topics, topic) {
+					continue
+				}
+				cands = append(cands, fromCatalog(it, KindPrerequisite, topic, 70+difficultyBonus(it.Difficulty), reason))
+			}
+		}
+		sortSuggestions(cands)
+		for i := 0; i < len(cands) && i < perFailure; i++ {
+			add(cands[i])
+		}
+	}
+
+	// Catalog problems in weak topics.
+	perTopic := map[string]int{}
+	cands := make([]Suggestion, 0)
+	for _, it := range catalog {
+		if owned[problems.NormalizeURL(it.URL)] {
+			continue
+		}
+		topic, ts, ok := weakestTopic(it.Topics, weak)
+		if !ok {
+			continue
+		}
+		reason := fmt.Sprintf("Not in your library yet; practices %q, one of your weakest topics (mastery %.0f)", topic, ts)
+		cands = append(cands, fromCatalog(it, KindNew, topic, 60+(100-ts)+difficultyBonus(it.Difficulty), reason))
+	}
+	sortSuggestions(cands)
+	for _, s := range cands {
+		if perTopic[s.Topic] == newPerTopic {
+			continue
+		}
+		perTopic[s.Topic]++
+		add(s)
+	}
+
+	for _, s := range best {
+		out.Suggestions = append(out.Suggestions, s)
+	}
+	sortSuggestions(out.Suggestions)
+	if len(out.Suggestions) > limit {
+		out.Suggestions = out.Suggestions[:limit]
+	}
+	return out
+}
+
+func sortSuggestions(ss []Suggestion) {
+	sort.SliceStable(ss, func(i, j int) bool {
+		if ss[i].Score != ss[j].Score {
+			return ss[i].Score > ss[j].Score
+		}
+		if ss[i].Title != ss[j].Title {
+			return ss[i].Title < ss[j].Title
+		}
+		return ss[i].URL < ss[j].URL
+	})
+}
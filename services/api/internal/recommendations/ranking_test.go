@@ -0,0 +1,107 @@
+package recommendations
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/problems"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/templates"
+)
+
+func TestPrerequisites(t *testing.T) {
+	got := prerequisites([]string{"bst", "Tree"})
+	want := []string{"binary-search", "linked-list", "recursion"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("prerequisites = %v, want %v", got, want)
+	}
+	if got := prerequisites([]string{"unknown-topic"}); len(got) != 0 {
+		t.Fatalf("unknown topics should have no prerequisites, got %v", got)
+	}
+}
+
+func TestRankCombinesSourcesDeterministically(t *testing.T) {
+	now := time.Date(2026, 2, 10, 12, 0, 0, 0, time.UTC)
+	ago := func(d int) *time.Time { t := now.AddDate(0, 0, -d); return &t }
+	grade := func(g int) *int { return &g }
+	// Library URLs are stored normalized, without the trailing slash the catalog has.
+	mk := func(id, title, difficulty string, topics []string, st problems.UserState, grades ...int) libraryProblem {
+		st.IsActive = true
+		return libraryProblem{
+			ProblemWithState: problems.ProblemWithState{
+				Problem: problems.Problem{ID: id, URL: "https://example.com/" + id, Title: title, Difficulty: difficulty, Topics: topics},
+				State:   st,
+			},
+			grades: grades,
+		}
+	}
+	strong := problems.UserState{Reps: 5, IntervalDays: 20, Ease: 2.6, DueAt: now.AddDate(0, 0, 18), LastReviewAt: ago(2), LastGrade: grade(4)}
+	library := []libraryProblem{
+		mk("g1", "Graph Valid Tree", "medium", []string{"graphs"}, problems.UserState{IntervalDays: 1, Ease: 2.5, DueAt: now.AddDate(0, 0, -3)}),
+		mk("t1", "Invert Binary Tree", "easy", []string{"tree"}, strong, 4, 4, 4),
+		mk("a1", "Two Sum", "easy", []string{"arrays"}, strong, 4, 4, 4),
+		mk("b1", "Kth Smallest in a BST", "medium", []string{"bst"},
+			problems.UserState{IntervalDays: 1, Ease: 2.3, DueAt: now.AddDate(0, 0, 1), LastReviewAt: ago(1), LastGrade: grade(1)}, 1, 3),
+	}
+	// A deactivated problem is neither suggested from the catalog nor counted
+	// toward mastery.
+	inactive := mk("clone", "Clone Graph", "medium", []string{"graphs"}, strong, 4, 4, 4)
+	inactive.State.IsActive = false
+	library = append(library, inactive)
+	catalog := []templates.Item{
+		{URL: "https://example.com/g1/", Title: "Graph Valid Tree", Difficulty: "Medium", Topics: []string{"graphs"}},
+		{URL: "https://example.com/clone/", Title: "Clone Graph", Difficulty: "Medium", Topics: []string{"graphs"}},
+		{URL: "https://example.com/islands/", Title: "Number of Islands", Difficulty: "Medium", Topics: []string{"graphs"}},
+		{URL: "https://example.com/ladder/", Title: "Word Ladder", Difficulty: "Hard", Topics: []string{"graphs"}},
+		{URL: "https://example.com/star/", Title: "Find Center of Star Graph", Difficulty: "Easy", Topics: []string{"graphs"}},
+		{URL: "https://example.com/course/", Title: "Course Schedule", Difficulty: "Medium", Topics: []string{"graphs"}},
+		{URL: "https://example.com/validate/", Title: "Validate BST", Difficulty: "Medium", Topics: []string{"bst"}},
+		{URL: "https://example.com/search/", Title: "Binary Search", Difficulty: "Easy", Topics: []string{"binary-search"}},
+	}
+
+	got := rank(now, library, catalog, 10)
+	if len(got.WeakTopics) != 2 || got.WeakTopics[0].Topic != "graphs" || got.WeakTopics[1].Topic != "bst" {
+		t.Fatalf("weak topics = %+v", got.WeakTopics)
+	}
+
+	titles := make([]string, 0, len(got.Suggestions))
+	for _, s := range got.Suggestions {
+		titles = append(titles, s.Title)
+		if s.Reason == "" {
+			t.Fatalf("suggestion without a reason: %+v", s)
+		}
+	}
+	want := []string{
+		"Graph Valid Tree",          // due, 3 days overdue in the weakest topic
+		"Find Center of Star Graph", // new, easy, weakest topic
+		"Course Schedule",           // new, medium (ties by title)
+		"Number of Islands",         // new, medium; Word Ladder is past the per-topic cap
+		"Validate BST",              // new, second weakest topic
+		"Invert Binary Tree",        // prerequisite from the library
+		"Binary Search",             // prerequisite from the catalog
+	}
+	if !reflect.DeepEqual(titles, want) {
+		t.Fatalf("suggestions = %v, want %v", titles, want)
+	}
+
+	first := got.Suggestions[0]
+	if first.Kind != KindDue || first.ProblemID != "g1" || !strings.Contains(first.Reason, "Overdue by 3 days") {
+		t.Fatalf("first suggestion = %+v", first)
+	}
+	for _, s := range got.Suggestions[5:] {
+		if s.Kind != KindPrerequisite || !strings.Contains(s.Reason, "Kth Smallest in a BST") {
+			t.Fatalf("expected a prerequisite of the failed problem, got %+v", s)
+		}
+	}
+	if got.Suggestions[6].ProblemID != "" {
+		t.Fatalf("catalog suggestions should have no problem id, got %+v", got.Suggestions[6])
+	}
+
+	if again := rank(now, library, catalog, 10); !reflect.DeepEqual(got, again) {
+		t.Fatalf("expected the same ranking twice")
+	}
+	if short := rank(now, library, catalog, 3); len(short.Suggestions) != 3 || short.Suggestions[2].Title != "Course Schedule" {
+		t.Fatalf("limit should keep the top suggestions, got %+v", short.Suggestions)
+	}
+}
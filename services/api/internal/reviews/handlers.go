@@ -9,6 +9,7 @@ import (
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/activity"
 	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/httpx"
 	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/problems"
 	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/reqctx"
@@ -164,6 +165,12 @@ func (h *Handler) Post(w http.ResponseWriter, r *http.Request) {
 		httpx.WriteError(w, http.StatusInternalServerError, "failed to write review log")
 		return
 	}
+	if err := activity.RecordReviewTx(ctx, tx, userID, loc, activity.Review{
+		ProblemID: req.ProblemID, Grade: req.Grade, ReviewedAtUTC: reviewedAt, TimeSpentSec: req.TimeSpentSec,
+	}); err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to record daily activity")
+		return
+	}
 
 	state, err := h.problemsRepo.GetStateForUpdate(ctx, tx, userID, req.ProblemID)
 	if err != nil {
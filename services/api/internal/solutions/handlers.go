@@ -0,0 +1,317 @@
+package solutions
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/activity"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/db"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/httpx"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/reqctx"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/users"
+)
+
+// Field limits. Code is capped well below the request body limit.
+const (
+	maxLanguageLen   = 32
+	maxCodeLen       = 64 * 1024
+	maxComplexityLen = 64
+	maxApproachLen   = 120
+)
+
+type Handler struct {
+	repo  *Repository
+	users *users.Repository
+}
+
+func NewHandler(repo *Repository, usersRepo *users.Repository) *Handler {
+	return &Handler{repo: repo, users: usersRepo}
+}
+
+type solutionResponse struct {
+	ID              string `json:"id"`
+	ProblemID       string `json:"problem_id"`
+	Language        string `json:"language"`
+	Code            string `json:"code"`
+	TimeComplexity  string `json:"time_complexity"`
+	SpaceComplexity string `json:"space_complexity"`
+	Approach        string `json:"approach"`
+	WrittenOn       string `json:"written_on"`
+	CreatedAt       string `json:"created_at"`
+	UpdatedAt       string `json:"updated_at"`
+}
+
+func toResponse(s Solution) solutionResponse {
+	return solutionResponse{
+		ID:              s.ID,
+		ProblemID:       s.ProblemID,
+		Language:        s.Language,
+		Code:            s.Code,
+		TimeComplexity:  s.TimeComplexity,
+		SpaceComplexity: s.SpaceComplexity,
+		Approach:        s.Approach,
+		WrittenOn:       s.WrittenOn.Format("2006-01-02"),
+		CreatedAt:       s.CreatedAt.UTC().Format(time.RFC3339),
+		UpdatedAt:       s.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+type solutionRequest struct {
+	Language        *string `json:"language"`
+	Code            *string `json:"code"`
+	TimeComplexity  *string `json:"time_complexity"`
+	SpaceComplexity *string `json:"space_complexity"`
+	Approach        *string `json:"approach"`
+	WrittenOn       *string `json:"written_on"`
+}
+
+// patch validates the fields present in the request. Languages are stored in
+// lower case so attempts in "Python" and "python" compare; written_on is a
+// YYYY-MM-DD date no later than today.
+func (req solutionRequest) patch(today time.Time) (Patch, error) {
+	var p Patch
+	text := func(name string, v *string, max int, required bool) (*string, error) {
+		if v == nil {
+			return nil, nil
+		}
+		s := strings.TrimSpace(*v)
+		if required && s == "" {
+			return nil, fmt.Errorf("%s cannot be empty", name)
+		}
+		if len(s) > max {
+			return nil, fmt.Errorf("%s must be at most %d bytes", name, max)
+		}
+		return &s, nil
+	}
+	var err error
+	if p.Language, err = text("language", req.Language, maxLanguageLen, true); err != nil {
+		return Patch{}, err
+	}
+	if p.Language != nil {
+		lang := strings.ToLower(*p.Language)
+		p.Language = &lang
+	}
+	if req.Code != nil {
+		// Keep the code's own indentation; only reject blank code.
+		if strings.TrimSpace(*req.Code) == "" {
+			return Patch{}, fmt.Errorf("code cannot be empty")
+		}
+		if len(*req.Code) > maxCodeLen {
+			return Patch{}, fmt.Errorf("code must be at most %d bytes", maxCodeLen)
+		}
+		p.Code = req.Code
+	}
+	if p.TimeComplexity, err = text("time_complexity", req.TimeComplexity, maxComplexityLen, false); err != nil {
+		return Patch{}, err
+	}
+	if p.SpaceComplexity, err = text("space_complexity", req.SpaceComplexity, maxComplexityLen, false); err != nil {
+		return Patch{}, err
+	}
+	if p.Approach, err = text("approach", req.Approach, maxApproachLen, false); err != nil {
+		return Patch{}, err
+	}
+	if req.WrittenOn != nil {
+		d, err := time.Parse("2006-01-02", strings.TrimSpace(*req.WrittenOn))
+		if err != nil {
+			return Patch{}, fmt.Errorf("written_on must be a date (YYYY-MM-DD)")
+		}
+		if d.After(today) {
+			return Patch{}, fmt.Errorf("written_on cannot be in the future")
+		}
+		p.WrittenOn = &d
+	}
+	return p, nil
+}
+
+// newSolution applies a create request: language and code are required and
+// written_on defaults to today.
+func newSolution(problemID string, req solutionRequest, today time.Time) (Solution, error) {
+	if req.Language == nil {
+		return Solution{}, fmt.Errorf("language required")
+	}
+	if req.Code == nil {
+		return Solution{}, fmt.Errorf("code required")
+	}
+	p, err := req.patch(today)
+	if err != nil {
+		return Solution{}, err
+	}
+	s := Solution{ProblemID: problemID, Language: *p.Language, Code: *p.Code, WrittenOn: today}
+	if p.TimeComplexity != nil {
+		s.TimeComplexity = *p.TimeComplexity
+	}
+	if p.SpaceComplexity != nil {
+		s.SpaceComplexity = *p.SpaceComplexity
+	}
+	if p.Approach != nil {
+		s.Approach = *p.Approach
+	}
+	if p.WrittenOn != nil {
+		s.WrittenOn = *p.WrittenOn
+	}
+	return s, nil
+}
+
+// today is the user's current local date.
+func (h *Handler) today(r *http.Request, userID string) (time.Time, error) {
+	settings, err := h.users.GetSettings(r.Context(), userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	loc, err := time.LoadLocation(settings.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	return activity.LocalDate(time.Now(), loc), nil
+}
+
+// List returns the user's solutions for the problem, oldest first, so attempts
+// read in the order they were written.
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httpx.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID, ok := reqctx.UserIDFromContext(r.Context())
+	if !ok {
+		httpx.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	problemID := strings.TrimSpace(chi.URLParam(r, "id"))
+	if problemID == "" {
+		httpx.WriteError(w, http.StatusBadRequest, "id required")
+		return
+	}
+
+	list, err := h.repo.List(r.Context(), userID, problemID)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load solutions")
+		return
+	}
+	out := make([]solutionResponse, 0, len(list))
+	for _, s := range list {
+		out = append(out, toResponse(s))
+	}
+	httpx.WriteJSON(w, http.StatusOK, out)
+}
+
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httpx.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID, ok := reqctx.UserIDFromContext(r.Context())
+	if !ok {
+		httpx.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	problemID := strings.TrimSpace(chi.URLParam(r, "id"))
+	if problemID == "" {
+		httpx.WriteError(w, http.StatusBadRequest, "id required")
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, 2*maxCodeLen)
+	var req solutionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpx.WriteError(w, http.StatusBadRequest, "invalid json body")
+		return
+	}
+	today, err := h.today(r, userID)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load user settings")
+		return
+	}
+	s, err := newSolution(problemID, req, today)
+	if err != nil {
+		httpx.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	out, err := h.repo.Create(r.Context(), userID, s)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			httpx.WriteError(w, http.StatusNotFound, "problem not found")
+			return
+		}
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to save solution")
+		return
+	}
+	httpx.WriteJSON(w, http.StatusCreated, toResponse(out))
+}
+
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPatch {
+		httpx.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID, ok := reqctx.UserIDFromContext(r.Context())
+	if !ok {
+		httpx.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	problemID := strings.TrimSpace(chi.URLParam(r, "id"))
+	solutionID := strings.TrimSpace(chi.URLParam(r, "solutionID"))
+	if problemID == "" || solutionID == "" {
+		httpx.WriteError(w, http.StatusBadRequest, "id required")
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, 2*maxCodeLen)
+	var req solutionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpx.WriteError(w, http.StatusBadRequest, "invalid json body")
+		return
+	}
+	today, err := h.today(r, userID)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load user settings")
+		return
+	}
+	p, err := req.patch(today)
+	if err != nil {
+		httpx.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	out, err := h.repo.Update(r.Context(), userID, problemID, solutionID, p)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			httpx.WriteError(w, http.StatusNotFound, "not found")
+			return
+		}
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to update solution")
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, toResponse(out))
+}
+
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		httpx.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID, ok := reqctx.UserIDFromContext(r.Context())
+	if !ok {
+		httpx.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	problemID := strings.TrimSpace(chi.URLParam(r, "id"))
+	solutionID := strings.TrimSpace(chi.URLParam(r, "solutionID"))
+	if problemID == "" || solutionID == "" {
+		httpx.WriteError(w, http.StatusBadRequest, "id required")
+		return
+	}
+	if err := h.repo.Delete(r.Context(), userID, problemID, solutionID); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			httpx.WriteError(w, http.StatusNotFound, "not found")
+			return
+		}
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to delete solution")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,52 @@
+package solutions
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewSolutionValidation(t *testing.T) {
+	today := time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC)
+	str := func(s string) *string { return &s }
+	code := "def two_sum(nums, target):\n    seen = {}\n"
+
+	s, err := newSolution("p-1", solutionRequest{
+		Language:       str("  Python "),
+		Code:           str(code),
+		TimeComplexity: str(" O(n) "),
+		Approach:       str("hash map"),
+	}, today)
+	if err != nil {
+		t.Fatalf("newSolution: %v", err)
+	}
+	if s.Language != "python" || s.Code != code || s.TimeComplexity != "O(n)" || s.SpaceComplexity != "" || s.Approach != "hash map" || !s.WrittenOn.Equal(today) {
+		t.Fatalf("unexpected solution %+v", s)
+	}
+
+	s, err = newSolution("p-1", solutionRequest{Language: str("go"), Code: str("x"), WrittenOn: str("2025-12-31")}, today)
+	if err != nil || s.WrittenOn != time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC) {
+		t.Fatalf("backdated solution = %+v, %v", s, err)
+	}
+
+	for name, req := range map[string]solutionRequest{
+		"missing language": {Code: str("x")},
+		"missing code":     {Language: str("go")},
+		"blank language":   {Language: str(" "), Code: str("x")},
+		"blank code":       {Language: str("go"), Code: str(" \n\t")},
+		"long approach":    {Language: str("go"), Code: str("x"), Approach: str(strings.Repeat("a", maxApproachLen+1))},
+		"long code":        {Language: str("go"), Code: str(strings.Repeat("a", maxCodeLen+1))},
+		"bad date":         {Language: str("go"), Code: str("x"), WrittenOn: str("03/09/2026")},
+		"future date":      {Language: str("go"), Code: str("x"), WrittenOn: str("2026-03-10")},
+	} {
+		if _, err := newSolution("p-1", req, today); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+
+	// A patch only touches the fields it carries, and may clear optional ones.
+	p, err := solutionRequest{Approach: str(""), WrittenOn: str("2026-03-01")}.patch(today)
+	if err != nil || p.Language != nil || p.Code != nil || p.Approach == nil || *p.Approach != "" || p.WrittenOn == nil {
+		t.Fatalf("patch = %+v, %v", p, err)
+	}
+}
@@ -0,0 +1,124 @@
+// Package solutions stores the code a user wrote for a problem, one row per
+// attempt, alongside (not inside) the problem's notes.
+package solutions
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/db"
+)
+
+// Solution is one written solution. WrittenOn is the user's local date as a
+// UTC midnight.
+type Solution struct {
+	ID              string
+	ProblemID       string
+	Language        string
+	Code            string
+	TimeComplexity  string
+	SpaceComplexity string
+	Approach        string
+	WrittenOn       time.Time
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// Patch holds the fields an update changes; nil leaves a field as is.
+type Patch struct {
+	Language        *string
+	Code            *string
+	TimeComplexity  *string
+	SpaceComplexity *string
+	Approach        *string
+	WrittenOn       *time.Time
+}
+
+type Repository struct {
+	pool *pgxpool.Pool
+}
+
+func NewRepository(pool *pgxpool.Pool) *Repository {
+	return &Repository{pool: pool}
+}
+
+const solutionColumns = `id::text, problem_id::text, language, code, time_complexity, space_complexity, approach, written_on, created_at, updated_at`
+
+func scanSolution(row pgx.Row) (Solution, error) {
+	var s Solution
+	err := row.Scan(&s.ID, &s.ProblemID, &s.Language, &s.Code, &s.TimeComplexity, &s.SpaceComplexity, &s.Approach, &s.WrittenOn, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Solution{}, db.ErrNotFound
+		}
+		return Solution{}, err
+	}
+	return s, nil
+}
+
+// List returns the user's solutions for a problem, oldest first.
+func (r *Repository) List(ctx context.Context, userID string, problemID string) ([]Solution, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+solutionColumns+`
+		FROM problem_solutions
+		WHERE user_id = $1 AND problem_id = $2
+		ORDER BY written_on, created_at
+	`, userID, problemID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]Solution, 0)
+	for rows.Next() {
+		s, err := scanSolution(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+// Create adds a solution. It returns db.ErrNotFound unless the problem is in
+// the user's library.
+func (r *Repository) Create(ctx context.Context, userID string, s Solution) (Solution, error) {
+	return scanSolution(r.pool.QueryRow(ctx, `
+		INSERT INTO problem_solutions (user_id, problem_id, language, code, time_complexity, space_complexity, approach, written_on)
+		SELECT s.user_id, s.problem_id, $3, $4, $5, $6, $7, $8
+		FROM user_problem_state s
+		WHERE s.user_id = $1 AND s.problem_id = $2
+		RETURNING `+solutionColumns,
+		userID, s.ProblemID, s.Language, s.Code, s.TimeComplexity, s.SpaceComplexity, s.Approach, s.WrittenOn))
+}
+
+func (r *Repository) Update(ctx context.Context, userID string, problemID string, id string, p Patch) (Solution, error) {
+	return scanSolution(r.pool.QueryRow(ctx, `
+		UPDATE problem_solutions
+		SET language = COALESCE($4, language),
+		    code = COALESCE($5, code),
+		    time_complexity = COALESCE($6, time_complexity),
+		    space_complexity = COALESCE($7, space_complexity),
+		    approach = COALESCE($8, approach),
+		    written_on = COALESCE($9, written_on),
+		    updated_at = now()
+		WHERE id = $1 AND user_id = $2 AND problem_id = $3
+		RETURNING `+solutionColumns,
+		id, userID, problemID, p.Language, p.Code, p.TimeComplexity, p.SpaceComplexity, p.Approach, p.WrittenOn))
+}
+
+func (r *Repository) Delete(ctx context.Context, userID string, problemID string, id string) error {
+	ct, err := r.pool.Exec(ctx, `
+		DELETE FROM problem_solutions
+		WHERE id = $1 AND user_id = $2 AND problem_id = $3
+	`, id, userID, problemID)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return db.ErrNotFound
+	}
+	return nil
+}
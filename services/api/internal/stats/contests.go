@@ -2,12 +2,11 @@ package stats
 
 import (
 	"net/http"
-	"strconv"
 	"time"
 
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/activity"
 	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/httpx"
 	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/reqctx"
-	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/scheduler"
 )
 
 type ContestStatsTotals struct {
@@ -39,31 +38,43 @@ type ContestStatsRecent struct {
 	SolvedCount      int        `json:"solved_count"`
 	AvgGrade         *float64   `json:"avg_grade,omitempty"`
 	TotalTimeSec     int        `json:"total_time_sec"`
+	RatingAfter      *float64   `json:"rating_after,omitempty"`
+	RatingDelta      *float64   `json:"rating_delta,omitempty"`
+}
+
+// ContestRating is the current rating for one scope: "overall", "difficulty:<d>" or "topic:<t>".
+type ContestRating struct {
+	Scope     string    `json:"scope"`
+	Rating    float64   `json:"rating"`
+	Contests  int       `json:"contests"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ContestRatingPoint is one rating change, for charting a scope over time.
+type ContestRatingPoint struct {
+	ContestID    string    `json:"contest_id"`
+	Scope        string    `json:"scope"`
+	RecordedAt   time.Time `json:"recorded_at"`
+	RatingBefore float64   `json:"rating_before"`
+	RatingAfter  float64   `json:"rating_after"`
 }
 
 type ContestStatsResponse struct {
-	WindowDays int               `json:"window_days"`
-	Totals     ContestStatsTotals `json:"totals"`
-	Days       []ContestStatsDay `json:"days"`
-	Recent     []ContestStatsRecent `json:"recent"`
+	WindowInfo
+	Totals        ContestStatsTotals   `json:"totals"`
+	Days          []ContestStatsDay    `json:"days"`
+	Recent        []ContestStatsRecent `json:"recent"`
+	Ratings       []ContestRating      `json:"ratings"`
+	RatingHistory []ContestRatingPoint `json:"rating_history"`
 }
 
-func parseWindowDays(r *http.Request) int {
-	q := r.URL.Query().Get("window_days")
-	if q == "" {
-		return 30
-	}
-	n, err := strconv.Atoi(q)
-	if err != nil {
-		return 30
-	}
-	if n < 1 {
-		return 1
-	}
-	if n > 180 {
-		return 180
+// avgOf is sum/n, or nil when nothing was graded.
+func avgOf(sum int, n int) *float64 {
+	if n == 0 {
+		return nil
 	}
-	return n
+	v := float64(sum) / float64(n)
+	return &v
 }
 
 func (h *Handler) Contests(w http.ResponseWriter, r *http.Request) {
@@ -87,146 +98,80 @@ func (h *Handler) Contests(w http.ResponseWriter, r *http.Request) {
 		loc = time.UTC
 	}
 
-	windowDays := parseWindowDays(r)
-	nowUTC := time.Now().UTC()
-	startTodayLocal := scheduler.AnchorLocalDay(nowUTC, loc, 0, 0)
-	startWindowLocal := startTodayLocal.AddDate(0, 0, -(windowDays - 1))
-	startWindowUTC := startWindowLocal.UTC()
-
-	// Totals (results + finished contests).
-	var totals ContestStatsTotals
-	var avgGrade *float64
-	err = h.pool.QueryRow(r.Context(), `
-		SELECT
-			COUNT(*) AS problems_recorded,
-			COUNT(*) FILTER (WHERE cr.solved_flag = true) AS solved_count,
-			AVG(cr.grade)::float8 AS avg_grade,
-			COALESCE(SUM(cr.time_spent_sec), 0) AS total_time_sec
-		FROM contest_results cr
-		JOIN contests c ON c.id = cr.contest_id
-		WHERE c.user_id = $1 AND cr.recorded_at >= $2
-	`, userID, startWindowUTC).Scan(&totals.ProblemsRecorded, &totals.SolvedCount, &avgGrade, &totals.TotalTimeSec)
-	if err != nil {
-		httpx.WriteError(w, http.StatusInternalServerError, "failed to load contest totals")
-		return
-	}
-	totals.AvgGrade = avgGrade
-
-	_ = h.pool.QueryRow(r.Context(), `
-		SELECT COUNT(*)
-		FROM contests
-		WHERE user_id = $1 AND completed_at IS NOT NULL AND completed_at >= $2
-	`, userID, startWindowUTC).Scan(&totals.ContestsFinished)
-
-	// Day series: results.
-	type resultsRow struct {
-		key       string
-		recorded  int
-		solved    int
-		avg       *float64
-		totalTime int
-	}
-	resultsByDay := map[string]resultsRow{}
-	rows, err := h.pool.Query(r.Context(), `
-		SELECT
-			(cr.recorded_at AT TIME ZONE $2)::date AS d,
-			COUNT(*) AS problems_recorded,
-			COUNT(*) FILTER (WHERE cr.solved_flag = true) AS solved_count,
-			AVG(cr.grade)::float8 AS avg_grade,
-			COALESCE(SUM(cr.time_spent_sec), 0) AS total_time_sec
-		FROM contest_results cr
-		JOIN contests c ON c.id = cr.contest_id
-		WHERE c.user_id = $1 AND cr.recorded_at >= $3
-		GROUP BY d
-		ORDER BY d ASC
-	`, userID, loc.String(), startWindowUTC)
+	win, err := parseWindow(r.URL.Query(), loc, time.Now().UTC(), 30, 180)
 	if err != nil {
-		httpx.WriteError(w, http.StatusInternalServerError, "failed to load contest daily series")
+		httpx.WriteError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	for rows.Next() {
-		var d time.Time
-		var rr resultsRow
-		if err := rows.Scan(&d, &rr.recorded, &rr.solved, &rr.avg, &rr.totalTime); err != nil {
-			rows.Close()
-			httpx.WriteError(w, http.StatusInternalServerError, "failed to parse contest daily series")
-			return
-		}
-		rr.key = d.Format("2006-01-02")
-		resultsByDay[rr.key] = rr
-	}
-	rows.Close()
 
-	// Day series: finished contests.
-	finishedByDay := map[string]int{}
-	rows, err = h.pool.Query(r.Context(), `
-		SELECT
-			(completed_at AT TIME ZONE $2)::date AS d,
-			COUNT(*) AS contests_finished
-		FROM contests
-		WHERE user_id = $1 AND completed_at IS NOT NULL AND completed_at >= $3
-		GROUP BY d
-		ORDER BY d ASC
-	`, userID, loc.String(), startWindowUTC)
+	// Totals and day series, from the daily rollup.
+	rollup, err := h.activity.Range(r.Context(), userID, win.From, win.To)
 	if err != nil {
-		httpx.WriteError(w, http.StatusInternalServerError, "failed to load contest daily finished series")
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load contest daily series")
 		return
 	}
-	for rows.Next() {
-		var d time.Time
-		var n int
-		if err := rows.Scan(&d, &n); err != nil {
-			rows.Close()
-			httpx.WriteError(w, http.StatusInternalServerError, "failed to parse contest daily finished series")
-			return
-		}
-		finishedByDay[d.Format("2006-01-02")] = n
-	}
-	rows.Close()
-
-	days := make([]ContestStatsDay, 0, windowDays)
-	for i := 0; i < windowDays; i++ {
-		dayLocal := startWindowLocal.AddDate(0, 0, i)
-		key := dayLocal.Format("2006-01-02")
-		rr, has := resultsByDay[key]
+	byDay := make(map[string]activity.Day, len(rollup))
+	var totals ContestStatsTotals
+	graded, gradeSum := 0, 0
+	for _, d := range rollup {
+		byDay[d.Date.Format("2006-01-02")] = d
+		totals.ContestsFinished += d.ContestsFinished
+		totals.ProblemsRecorded += d.ContestProblems
+		totals.SolvedCount += d.ContestSolved
+		totals.TotalTimeSec += d.ContestTimeSec
+		graded += d.ContestGraded
+		gradeSum += d.ContestGradeSum
+	}
+	totals.AvgGrade = avgOf(gradeSum, graded)
+
+	days := make([]ContestStatsDay, 0, win.Days)
+	for i := 0; i < win.Days; i++ {
+		key := win.From.AddDate(0, 0, i).Format("2006-01-02")
+		d := byDay[key]
 		days = append(days, ContestStatsDay{
 			Date:             key,
-			ContestsFinished: finishedByDay[key],
-			ProblemsRecorded: func() int { if has { return rr.recorded }; return 0 }(),
-			SolvedCount:      func() int { if has { return rr.solved }; return 0 }(),
-			AvgGrade:         func() *float64 { if has { return rr.avg }; return nil }(),
-			TotalTimeSec:     func() int { if has { return rr.totalTime }; return 0 }(),
+			ContestsFinished: d.ContestsFinished,
+			ProblemsRecorded: d.ContestProblems,
+			SolvedCount:      d.ContestSolved,
+			AvgGrade:         avgOf(d.ContestGradeSum, d.ContestGraded),
+			TotalTimeSec:     d.ContestTimeSec,
 		})
 	}
 
-	// Recent contests: last 12 completed, with aggregates.
+	// Recent contests: last 12 completed, aggregating only their own rows.
 	recent := make([]ContestStatsRecent, 0, 12)
-	rows, err = h.pool.Query(r.Context(), `
+	rows, err := h.pool.Query(r.Context(), `
 		SELECT
 			c.id::text, c.strategy, c.duration_minutes, c.created_at, c.started_at, c.completed_at,
 			COALESCE(ci.total_items, 0) AS total_items,
 			COALESCE(cr.recorded_count, 0) AS recorded_count,
 			COALESCE(cr.solved_count, 0) AS solved_count,
 			cr.avg_grade,
-			COALESCE(cr.total_time_sec, 0) AS total_time_sec
-		FROM contests c
-		LEFT JOIN (
-			SELECT contest_id, COUNT(*) AS total_items
+			COALESCE(cr.total_time_sec, 0) AS total_time_sec,
+			rh.rating_after, rh.rating_after - rh.rating_before
+		FROM (
+			SELECT c.*
+			FROM contests c
+			JOIN contest_participants cp ON cp.contest_id = c.id AND cp.user_id = $1
+			WHERE c.completed_at IS NOT NULL
+			ORDER BY c.completed_at DESC
+			LIMIT 12
+		) c
+		LEFT JOIN LATERAL (
+			SELECT COUNT(*) AS total_items
 			FROM contest_items
-			GROUP BY contest_id
-		) ci ON ci.contest_id = c.id
-		LEFT JOIN (
-			SELECT contest_id,
-			       COUNT(*) AS recorded_count,
+			WHERE contest_id = c.id
+		) ci ON true
+		LEFT JOIN LATERAL (
+			SELECT COUNT(*) AS recorded_count,
 			       COUNT(*) FILTER (WHERE solved_flag = true) AS solved_count,
 			       AVG(grade)::float8 AS avg_grade,
 			       COALESCE(SUM(time_spent_sec), 0) AS total_time_sec
 			FROM contest_results
-			GROUP BY contest_id
-		) cr ON cr.contest_id = c.id
-		WHERE c.user_id = $1 AND c.completed_at IS NOT NULL
+			WHERE contest_id = c.id AND user_id = $1
+		) cr ON true
+		LEFT JOIN contest_rating_history rh ON rh.contest_id = c.id AND rh.user_id = $1 AND rh.scope = 'overall'
 		ORDER BY c.completed_at DESC
-		LIMIT 12
 	`, userID)
 	if err == nil {
 		for rows.Next() {
@@ -234,6 +179,7 @@ func (h *Handler) Contests(w http.ResponseWriter, r *http.Request) {
 			if err := rows.Scan(
 				&rc.ContestID, &rc.Strategy, &rc.DurationMinutes, &rc.CreatedAt, &rc.StartedAt, &rc.CompletedAt,
 				&rc.TotalItems, &rc.RecordedCount, &rc.SolvedCount, &rc.AvgGrade, &rc.TotalTimeSec,
+				&rc.RatingAfter, &rc.RatingDelta,
 			); err != nil {
 				break
 			}
@@ -242,11 +188,58 @@ func (h *Handler) Contests(w http.ResponseWriter, r *http.Request) {
 		rows.Close()
 	}
 
+	// Ratings: current value per scope, plus every change inside the window.
+	ratings := make([]ContestRating, 0)
+	rows, err = h.pool.Query(r.Context(), `
+		SELECT scope, rating, contests, updated_at
+		FROM contest_ratings
+		WHERE user_id = $1
+		ORDER BY (scope = 'overall') DESC, scope ASC
+	`, userID)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load contest ratings")
+		return
+	}
+	for rows.Next() {
+		var cr ContestRating
+		if err := rows.Scan(&cr.Scope, &cr.Rating, &cr.Contests, &cr.UpdatedAt); err != nil {
+			rows.Close()
+			httpx.WriteError(w, http.StatusInternalServerError, "failed to parse contest ratings")
+			return
+		}
+		ratings = append(ratings, cr)
+	}
+	rows.Close()
+
+	history := make([]ContestRatingPoint, 0)
+	rows, err = h.pool.Query(r.Context(), `
+		SELECT contest_id::text, scope, recorded_at, rating_before, rating_after
+		FROM contest_rating_history
+		WHERE user_id = $1 AND recorded_at >= $2 AND recorded_at < $3
+		ORDER BY recorded_at ASC, id ASC
+	`, userID, win.Start, win.End)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load contest rating history")
+		return
+	}
+	for rows.Next() {
+		var p ContestRatingPoint
+		if err := rows.Scan(&p.ContestID, &p.Scope, &p.RecordedAt, &p.RatingBefore, &p.RatingAfter); err != nil {
+			rows.Close()
+			httpx.WriteError(w, http.StatusInternalServerError, "failed to parse contest rating history")
+			return
+		}
+		history = append(history, p)
+	}
+	rows.Close()
+
 	httpx.WriteJSON(w, http.StatusOK, ContestStatsResponse{
-		WindowDays: windowDays,
-		Totals:     totals,
-		Days:       days,
-		Recent:     recent,
+		WindowInfo:    win.info(),
+		Totals:        totals,
+		Days:          days,
+		Recent:        recent,
+		Ratings:       ratings,
+		RatingHistory: history,
 	})
 }
 
@@ -0,0 +1,228 @@
+package stats
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/activity"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/httpx"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/mastery"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/reqctx"
+)
+
+// CSV exports stream rows as they are scanned, so their windows may be much
+// longer than the JSON endpoints'. Columns are only ever appended.
+const (
+	exportDefaultDays = 365
+	exportMaxDays     = 3660
+)
+
+var reviewHistoryColumns = []string{
+	"review_id", "reviewed_at", "local_date", "problem_id", "title", "platform", "difficulty", "topics",
+	"grade", "time_spent_sec", "source", "contest_id",
+}
+
+var dailyColumns = []string{
+	"date", "reviews", "time_spent_sec", "goal_met", "frozen",
+	"contests_finished", "contest_problems", "contest_solved", "contest_graded", "contest_avg_grade", "contest_time_sec",
+}
+
+type reviewRow struct {
+	ID           string
+	ReviewedAt   time.Time
+	ProblemID    string
+	Title        string
+	Platform     string
+	Difficulty   string
+	Topics       []string
+	Grade        int
+	TimeSpentSec *int
+	Source       string
+	ContestID    *string
+}
+
+// record formats the row for reviewHistoryColumns. Times are RFC 3339 in UTC,
+// local_date is the day in loc, topics are joined with ";" and missing values
+// are empty.
+func (r reviewRow) record(loc *time.Location) []string {
+	spent, contest := "", ""
+	if r.TimeSpentSec != nil {
+		spent = strconv.Itoa(*r.TimeSpentSec)
+	}
+	if r.ContestID != nil {
+		contest = *r.ContestID
+	}
+	return []string{
+		r.ID,
+		r.ReviewedAt.UTC().Format(time.RFC3339),
+		activity.LocalDate(r.ReviewedAt, loc).Format("2006-01-02"),
+		r.ProblemID,
+		r.Title,
+		r.Platform,
+		r.Difficulty,
+		strings.Join(r.Topics, ";"),
+		strconv.Itoa(r.Grade),
+		spent,
+		r.Source,
+		contest,
+	}
+}
+
+// dayRecord formats a day for dailyColumns.
+func dayRecord(d activity.Day) []string {
+	avg := ""
+	if v := avgOf(d.ContestGradeSum, d.ContestGraded); v != nil {
+		avg = strconv.FormatFloat(*v, 'f', 2, 64)
+	}
+	return []string{
+		d.Date.Format("2006-01-02"),
+		strconv.Itoa(d.Reviews),
+		strconv.Itoa(d.TimeSpentSec),
+		strconv.FormatBool(d.GoalMet),
+		strconv.FormatBool(d.Frozen),
+		strconv.Itoa(d.ContestsFinished),
+		strconv.Itoa(d.ContestProblems),
+		strconv.Itoa(d.ContestSolved),
+		strconv.Itoa(d.ContestGraded),
+		avg,
+		strconv.Itoa(d.ContestTimeSec),
+	}
+}
+
+// startCSV writes the headers and the column row. After it the status is sent,
+// so later failures can only cut the body short.
+func startCSV(w http.ResponseWriter, filename string, columns []string) (*csv.Writer, error) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.WriteHeader(http.StatusOK)
+	cw := csv.NewWriter(w)
+	return cw, cw.Write(columns)
+}
+
+// ReviewHistoryCSV streams the user's review logs in the window, oldest first.
+// It takes the stats window parameters plus problem_id, topic and source filters.
+func (h *Handler) ReviewHistoryCSV(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httpx.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID, ok := reqctx.UserIDFromContext(r.Context())
+	if !ok {
+		httpx.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	loc, err := h.location(r.Context(), userID)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load user settings")
+		return
+	}
+	q := r.URL.Query()
+	win, err := parseWindow(q, loc, time.Now().UTC(), exportDefaultDays, exportMaxDays)
+	if err != nil {
+		httpx.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	args := []any{userID, win.Start, win.End}
+	filters := ""
+	if v := strings.TrimSpace(q.Get("problem_id")); v != "" {
+		args = append(args, v)
+		filters += fmt.Sprintf(" AND rl.problem_id::text = $%d", len(args))
+	}
+	if v := mastery.NormalizeTopic(q.Get("topic")); v != "" {
+		args = append(args, v)
+		filters += fmt.Sprintf(" AND EXISTS (SELECT 1 FROM unnest(p.topics) AS t(topic) WHERE lower(trim(t.topic)) = $%d)", len(args))
+	}
+	if v := strings.TrimSpace(q.Get("source")); v != "" {
+		args = append(args, v)
+		filters += fmt.Sprintf(" AND rl.source = $%d", len(args))
+	}
+
+	rows, err := h.pool.Query(r.Context(), `
+		SELECT rl.id::text, rl.reviewed_at, p.id::text, p.title, p.platform, p.difficulty, p.topics,
+		       rl.grade, rl.time_spent_sec, rl.source, rl.contest_id::text
+		FROM review_logs rl
+		JOIN problems p ON p.id = rl.problem_id
+		WHERE rl.user_id = $1 AND rl.reviewed_at >= $2 AND rl.reviewed_at < $3`+filters+`
+		ORDER BY rl.reviewed_at, rl.id
+	`, args...)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load review history")
+		return
+	}
+	defer rows.Close()
+
+	cw, err := startCSV(w, "review-history.csv", reviewHistoryColumns)
+	for err == nil && rows.Next() {
+		var rv reviewRow
+		if err = rows.Scan(&rv.ID, &rv.ReviewedAt, &rv.ProblemID, &rv.Title, &rv.Platform, &rv.Difficulty, &rv.Topics,
+			&rv.Grade, &rv.TimeSpentSec, &rv.Source, &rv.ContestID); err == nil {
+			err = cw.Write(rv.record(loc))
+		}
+	}
+	if err == nil {
+		err = rows.Err()
+	}
+	cw.Flush()
+	if err == nil {
+		err = cw.Error()
+	}
+	if err != nil {
+		log.Printf("review history export user_id=%s err=%v", userID, err)
+	}
+}
+
+// DailyCSV streams one row per local day in the window from the daily activity
+// rollup, oldest first, including days without activity.
+func (h *Handler) DailyCSV(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httpx.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID, ok := reqctx.UserIDFromContext(r.Context())
+	if !ok {
+		httpx.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	loc, err := h.location(r.Context(), userID)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load user settings")
+		return
+	}
+	win, err := parseWindow(r.URL.Query(), loc, time.Now().UTC(), exportDefaultDays, exportMaxDays)
+	if err != nil {
+		httpx.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var cw *csv.Writer
+	err = h.activity.EachDay(r.Context(), userID, win.From, win.To, func(d activity.Day) error {
+		if cw == nil {
+			var err error
+			if cw, err = startCSV(w, "daily-activity.csv", dailyColumns); err != nil {
+				return err
+			}
+		}
+		return cw.Write(dayRecord(d))
+	})
+	if cw == nil {
+		if err != nil {
+			// Nothing was sent yet, so the failure can still be reported.
+			httpx.WriteError(w, http.StatusInternalServerError, "failed to load daily activity")
+			return
+		}
+		cw, err = startCSV(w, "daily-activity.csv", dailyColumns)
+	}
+	cw.Flush()
+	if err == nil {
+		err = cw.Error()
+	}
+	if err != nil {
+		log.Printf("daily activity export user_id=%s err=%v", userID, err)
+	}
+}
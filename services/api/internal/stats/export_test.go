@@ -0,0 +1,44 @@
+package stats
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/activity"
+)
+
+func TestExportRecordsMatchColumns(t *testing.T) {
+	ny := mustLoad(t, "America/New_York")
+	spent, contest := 90, "c-1"
+	rv := reviewRow{
+		ID:           "r-1",
+		ReviewedAt:   time.Date(2026, 3, 9, 2, 30, 0, 0, time.UTC), // 22:30 on 03-08 in New York
+		ProblemID:    "p-1",
+		Title:        "Two Sum, again",
+		Platform:     "leetcode",
+		Difficulty:   "easy",
+		Topics:       []string{"array", "hashmap"},
+		Grade:        3,
+		TimeSpentSec: &spent,
+		Source:       "contest",
+		ContestID:    &contest,
+	}
+	got := rv.record(ny)
+	want := []string{"r-1", "2026-03-09T02:30:00Z", "2026-03-08", "p-1", "Two Sum, again", "leetcode", "easy", "array;hashmap", "3", "90", "contest", "c-1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("review record = %q, want %q", got, want)
+	}
+	rv.TimeSpentSec, rv.ContestID = nil, nil
+	if got := rv.record(ny); len(got) != len(reviewHistoryColumns) || got[9] != "" || got[11] != "" {
+		t.Fatalf("missing values should be empty, got %q", got)
+	}
+
+	day := dayRecord(activity.Day{Date: date(2026, 3, 8), Reviews: 4, TimeSpentSec: 1200, GoalMet: true, ContestGraded: 3, ContestGradeSum: 10})
+	if len(day) != len(dailyColumns) || day[0] != "2026-03-08" || day[3] != "true" || day[9] != "3.33" {
+		t.Fatalf("day record = %q", day)
+	}
+	if day := dayRecord(activity.Day{Date: date(2026, 3, 9)}); day[9] != "" {
+		t.Fatalf("a day without graded contest problems should have no average, got %q", day[9])
+	}
+}
@@ -2,37 +2,55 @@ package stats
 
 import (
 	"context"
-	"math"
+	"encoding/json"
+	"errors"
 	"net/http"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/activity"
 	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/httpx"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/mastery"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/problems"
 	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/reqctx"
-	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/scheduler"
 	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/users"
 )
 
 type Handler struct {
-	pool  *pgxpool.Pool
-	users *users.Repository
+	pool     *pgxpool.Pool
+	users    *users.Repository
+	activity *activity.Repository
 }
 
 func NewHandler(pool *pgxpool.Pool, usersRepo *users.Repository) *Handler {
-	return &Handler{pool: pool, users: usersRepo}
+	return &Handler{pool: pool, users: usersRepo, activity: activity.NewRepository(pool)}
 }
 
 type Overview struct {
+	WindowInfo
 	ActiveProblems    int `json:"active_problems"`
 	OverdueCount      int `json:"overdue_count"`
 	DueTodayCount     int `json:"due_today_count"`
 	DueSoonCount      int `json:"due_soon_count"`
+	DueSoonDays       int `json:"due_soon_days"`
 	ReviewsLast7Days  int `json:"reviews_last_7_days"`
+	ReviewsInWindow   int `json:"reviews_in_window"`
 	CurrentStreakDays int `json:"current_streak_days"`
 }
 
+// Overview defaults: reviews are counted over the last 7 local days and "due
+// soon" covers the 3 days after today.
+const (
+	overviewWindowDays  = 7
+	overviewDueSoonDays = 3
+)
+
+// Overview summarizes the schedule and recent activity. from/to (or
+// window_days) choose the days reviews_in_window counts; due_soon_days how far
+// past today due_soon_count looks.
 func (h *Handler) Overview(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		httpx.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
@@ -53,11 +71,24 @@ func (h *Handler) Overview(w http.ResponseWriter, r *http.Request) {
 		loc = time.UTC
 	}
 	now := time.Now().UTC()
-	startTodayLocal := scheduler.AnchorLocalDay(now, loc, 0, 0)
-	startTomorrowLocal := startTodayLocal.AddDate(0, 0, 1)
-	startTodayUTC := startTodayLocal.UTC()
-	startTomorrowUTC := startTomorrowLocal.UTC()
-	dueSoonUTC := startTomorrowLocal.AddDate(0, 0, 3).UTC()
+	win, err := parseWindow(r.URL.Query(), loc, now, overviewWindowDays, 365)
+	if err != nil {
+		httpx.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	dueSoonDays := overviewDueSoonDays
+	if v := strings.TrimSpace(r.URL.Query().Get("due_soon_days")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 30 {
+			httpx.WriteError(w, http.StatusBadRequest, "due_soon_days must be 1..30")
+			return
+		}
+		dueSoonDays = n
+	}
+	today := activity.LocalDate(now, loc)
+	startTodayUTC := startOfDay(today, loc).UTC()
+	startTomorrowUTC := startOfDay(today.AddDate(0, 0, 1), loc).UTC()
+	dueSoonUTC := startOfDay(today.AddDate(0, 0, 1+dueSoonDays), loc).UTC()
 
 	var active, overdue, dueToday, dueSoon int
 	err = h.pool.QueryRow(r.Context(), `
@@ -74,29 +105,99 @@ func (h *Handler) Overview(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var reviews7 int
-	_ = h.pool.QueryRow(r.Context(), `
-		SELECT COUNT(*)
-		FROM review_logs
-		WHERE user_id = $1 AND reviewed_at >= $2
-	`, userID, now.Add(-7*24*time.Hour)).Scan(&reviews7)
+	// Review counts from the daily rollup: the window, and the last 7 local days
+	// including today.
+	last7 := today.AddDate(0, 0, -(overviewWindowDays - 1))
+	first := win.From
+	if last7.Before(first) {
+		first = last7
+	}
+	recent, err := h.activity.Range(r.Context(), userID, first, today)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load daily activity")
+		return
+	}
+	reviews7, reviewsInWindow := 0, 0
+	for _, d := range recent {
+		if !d.Date.Before(last7) {
+			reviews7 += d.Reviews
+		}
+		if !d.Date.Before(win.From) && !d.Date.After(win.To) {
+			reviewsInWindow += d.Reviews
+		}
+	}
 
-	streak := h.currentStreakDays(r.Context(), userID, loc, now)
+	streaks, err := h.activity.Streaks(r.Context(), userID, today)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load streaks")
+		return
+	}
 
 	httpx.WriteJSON(w, http.StatusOK, Overview{
+		WindowInfo:        win.info(),
 		ActiveProblems:    active,
 		OverdueCount:      overdue,
 		DueTodayCount:     dueToday,
 		DueSoonCount:      dueSoon,
+		DueSoonDays:       dueSoonDays,
 		ReviewsLast7Days:  reviews7,
-		CurrentStreakDays: streak,
+		ReviewsInWindow:   reviewsInWindow,
+		CurrentStreakDays: streaks.CurrentDays,
 	})
 }
 
 type TopicStat struct {
-	Topic      string  `json:"topic"`
-	Count      int     `json:"count"`
-	MasteryAvg float64 `json:"mastery_avg"`
+	Topic       string  `json:"topic"`
+	Count       int     `json:"count"`
+	MasteryAvg  float64 `json:"mastery_avg"`
+	MasteryLow  float64 `json:"mastery_low"`
+	MasteryHigh float64 `json:"mastery_high"`
+}
+
+// ProblemMastery is one active problem with its mastery estimate.
+type ProblemMastery struct {
+	ProblemID  string   `json:"problem_id"`
+	Title      string   `json:"title"`
+	Difficulty string   `json:"difficulty"`
+	Topics     []string `json:"topics"`
+	mastery.Estimate
+}
+
+// problemMasteries estimates mastery for every active problem of the user.
+func (h *Handler) problemMasteries(ctx context.Context, userID string, now time.Time) ([]ProblemMastery, error) {
+	rows, err := h.pool.Query(ctx, `
+		SELECT p.id::text, p.title, p.difficulty, p.topics,
+		       s.reps, s.interval_days, s.ease, s.due_at, s.last_review_at, s.last_grade, s.recent_grades
+		FROM problems p
+		JOIN user_problem_state s ON s.problem_id = p.id
+		WHERE s.user_id = $1 AND s.is_active = true
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]ProblemMastery, 0)
+	for rows.Next() {
+		var pm ProblemMastery
+		var st problems.UserState
+		var grades []int
+		if err := rows.Scan(&pm.ProblemID, &pm.Title, &pm.Difficulty, &pm.Topics,
+			&st.Reps, &st.IntervalDays, &st.Ease, &st.DueAt, &st.LastReviewAt, &st.LastGrade, &grades); err != nil {
+			return nil, err
+		}
+		pm.Estimate = mastery.Problem(st, grades, now)
+		out = append(out, pm)
+	}
+	return out, rows.Err()
+}
+
+// topicMasteries groups problem estimates by normalized topic, weakest first.
+func topicMasteries(ps []ProblemMastery) []mastery.TopicEstimate {
+	tagged := make([]mastery.Tagged, len(ps))
+	for i, p := range ps {
+		tagged[i] = mastery.Tagged{Topics: p.Topics, Estimate: p.Estimate}
+	}
+	return mastery.ByTopic(tagged)
 }
 
 func (h *Handler) Topics(w http.ResponseWriter, r *http.Request) {
@@ -109,79 +210,87 @@ func (h *Handler) Topics(w http.ResponseWriter, r *http.Request) {
 		httpx.WriteError(w, http.StatusUnauthorized, "unauthorized")
 		return
 	}
-	now := time.Now().UTC()
-
-	rows, err := h.pool.Query(r.Context(), `
-		SELECT p.topics, s.reps, s.ease, s.due_at
-		FROM problems p
-		JOIN user_problem_state s ON s.problem_id = p.id
-		WHERE s.user_id = $1 AND s.is_active = true
-	`, userID)
+	ps, err := h.problemMasteries(r.Context(), userID, time.Now().UTC())
 	if err != nil {
 		httpx.WriteError(w, http.StatusInternalServerError, "failed to load topics")
 		return
 	}
-	defer rows.Close()
-
-	type agg struct {
-		sum float64
-		n   int
+	topics := topicMasteries(ps)
+	out := make([]TopicStat, 0, len(topics))
+	for _, t := range topics {
+		out = append(out, TopicStat{
+			Topic:       t.Topic,
+			Count:       t.Problems,
+			MasteryAvg:  t.Score,
+			MasteryLow:  t.Low,
+			MasteryHigh: t.High,
+		})
 	}
-	byTopic := map[string]*agg{}
+	httpx.WriteJSON(w, http.StatusOK, out)
+}
 
-	for rows.Next() {
-		var topics []string
-		var reps int
-		var ease float64
-		var dueAt time.Time
-		if err := rows.Scan(&topics, &reps, &ease, &dueAt); err != nil {
-			httpx.WriteError(w, http.StatusInternalServerError, "failed to parse topics")
-			return
-		}
-		od := 0
-		if now.After(dueAt) {
-			od = int(now.Sub(dueAt).Hours() / 24)
-		}
-		mastery := masteryScore(reps, ease, od)
-		for _, t := range topics {
-			t = strings.TrimSpace(strings.ToLower(t))
-			if t == "" {
-				continue
-			}
-			a := byTopic[t]
-			if a == nil {
-				a = &agg{}
-				byTopic[t] = a
-			}
-			a.sum += mastery
-			a.n++
-		}
-	}
+type MasteryResponse struct {
+	Problems []ProblemMastery        `json:"problems"`
+	Topics   []mastery.TopicEstimate `json:"topics"`
+}
 
-	out := make([]TopicStat, 0, len(byTopic))
-	for t, a := range byTopic {
-		if a.n == 0 {
-			continue
+// Mastery returns per-problem and per-topic mastery estimates, weakest first.
+// topic narrows the problems (not the topic summary) to one topic.
+func (h *Handler) Mastery(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httpx.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID, ok := reqctx.UserIDFromContext(r.Context())
+	if !ok {
+		httpx.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	ps, err := h.problemMasteries(r.Context(), userID, time.Now().UTC())
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load mastery")
+		return
+	}
+	topics := topicMasteries(ps)
+	if topic := mastery.NormalizeTopic(r.URL.Query().Get("topic")); topic != "" {
+		filtered := make([]ProblemMastery, 0)
+		for _, p := range ps {
+			for _, t := range p.Topics {
+				if mastery.NormalizeTopic(t) == topic {
+					filtered = append(filtered, p)
+					break
+				}
+			}
 		}
-		out = append(out, TopicStat{
-			Topic:      t,
-			Count:      a.n,
-			MasteryAvg: math.Round((a.sum/float64(a.n))*10) / 10,
-		})
+		ps = filtered
 	}
-	sort.SliceStable(out, func(i, j int) bool {
-		if out[i].MasteryAvg == out[j].MasteryAvg {
-			return out[i].Topic < out[j].Topic
+	sort.SliceStable(ps, func(i, j int) bool {
+		if ps[i].Score == ps[j].Score {
+			return ps[i].Title < ps[j].Title
 		}
-		return out[i].MasteryAvg < out[j].MasteryAvg
+		return ps[i].Score < ps[j].Score
 	})
-	httpx.WriteJSON(w, http.StatusOK, out)
+	httpx.WriteJSON(w, http.StatusOK, MasteryResponse{Problems: ps, Topics: topics})
 }
 
 type StreaksResponse struct {
-	CurrentStreakDays int `json:"current_streak_days"`
+	activity.Streaks
+	FreezeCap        int `json:"freeze_cap"`
+	FreezeEvery      int `json:"freeze_every_days"`
+	FreezeWindowDays int `json:"freeze_window_days"`
 }
 
+func streaksResponse(st activity.Streaks) StreaksResponse {
+	return StreaksResponse{
+		Streaks:          st,
+		FreezeCap:        activity.FreezeCap,
+		FreezeEvery:      activity.FreezeEvery,
+		FreezeWindowDays: activity.FreezeWindowDays,
+	}
+}
+
+// Streaks reports the current and longest streaks, recent runs and the freeze
+// balance, computed from the daily activity rollup.
 func (h *Handler) Streaks(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		httpx.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
@@ -192,74 +301,142 @@ func (h *Handler) Streaks(w http.ResponseWriter, r *http.Request) {
 		httpx.WriteError(w, http.StatusUnauthorized, "unauthorized")
 		return
 	}
-	settings, err := h.users.GetSettings(r.Context(), userID)
+	loc, err := h.location(r.Context(), userID)
 	if err != nil {
 		httpx.WriteError(w, http.StatusInternalServerError, "failed to load user settings")
 		return
 	}
-	loc, err := time.LoadLocation(settings.Timezone)
+	st, err := h.activity.Streaks(r.Context(), userID, activity.LocalDate(time.Now().UTC(), loc))
 	if err != nil {
-		loc = time.UTC
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load streaks")
+		return
 	}
-	now := time.Now().UTC()
-	httpx.WriteJSON(w, http.StatusOK, StreaksResponse{
-		CurrentStreakDays: h.currentStreakDays(r.Context(), userID, loc, now),
-	})
+	httpx.WriteJSON(w, http.StatusOK, streaksResponse(st))
 }
 
-func (h *Handler) currentStreakDays(ctx context.Context, userID string, loc *time.Location, nowUTC time.Time) int {
-	// Collect local dates with at least one review.
-	start := nowUTC.Add(-90 * 24 * time.Hour)
-	rows, err := h.pool.Query(ctx, `
-		SELECT DISTINCT (reviewed_at AT TIME ZONE $2)::date AS d
-		FROM review_logs
-		WHERE user_id = $1 AND reviewed_at >= $3
-		ORDER BY d DESC
-	`, userID, loc.String(), start)
+type freezeRequest struct {
+	Date string `json:"date"`
+}
+
+// Freeze spends a streak freeze to cover a missed local day.
+func (h *Handler) Freeze(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httpx.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID, ok := reqctx.UserIDFromContext(r.Context())
+	if !ok {
+		httpx.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	var req freezeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpx.WriteError(w, http.StatusBadRequest, "invalid json body")
+		return
+	}
+	day, err := time.Parse("2006-01-02", strings.TrimSpace(req.Date))
 	if err != nil {
-		return 0
+		httpx.WriteError(w, http.StatusBadRequest, "date must be YYYY-MM-DD")
+		return
 	}
-	defer rows.Close()
-	set := map[string]struct{}{}
-	for rows.Next() {
-		var d time.Time
-		if err := rows.Scan(&d); err != nil {
-			continue
-		}
-		set[d.Format("2006-01-02")] = struct{}{}
+	loc, err := h.location(r.Context(), userID)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load user settings")
+		return
 	}
-
-	todayLocal := nowUTC.In(loc)
-	cur := time.Date(todayLocal.Year(), todayLocal.Month(), todayLocal.Day(), 0, 0, 0, 0, time.UTC)
-	key := cur.Format("2006-01-02")
-	if _, ok := set[key]; !ok {
-		// If no reviews today, streak can still be ongoing if user reviewed yesterday.
-		cur = cur.AddDate(0, 0, -1)
+	st, err := h.activity.Freeze(r.Context(), userID, day, activity.LocalDate(time.Now().UTC(), loc))
+	switch {
+	case errors.Is(err, activity.ErrDayNotFreezable):
+		httpx.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, activity.ErrNoFreezes):
+		httpx.WriteError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to freeze day")
+		return
 	}
+	httpx.WriteJSON(w, http.StatusOK, streaksResponse(st))
+}
 
-	streak := 0
-	for i := 0; i < 365; i++ {
-		k := cur.Format("2006-01-02")
-		if _, ok := set[k]; !ok {
-			break
+type GoalDay struct {
+	Date         string `json:"date"`
+	Reviews      int    `json:"reviews"`
+	TotalTimeSec int    `json:"total_time_sec"`
+	GoalMet      bool   `json:"goal_met"`
+	Frozen       bool   `json:"frozen"`
+}
+
+type GoalsResponse struct {
+	WindowInfo
+	Goal    activity.Goal `json:"goal"`
+	Today   GoalDay       `json:"today"`
+	MetDays int           `json:"met_days"`
+	Days    []GoalDay     `json:"days"`
+}
+
+// goalDays fills every local day in [first, last] from the recorded days, oldest
+// first. Past days keep the verdict recorded when they happened; today is judged
+// against the current goal so a goal change shows up immediately.
+func goalDays(recorded []activity.Day, first time.Time, last time.Time, today time.Time, goal activity.Goal) []GoalDay {
+	byDate := make(map[string]activity.Day, len(recorded))
+	for _, d := range recorded {
+		byDate[d.Date.Format("2006-01-02")] = d
+	}
+	out := make([]GoalDay, 0)
+	for d := first; !d.After(last); d = d.AddDate(0, 0, 1) {
+		key := d.Format("2006-01-02")
+		rec := byDate[key]
+		gd := GoalDay{Date: key, Reviews: rec.Reviews, TotalTimeSec: rec.TimeSpentSec, GoalMet: rec.GoalMet, Frozen: rec.Frozen}
+		if d.Equal(today) && rec.Reviews > 0 {
+			gd.GoalMet = gd.GoalMet || goal.Met(rec.Reviews, rec.TimeSpentSec)
 		}
-		streak++
-		cur = cur.AddDate(0, 0, -1)
+		out = append(out, gd)
 	}
-	return streak
+	return out
 }
 
-func masteryScore(reps int, ease float64, overdueDays int) float64 {
-	overduePenalty := float64(overdueDays * 2)
-	if overduePenalty > 30 {
-		overduePenalty = 30
+// Goals reports the daily goal, today's progress and goal completion per local day.
+func (h *Handler) Goals(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httpx.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID, ok := reqctx.UserIDFromContext(r.Context())
+	if !ok {
+		httpx.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
 	}
-	m := 20*math.Log2(float64(reps)+1) + 25*(ease-1.3) - overduePenalty
-	if m < 0 {
-		return 0
+	settings, err := h.users.GetSettings(r.Context(), userID)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load user settings")
+		return
 	}
-	if m > 100 {
-		return 100
+	loc, err := time.LoadLocation(settings.Timezone)
+	if err != nil {
+		loc = time.UTC
 	}
-	return m
+	now := time.Now().UTC()
+	win, err := parseWindow(r.URL.Query(), loc, now, 30, 365)
+	if err != nil {
+		httpx.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	// Load through today so Today is filled even when the window ends earlier.
+	today := activity.LocalDate(now, loc)
+	recorded, err := h.activity.Range(r.Context(), userID, win.From, today)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load daily activity")
+		return
+	}
+	goal := activity.Goal{Reviews: settings.DailyGoalReviews, Minutes: settings.DailyGoalMinutes}
+	all := goalDays(recorded, win.From, today, today, goal)
+	days := all[:win.Days]
+	out := GoalsResponse{WindowInfo: win.info(), Goal: goal, Today: all[len(all)-1], Days: days}
+	for _, d := range days {
+		if d.GoalMet {
+			out.MetDays++
+		}
+	}
+	httpx.WriteJSON(w, http.StatusOK, out)
 }
@@ -0,0 +1,478 @@
+package stats
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/httpx"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/reqctx"
+)
+
+// location returns the user's configured time zone, falling back to UTC.
+func (h *Handler) location(ctx context.Context, userID string) (*time.Location, error) {
+	settings, err := h.users.GetSettings(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := time.LoadLocation(settings.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	return loc, nil
+}
+
+// Retention buckets group reviews by the gap since the previous review of the
+// same problem, which is the interval the user was actually tested on.
+var retentionBuckets = []struct {
+	Label   string
+	MaxDays float64 // inclusive upper bound; the last bucket is open-ended
+}{
+	{"1d", 1.5},
+	{"2-3d", 3.5},
+	{"4-7d", 7.5},
+	{"8-14d", 14.5},
+	{"15-30d", 30.5},
+	{"31-90d", 90.5},
+	{"90d+", 0},
+}
+
+const retentionFirstBucket = "first"
+
+func retentionBucket(gapDays *float64) string {
+	if gapDays == nil {
+		return retentionFirstBucket
+	}
+	for _, b := range retentionBuckets {
+		if b.MaxDays == 0 || *gapDays <= b.MaxDays {
+			return b.Label
+		}
+	}
+	return retentionBuckets[len(retentionBuckets)-1].Label
+}
+
+type RetentionBucket struct {
+	Bucket        string   `json:"bucket"`
+	Reviews       int      `json:"reviews"`
+	Recalled      int      `json:"recalled"`
+	RetentionRate *float64 `json:"retention_rate,omitempty"`
+}
+
+type RetentionResponse struct {
+	WindowInfo
+	Overall RetentionBucket   `json:"overall"`
+	Buckets []RetentionBucket `json:"buckets"`
+}
+
+type retentionSample struct {
+	gapDays *float64
+	grade   int
+}
+
+// buildRetention tallies samples into every bucket (in fixed order, including
+// empty ones) and overall. A review counts as recalled when graded >= 2; first
+// reviews of a problem have no interval and are excluded from the overall rate.
+func buildRetention(samples []retentionSample) (RetentionBucket, []RetentionBucket) {
+	order := []string{retentionFirstBucket}
+	for _, b := range retentionBuckets {
+		order = append(order, b.Label)
+	}
+	byLabel := make(map[string]*RetentionBucket, len(order))
+	out := make([]RetentionBucket, len(order))
+	for i, l := range order {
+		out[i].Bucket = l
+		byLabel[l] = &out[i]
+	}
+	overall := RetentionBucket{Bucket: "overall"}
+	for _, s := range samples {
+		b := byLabel[retentionBucket(s.gapDays)]
+		b.Reviews++
+		if s.grade >= 2 {
+			b.Recalled++
+		}
+		if s.gapDays != nil {
+			overall.Reviews++
+			if s.grade >= 2 {
+				overall.Recalled++
+			}
+		}
+	}
+	setRate := func(b *RetentionBucket) {
+		if b.Reviews > 0 {
+			rate := roundTo(float64(b.Recalled)/float64(b.Reviews), 3)
+			b.RetentionRate = &rate
+		}
+	}
+	for i := range out {
+		setRate(&out[i])
+	}
+	setRate(&overall)
+	return overall, out
+}
+
+func roundTo(v float64, places int) float64 {
+	p := 1.0
+	for i := 0; i < places; i++ {
+		p *= 10
+	}
+	if v < 0 {
+		return float64(int64(v*p-0.5)) / p
+	}
+	return float64(int64(v*p+0.5)) / p
+}
+
+// Retention reports the share of reviews graded >= 2 by interval bucket.
+func (h *Handler) Retention(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httpx.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID, ok := reqctx.UserIDFromContext(r.Context())
+	if !ok {
+		httpx.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	loc, err := h.location(r.Context(), userID)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load user settings")
+		return
+	}
+	win, err := parseWindow(r.URL.Query(), loc, time.Now().UTC(), 90, 365)
+	if err != nil {
+		httpx.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// The gap is measured against the previous review even when that one falls
+	// before the window, so LAG runs over the user's full history.
+	rows, err := h.pool.Query(r.Context(), `
+		SELECT grade, gap_days
+		FROM (
+			SELECT grade, reviewed_at,
+			       EXTRACT(EPOCH FROM reviewed_at - LAG(reviewed_at) OVER (
+			         PARTITION BY problem_id ORDER BY reviewed_at, created_at
+			       ))::float8 / 86400 AS gap_days
+			FROM review_logs
+			WHERE user_id = $1
+		) l
+		WHERE reviewed_at >= $2 AND reviewed_at < $3
+	`, userID, win.Start, win.End)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load retention")
+		return
+	}
+	defer rows.Close()
+	samples := make([]retentionSample, 0)
+	for rows.Next() {
+		var s retentionSample
+		if err := rows.Scan(&s.grade, &s.gapDays); err != nil {
+			httpx.WriteError(w, http.StatusInternalServerError, "failed to parse retention")
+			return
+		}
+		samples = append(samples, s)
+	}
+	if err := rows.Err(); err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load retention")
+		return
+	}
+	overall, buckets := buildRetention(samples)
+	httpx.WriteJSON(w, http.StatusOK, RetentionResponse{WindowInfo: win.info(), Overall: overall, Buckets: buckets})
+}
+
+type GradePeriod struct {
+	PeriodStart string   `json:"period_start"`
+	Counts      [5]int   `json:"counts"` // index = grade 0..4
+	Total       int      `json:"total"`
+	AvgGrade    *float64 `json:"avg_grade,omitempty"`
+}
+
+type GradesResponse struct {
+	WindowInfo
+	Bucket  string        `json:"bucket"`
+	Periods []GradePeriod `json:"periods"`
+}
+
+// periodStart returns the local calendar day that starts d's bucket: the day
+// itself, or the Monday of its ISO week.
+func periodStart(d time.Time, bucket string) time.Time {
+	if bucket != "week" {
+		return d
+	}
+	offset := (int(d.Weekday()) + 6) % 7
+	return d.AddDate(0, 0, -offset)
+}
+
+// Grades reports the grade distribution per local day or week.
+func (h *Handler) Grades(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httpx.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID, ok := reqctx.UserIDFromContext(r.Context())
+	if !ok {
+		httpx.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	bucket := r.URL.Query().Get("bucket")
+	switch bucket {
+	case "":
+		bucket = "week"
+	case "day", "week":
+	default:
+		httpx.WriteError(w, http.StatusBadRequest, "bucket must be day|week")
+		return
+	}
+	loc, err := h.location(r.Context(), userID)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load user settings")
+		return
+	}
+	win, err := parseWindow(r.URL.Query(), loc, time.Now().UTC(), 90, 365)
+	if err != nil {
+		httpx.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rows, err := h.pool.Query(r.Context(), `
+		SELECT (reviewed_at AT TIME ZONE $2)::date AS d, grade, COUNT(*)
+		FROM review_logs
+		WHERE user_id = $1 AND reviewed_at >= $3 AND reviewed_at < $4
+		GROUP BY d, grade
+	`, userID, loc.String(), win.Start, win.End)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load grade distribution")
+		return
+	}
+	defer rows.Close()
+
+	periods := make([]GradePeriod, 0)
+	index := map[string]int{}
+	for d := periodStart(win.From, bucket); !d.After(win.To); {
+		key := d.Format("2006-01-02")
+		index[key] = len(periods)
+		periods = append(periods, GradePeriod{PeriodStart: key})
+		if bucket == "week" {
+			d = d.AddDate(0, 0, 7)
+		} else {
+			d = d.AddDate(0, 0, 1)
+		}
+	}
+	sums := make([]int, len(periods))
+	for rows.Next() {
+		var d time.Time
+		var grade, n int
+		if err := rows.Scan(&d, &grade, &n); err != nil {
+			httpx.WriteError(w, http.StatusInternalServerError, "failed to parse grade distribution")
+			return
+		}
+		i, ok := index[periodStart(d, bucket).Format("2006-01-02")]
+		if !ok || grade < 0 || grade > 4 {
+			continue
+		}
+		periods[i].Counts[grade] += n
+		periods[i].Total += n
+		sums[i] += grade * n
+	}
+	if err := rows.Err(); err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load grade distribution")
+		return
+	}
+	for i := range periods {
+		if periods[i].Total > 0 {
+			avg := roundTo(float64(sums[i])/float64(periods[i].Total), 2)
+			periods[i].AvgGrade = &avg
+		}
+	}
+	httpx.WriteJSON(w, http.StatusOK, GradesResponse{WindowInfo: win.info(), Bucket: bucket, Periods: periods})
+}
+
+// civilDay drops the time and zone from a local midnight so it compares with
+// dates scanned from Postgres (which come back as UTC midnights).
+func civilDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+type TimeSpentGroup struct {
+	Key          string  `json:"key"`
+	Reviews      int     `json:"reviews"`
+	MedianSec    float64 `json:"median_sec"`
+	TotalTimeSec int     `json:"total_time_sec"`
+}
+
+type TimeSpentResponse struct {
+	WindowInfo
+	ByDifficulty []TimeSpentGroup `json:"by_difficulty"`
+	ByTopic      []TimeSpentGroup `json:"by_topic"`
+}
+
+// TimeSpent reports the median time_spent_sec of reviews by difficulty and by topic.
+// Reviews without a recorded time are ignored.
+func (h *Handler) TimeSpent(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httpx.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID, ok := reqctx.UserIDFromContext(r.Context())
+	if !ok {
+		httpx.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	loc, err := h.location(r.Context(), userID)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load user settings")
+		return
+	}
+	win, err := parseWindow(r.URL.Query(), loc, time.Now().UTC(), 90, 365)
+	if err != nil {
+		httpx.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	load := func(keyExpr string, from string) ([]TimeSpentGroup, error) {
+		rows, err := h.pool.Query(r.Context(), `
+			SELECT `+keyExpr+` AS k,
+			       COUNT(*),
+			       percentile_cont(0.5) WITHIN GROUP (ORDER BY rl.time_spent_sec)::float8,
+			       SUM(rl.time_spent_sec)
+			FROM review_logs rl
+			JOIN problems p ON p.id = rl.problem_id
+			`+from+`
+			WHERE rl.user_id = $1 AND rl.reviewed_at >= $2 AND rl.reviewed_at < $3 AND rl.time_spent_sec IS NOT NULL
+			GROUP BY k
+			ORDER BY k
+		`, userID, win.Start, win.End)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		out := make([]TimeSpentGroup, 0)
+		for rows.Next() {
+			var g TimeSpentGroup
+			if err := rows.Scan(&g.Key, &g.Reviews, &g.MedianSec, &g.TotalTimeSec); err != nil {
+				return nil, err
+			}
+			if g.Key == "" {
+				continue
+			}
+			out = append(out, g)
+		}
+		return out, rows.Err()
+	}
+
+	byDifficulty, err := load(`lower(p.difficulty)`, ``)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load time spent by difficulty")
+		return
+	}
+	byTopic, err := load(`lower(trim(t.topic))`, `CROSS JOIN LATERAL unnest(p.topics) AS t(topic)`)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load time spent by topic")
+		return
+	}
+	httpx.WriteJSON(w, http.StatusOK, TimeSpentResponse{WindowInfo: win.info(), ByDifficulty: byDifficulty, ByTopic: byTopic})
+}
+
+type HeatmapDay struct {
+	Date         string `json:"date"`
+	Reviews      int    `json:"reviews"`
+	TotalTimeSec int    `json:"total_time_sec"`
+	Level        int    `json:"level"`
+}
+
+type HeatmapResponse struct {
+	WindowInfo
+	Days         []HeatmapDay `json:"days"`
+	TotalReviews int          `json:"total_reviews"`
+	ActiveDays   int          `json:"active_days"`
+	MaxReviews   int          `json:"max_reviews"`
+}
+
+const (
+	heatmapDays    = 365
+	heatmapMaxDays = 366
+)
+
+// heatmapLevels assigns each day a 0..4 intensity: 0 for no reviews, otherwise the
+// quartile of its count among the active days, so one huge day does not flatten
+// the rest of the year.
+func heatmapLevels(days []HeatmapDay) {
+	counts := make([]int, 0, len(days))
+	for _, d := range days {
+		if d.Reviews > 0 {
+			counts = append(counts, d.Reviews)
+		}
+	}
+	if len(counts) == 0 {
+		return
+	}
+	sort.Ints(counts)
+	q := func(p float64) int { return counts[int(p*float64(len(counts)-1))] }
+	q1, q2, q3 := q(0.25), q(0.5), q(0.75)
+	for i := range days {
+		n := days[i].Reviews
+		switch {
+		case n == 0:
+			days[i].Level = 0
+		case n <= q1:
+			days[i].Level = 1
+		case n <= q2:
+			days[i].Level = 2
+		case n <= q3:
+			days[i].Level = 3
+		default:
+			days[i].Level = 4
+		}
+	}
+}
+
+// Heatmap returns review volume for each local day in the window (by default the
+// last 365), oldest first.
+func (h *Handler) Heatmap(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httpx.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID, ok := reqctx.UserIDFromContext(r.Context())
+	if !ok {
+		httpx.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	loc, err := h.location(r.Context(), userID)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load user settings")
+		return
+	}
+	win, err := parseWindow(r.URL.Query(), loc, time.Now().UTC(), heatmapDays, heatmapMaxDays)
+	if err != nil {
+		httpx.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rollup, err := h.activity.Range(r.Context(), userID, win.From, win.To)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load heatmap")
+		return
+	}
+	byDay := make(map[string]HeatmapDay, len(rollup))
+	for _, d := range rollup {
+		byDay[d.Date.Format("2006-01-02")] = HeatmapDay{Reviews: d.Reviews, TotalTimeSec: d.TimeSpentSec}
+	}
+
+	out := HeatmapResponse{WindowInfo: win.info(), Days: make([]HeatmapDay, 0, win.Days)}
+	for i := 0; i < win.Days; i++ {
+		key := win.From.AddDate(0, 0, i).Format("2006-01-02")
+		hd := byDay[key]
+		hd.Date = key
+		out.Days = append(out.Days, hd)
+		out.TotalReviews += hd.Reviews
+		if hd.Reviews > 0 {
+			out.ActiveDays++
+		}
+		if hd.Reviews > out.MaxReviews {
+			out.MaxReviews = hd.Reviews
+		}
+	}
+	heatmapLevels(out.Days)
+	httpx.WriteJSON(w, http.StatusOK, out)
+}
@@ -0,0 +1,94 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/activity"
+)
+
+func TestRetentionBuckets(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	cases := []struct {
+		gap  *float64
+		want string
+	}{
+		{nil, "first"},
+		{f(0.2), "1d"},
+		{f(1), "1d"},
+		{f(3), "2-3d"},
+		{f(7), "4-7d"},
+		{f(10), "8-14d"},
+		{f(30), "15-30d"},
+		{f(60), "31-90d"},
+		{f(400), "90d+"},
+	}
+	for _, c := range cases {
+		if got := retentionBucket(c.gap); got != c.want {
+			t.Fatalf("retentionBucket(%v) = %q, want %q", c.gap, got, c.want)
+		}
+	}
+
+	overall, buckets := buildRetention([]retentionSample{
+		{gapDays: nil, grade: 0},
+		{gapDays: f(1), grade: 3},
+		{gapDays: f(1), grade: 1},
+		{gapDays: f(5), grade: 2},
+	})
+	if len(buckets) != len(retentionBuckets)+1 || buckets[0].Bucket != "first" {
+		t.Fatalf("expected every bucket in order, got %+v", buckets)
+	}
+	if overall.Reviews != 3 || overall.Recalled != 2 || overall.RetentionRate == nil || *overall.RetentionRate != 0.667 {
+		t.Fatalf("unexpected overall: %+v", overall)
+	}
+	if b := buckets[1]; b.Reviews != 2 || *b.RetentionRate != 0.5 {
+		t.Fatalf("unexpected 1d bucket: %+v", b)
+	}
+	if b := buckets[4]; b.Reviews != 0 || b.RetentionRate != nil {
+		t.Fatalf("expected empty bucket without rate: %+v", b)
+	}
+}
+
+func TestPeriodStartWeekIsMonday(t *testing.T) {
+	sun := time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC)
+	if got := periodStart(sun, "week").Format("2006-01-02"); got != "2026-03-02" {
+		t.Fatalf("expected Monday 2026-03-02, got %s", got)
+	}
+	if got := periodStart(sun, "day"); !got.Equal(sun) {
+		t.Fatalf("expected day bucket unchanged, got %s", got)
+	}
+}
+
+func TestHeatmapLevels(t *testing.T) {
+	days := []HeatmapDay{{Reviews: 0}, {Reviews: 1}, {Reviews: 2}, {Reviews: 3}, {Reviews: 40}}
+	heatmapLevels(days)
+	want := []int{0, 1, 2, 3, 4}
+	for i, d := range days {
+		if d.Level != want[i] {
+			t.Fatalf("day %d: level %d, want %d", i, d.Level, want[i])
+		}
+	}
+}
+
+func TestGoalDaysFillsWindowAndJudgesToday(t *testing.T) {
+	first := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+	today := first.AddDate(0, 0, 2)
+	recorded := []activity.Day{
+		{Date: first, Reviews: 1, GoalMet: true},
+		{Date: today, Reviews: 4, TimeSpentSec: 1200},
+	}
+	goal := activity.Goal{Reviews: 3, Minutes: 20}
+	days := goalDays(recorded, first, today, today, goal)
+	if len(days) != 3 || days[1].Date != "2026-04-02" || days[1].Reviews != 0 {
+		t.Fatalf("expected 3 filled days, got %+v", days)
+	}
+	if !days[0].GoalMet {
+		t.Fatalf("expected the recorded verdict to stand for past days")
+	}
+	if !days[2].GoalMet {
+		t.Fatalf("expected today to be judged against the current goal")
+	}
+	if past := goalDays(recorded, first, today, today.AddDate(0, 0, 1), goal); past[2].GoalMet {
+		t.Fatalf("expected a past window end to keep its recorded verdict")
+	}
+}
@@ -0,0 +1,487 @@
+package stats
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/activity"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/httpx"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/mastery"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/problems"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/reqctx"
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/scheduler"
+)
+
+// reportPeriods maps a report period to the number of local days it covers,
+// ending today. A report for explicit from/to dates has period "custom".
+var reportPeriods = map[string]int{"week": 7, "month": 30}
+
+const (
+	reportCustomPeriod = "custom"
+	// reportMaxDays bounds a custom report window.
+	reportMaxDays = 92
+	// reportTopicDelta is the mastery change (in points) a topic needs to count
+	// as improved or regressed.
+	reportTopicDelta = 5.0
+	// reportListLen bounds each list in the report.
+	reportListLen = 5
+	// reportNewProblemsLen bounds the new problems listed (the count is exact).
+	reportNewProblemsLen = 10
+)
+
+type ReportReviews struct {
+	Done             int `json:"reviews_done"`
+	ProblemsReviewed int `json:"problems_reviewed"`
+	// StillDue, Due and CompletionRate compare against today's schedule, so
+	// they are only set when the window includes today; a past window leaves
+	// them out. Due is the problems reviewed in the period plus those still due
+	// at its end.
+	StillDue       *int     `json:"still_due,omitempty"`
+	Due            *int     `json:"due,omitempty"`
+	CompletionRate *float64 `json:"completion_rate,omitempty"`
+	TotalTimeSec   int      `json:"total_time_sec"`
+}
+
+type ReportProblem struct {
+	ProblemID  string    `json:"problem_id"`
+	Title      string    `json:"title"`
+	Difficulty string    `json:"difficulty"`
+	AddedAt    time.Time `json:"added_at"`
+}
+
+type ReportNewProblems struct {
+	Count    int             `json:"count"`
+	Problems []ReportProblem `json:"problems"`
+}
+
+// TopicChange compares a topic's mastery at the start and end of the period,
+// over the problems that were already in the library at the start.
+type TopicChange struct {
+	Topic    string  `json:"topic"`
+	Problems int     `json:"count"`
+	Start    float64 `json:"start_mastery"`
+	End      float64 `json:"end_mastery"`
+	Delta    float64 `json:"delta"`
+}
+
+type ReportTopics struct {
+	Improved  []TopicChange `json:"improved"`
+	Regressed []TopicChange `json:"regressed"`
+}
+
+type ReportContests struct {
+	ContestStatsTotals
+	RatingStart *float64 `json:"rating_start,omitempty"`
+	RatingEnd   *float64 `json:"rating_end,omitempty"`
+}
+
+type ReportStreak struct {
+	CurrentDays int `json:"current_streak_days"`
+	LongestDays int `json:"longest_streak_days"`
+	GoalDays    int `json:"goal_days"`
+	FrozenDays  int `json:"frozen_days"`
+	Days        int `json:"days"`
+}
+
+// HardProblem is a problem failed (graded 0 or 1) during the period.
+type HardProblem struct {
+	ProblemID  string `json:"problem_id"`
+	Title      string `json:"title"`
+	Difficulty string `json:"difficulty"`
+	Failures   int    `json:"failures"`
+	Reviews    int    `json:"reviews"`
+}
+
+type Report struct {
+	Period      string            `json:"period"`
+	From        string            `json:"from"`
+	To          string            `json:"to"`
+	Timezone    string            `json:"timezone"`
+	Reviews     ReportReviews     `json:"reviews"`
+	NewProblems ReportNewProblems `json:"new_problems"`
+	Topics      ReportTopics      `json:"topics"`
+	Contests    ReportContests    `json:"contests"`
+	Streak      ReportStreak      `json:"streak"`
+	Hardest     []HardProblem     `json:"hardest_problems"`
+}
+
+// gradedReview is one review of a problem, for replaying its state.
+type gradedReview struct {
+	at    time.Time
+	grade int
+}
+
+// replayState rebuilds a problem's SM-2 state and recent grades (newest first)
+// from its last mastery.HistoryLen reviews before until; reviews are oldest
+// first. Only the fields mastery reads are meaningful; the due date is not.
+func replayState(reviews []gradedReview, until time.Time) (problems.UserState, []int) {
+	st := scheduler.State{Reps: 0, IntervalDays: 1, Ease: 2.5}
+	out := problems.UserState{IntervalDays: 1, Ease: 2.5, DueAt: until}
+	n := 0
+	for n < len(reviews) && reviews[n].at.Before(until) {
+		n++
+	}
+	reviews = reviews[max(0, n-mastery.HistoryLen):n]
+	grades := make([]int, 0, len(reviews))
+	for _, rv := range reviews {
+		st = scheduler.Update(st, rv.grade, rv.at, time.UTC, 1, 0, 0).State
+		at, g := rv.at, rv.grade
+		out.LastReviewAt, out.LastGrade = &at, &g
+		grades = append([]int{rv.grade}, grades...)
+	}
+	out.Reps, out.IntervalDays, out.Ease = st.Reps, st.IntervalDays, st.Ease
+	return out, grades
+}
+
+// topicChanges pairs topic estimates from the start and end of a period and
+// keeps those that moved by at least reportTopicDelta, biggest moves first.
+func topicChanges(start []mastery.TopicEstimate, end []mastery.TopicEstimate) ReportTopics {
+	before := make(map[string]mastery.TopicEstimate, len(start))
+	for _, t := range start {
+		before[t.Topic] = t
+	}
+	out := ReportTopics{Improved: make([]TopicChange, 0), Regressed: make([]TopicChange, 0)}
+	for _, t := range end {
+		b, ok := before[t.Topic]
+		if !ok {
+			continue
+		}
+		tc := TopicChange{Topic: t.Topic, Problems: t.Problems, Start: b.Score, End: t.Score, Delta: roundTo(t.Score-b.Score, 1)}
+		switch {
+		case tc.Delta >= reportTopicDelta:
+			out.Improved = append(out.Improved, tc)
+		case tc.Delta <= -reportTopicDelta:
+			out.Regressed = append(out.Regressed, tc)
+		}
+	}
+	sort.SliceStable(out.Improved, func(i, j int) bool {
+		if out.Improved[i].Delta == out.Improved[j].Delta {
+			return out.Improved[i].Topic < out.Improved[j].Topic
+		}
+		return out.Improved[i].Delta > out.Improved[j].Delta
+	})
+	sort.SliceStable(out.Regressed, func(i, j int) bool {
+		if out.Regressed[i].Delta == out.Regressed[j].Delta {
+			return out.Regressed[i].Topic < out.Regressed[j].Topic
+		}
+		return out.Regressed[i].Delta < out.Regressed[j].Delta
+	})
+	if len(out.Improved) > reportListLen {
+		out.Improved = out.Improved[:reportListLen]
+	}
+	if len(out.Regressed) > reportListLen {
+		out.Regressed = out.Regressed[:reportListLen]
+	}
+	return out
+}
+
+// Report summarizes the last week or month (period=week|month) or the days
+// from/to, as JSON or rendered with format=markdown|html.
+func (h *Handler) Report(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httpx.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID, ok := reqctx.UserIDFromContext(r.Context())
+	if !ok {
+		httpx.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	q := r.URL.Query()
+	custom := q.Get("from") != "" || q.Get("to") != ""
+	period := strings.TrimSpace(q.Get("period"))
+	if custom && period != "" {
+		httpx.WriteError(w, http.StatusBadRequest, "use period or from/to, not both")
+		return
+	}
+	if period == "" {
+		period = "week"
+	}
+	days, ok := reportPeriods[period]
+	if !ok && !custom {
+		httpx.WriteError(w, http.StatusBadRequest, "period must be week or month")
+		return
+	}
+	format := strings.TrimSpace(r.URL.Query().Get("format"))
+	switch format {
+	case "", "json", "markdown", "html":
+	default:
+		httpx.WriteError(w, http.StatusBadRequest, "format must be json, markdown or html")
+		return
+	}
+
+	settings, err := h.users.GetSettings(r.Context(), userID)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load user settings")
+		return
+	}
+	loc, err := time.LoadLocation(settings.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	now := time.Now().UTC()
+	today := activity.LocalDate(now, loc)
+	win := newWindow(today.AddDate(0, 0, -(days-1)), today, loc)
+	if custom {
+		period = reportCustomPeriod
+		if win, err = parseWindow(q, loc, now, reportPeriods["week"], reportMaxDays); err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	// The report covers [start, end); end is now while the window includes today.
+	start, end := win.Start, win.End
+	current := end.After(now)
+	if current {
+		end = now
+	}
+
+	rep := Report{
+		Period:   period,
+		From:     win.From.Format("2006-01-02"),
+		To:       win.To.Format("2006-01-02"),
+		Timezone: loc.String(),
+		Streak:   ReportStreak{Days: win.Days},
+	}
+
+	// Reviews, contests and goal days come from the daily rollup.
+	recorded, err := h.activity.Range(r.Context(), userID, win.From, win.To)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load daily activity")
+		return
+	}
+	graded, gradeSum := 0, 0
+	for _, d := range recorded {
+		rep.Reviews.Done += d.Reviews
+		rep.Reviews.TotalTimeSec += d.TimeSpentSec
+		rep.Contests.ContestsFinished += d.ContestsFinished
+		rep.Contests.ProblemsRecorded += d.ContestProblems
+		rep.Contests.SolvedCount += d.ContestSolved
+		rep.Contests.TotalTimeSec += d.ContestTimeSec
+		graded += d.ContestGraded
+		gradeSum += d.ContestGradeSum
+	}
+	rep.Contests.AvgGrade = avgOf(gradeSum, graded)
+	goal := activity.Goal{Reviews: settings.DailyGoalReviews, Minutes: settings.DailyGoalMinutes}
+	for _, d := range goalDays(recorded, win.From, win.To, today, goal) {
+		if d.GoalMet {
+			rep.Streak.GoalDays++
+		} else if d.Frozen {
+			rep.Streak.FrozenDays++
+		}
+	}
+	streaks, err := h.activity.Streaks(r.Context(), userID, today)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load streaks")
+		return
+	}
+	rep.Streak.CurrentDays, rep.Streak.LongestDays = streaks.CurrentDays, streaks.LongestDays
+
+	err = h.pool.QueryRow(r.Context(), `
+		SELECT COUNT(DISTINCT problem_id) FROM review_logs WHERE user_id = $1 AND reviewed_at >= $2 AND reviewed_at < $3
+	`, userID, start, end).Scan(&rep.Reviews.ProblemsReviewed)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load reviews")
+		return
+	}
+	// user_problem_state only knows today's due dates, so what was due in a past
+	// window cannot be told from it.
+	if current {
+		var stillDue int
+		err = h.pool.QueryRow(r.Context(), `
+			SELECT COUNT(*) FROM user_problem_state WHERE user_id = $1 AND is_active = true AND due_at <= $2
+		`, userID, end).Scan(&stillDue)
+		if err != nil {
+			httpx.WriteError(w, http.StatusInternalServerError, "failed to load due reviews")
+			return
+		}
+		due := rep.Reviews.ProblemsReviewed + stillDue
+		rep.Reviews.StillDue, rep.Reviews.Due = &stillDue, &due
+		if due > 0 {
+			v := roundTo(float64(rep.Reviews.ProblemsReviewed)/float64(due), 3)
+			rep.Reviews.CompletionRate = &v
+		}
+	}
+
+	if rep.NewProblems, err = h.reportNewProblems(r.Context(), userID, start, end); err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load new problems")
+		return
+	}
+	if rep.Topics, err = h.reportTopics(r.Context(), userID, start, end); err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load topic mastery")
+		return
+	}
+
+	rows, err := h.pool.Query(r.Context(), `
+		SELECT rating_before, rating_after
+		FROM contest_rating_history
+		WHERE user_id = $1 AND scope = 'overall' AND recorded_at >= $2 AND recorded_at < $3
+		ORDER BY recorded_at ASC, id ASC
+	`, userID, start, end)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load contest rating history")
+		return
+	}
+	for rows.Next() {
+		var before, after float64
+		if err := rows.Scan(&before, &after); err != nil {
+			rows.Close()
+			httpx.WriteError(w, http.StatusInternalServerError, "failed to parse contest rating history")
+			return
+		}
+		if rep.Contests.RatingStart == nil {
+			rep.Contests.RatingStart = &before
+		}
+		rep.Contests.RatingEnd = &after
+	}
+	rows.Close()
+
+	rep.Hardest = make([]HardProblem, 0, reportListLen)
+	rows, err = h.pool.Query(r.Context(), `
+		SELECT p.id::text, p.title, p.difficulty,
+		       COUNT(*) FILTER (WHERE rl.grade <= 1) AS failures,
+		       COUNT(*) AS reviews
+		FROM review_logs rl
+		JOIN problems p ON p.id = rl.problem_id
+		WHERE rl.user_id = $1 AND rl.reviewed_at >= $2 AND rl.reviewed_at < $3
+		GROUP BY p.id, p.title, p.difficulty
+		HAVING COUNT(*) FILTER (WHERE rl.grade <= 1) > 0
+		ORDER BY failures DESC, reviews DESC, p.title ASC
+		LIMIT $4
+	`, userID, start, end, reportListLen)
+	if err != nil {
+		httpx.WriteError(w, http.StatusInternalServerError, "failed to load hardest problems")
+		return
+	}
+	for rows.Next() {
+		var hp HardProblem
+		if err := rows.Scan(&hp.ProblemID, &hp.Title, &hp.Difficulty, &hp.Failures, &hp.Reviews); err != nil {
+			rows.Close()
+			httpx.WriteError(w, http.StatusInternalServerError, "failed to parse hardest problems")
+			return
+		}
+		rep.Hardest = append(rep.Hardest, hp)
+	}
+	rows.Close()
+
+	switch format {
+	case "markdown":
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(renderReportMarkdown(rep)))
+	case "html":
+		out, err := renderReportHTML(rep)
+		if err != nil {
+			httpx.WriteError(w, http.StatusInternalServerError, "failed to render report")
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(out))
+	default:
+		httpx.WriteJSON(w, http.StatusOK, rep)
+	}
+}
+
+func (h *Handler) reportNewProblems(ctx context.Context, userID string, start time.Time, end time.Time) (ReportNewProblems, error) {
+	out := ReportNewProblems{Problems: make([]ReportProblem, 0)}
+	rows, err := h.pool.Query(ctx, `
+		SELECT p.id::text, p.title, p.difficulty, s.added_at
+		FROM user_problem_state s
+		JOIN problems p ON p.id = s.problem_id
+		WHERE s.user_id = $1 AND s.added_at >= $2 AND s.added_at < $3
+		ORDER BY s.added_at DESC
+	`, userID, start, end)
+	if err != nil {
+		return out, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p ReportProblem
+		if err := rows.Scan(&p.ProblemID, &p.Title, &p.Difficulty, &p.AddedAt); err != nil {
+			return out, err
+		}
+		out.Count++
+		if len(out.Problems) < reportNewProblemsLen {
+			out.Problems = append(out.Problems, p)
+		}
+	}
+	return out, rows.Err()
+}
+
+// reportTopics compares topic mastery at start and end over the active problems
+// that were in the library at start, replaying each problem's last reviews
+// before either end so both are estimated the same way. Only those reviews are
+// loaded, so the report does not grow with the user's history.
+func (h *Handler) reportTopics(ctx context.Context, userID string, start time.Time, end time.Time) (ReportTopics, error) {
+	rows, err := h.pool.Query(ctx, `
+		SELECT p.id::text, p.topics
+		FROM user_problem_state s
+		JOIN problems p ON p.id = s.problem_id
+		WHERE s.user_id = $1 AND s.is_active = true AND s.added_at < $2
+	`, userID, start)
+	if err != nil {
+		return ReportTopics{}, err
+	}
+	ps := make([]ProblemMastery, 0)
+	for rows.Next() {
+		var pm ProblemMastery
+		if err := rows.Scan(&pm.ProblemID, &pm.Topics); err != nil {
+			rows.Close()
+			return ReportTopics{}, err
+		}
+		ps = append(ps, pm)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return ReportTopics{}, err
+	}
+
+	// from_end ranks a review among the problem's reviews before end; less
+	// in_period, it ranks it among those before start.
+	rows, err = h.pool.Query(ctx, `
+		SELECT problem_id::text, reviewed_at, grade
+		FROM (
+			SELECT rl.problem_id, rl.reviewed_at, rl.created_at, rl.grade,
+			       ROW_NUMBER() OVER w AS from_end,
+			       COUNT(*) FILTER (WHERE rl.reviewed_at >= $2) OVER (PARTITION BY rl.problem_id) AS in_period
+			FROM review_logs rl
+			JOIN user_problem_state s ON s.user_id = rl.user_id AND s.problem_id = rl.problem_id
+			WHERE rl.user_id = $1 AND rl.reviewed_at < $3
+			  AND s.is_active = true AND s.added_at < $2
+			WINDOW w AS (PARTITION BY rl.problem_id ORDER BY rl.reviewed_at DESC, rl.created_at DESC)
+		) h
+		WHERE from_end <= $4 OR (reviewed_at < $2 AND from_end <= in_period + $4)
+		ORDER BY problem_id, reviewed_at ASC, created_at ASC
+	`, userID, start, end, mastery.HistoryLen)
+	if err != nil {
+		return ReportTopics{}, err
+	}
+	history := map[string][]gradedReview{}
+	for rows.Next() {
+		var id string
+		var rv gradedReview
+		if err := rows.Scan(&id, &rv.at, &rv.grade); err != nil {
+			rows.Close()
+			return ReportTopics{}, err
+		}
+		history[id] = append(history[id], rv)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return ReportTopics{}, err
+	}
+
+	startPs := make([]ProblemMastery, len(ps))
+	for i, pm := range ps {
+		st, grades := replayState(history[pm.ProblemID], start)
+		startPs[i] = pm
+		startPs[i].Estimate = mastery.Problem(st, grades, start)
+		st, grades = replayState(history[pm.ProblemID], end)
+		ps[i].Estimate = mastery.Problem(st, grades, end)
+	}
+	return topicChanges(topicMasteries(startPs), topicMasteries(ps)), nil
+}
@@ -0,0 +1,222 @@
+package stats
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strings"
+)
+
+func reportTitle(rep Report) string {
+	name := "Weekly progress report"
+	switch rep.Period {
+	case "month":
+		name = "Monthly progress report"
+	case reportCustomPeriod:
+		name = "Progress report"
+	}
+	return fmt.Sprintf("%s: %s to %s", name, rep.From, rep.To)
+}
+
+// formatDuration renders seconds as "1h 20m" or "45m".
+func formatDuration(sec int) string {
+	m := sec / 60
+	if m < 60 {
+		return fmt.Sprintf("%dm", m)
+	}
+	return fmt.Sprintf("%dh %dm", m/60, m%60)
+}
+
+func formatSigned(v float64) string {
+	return fmt.Sprintf("%+.1f", v)
+}
+
+func formatPercent(v *float64) string {
+	if v == nil {
+		return "n/a"
+	}
+	return fmt.Sprintf("%.0f%%", *v*100)
+}
+
+func formatRating(rc ReportContests) string {
+	if rc.RatingStart == nil || rc.RatingEnd == nil {
+		return ""
+	}
+	return fmt.Sprintf("%.0f → %.0f (%+.0f)", *rc.RatingStart, *rc.RatingEnd, *rc.RatingEnd-*rc.RatingStart)
+}
+
+// mdCell escapes text for a Markdown table cell.
+func mdCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// renderReportMarkdown renders the report as GitHub-flavored Markdown.
+func renderReportMarkdown(rep Report) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n_Time zone: %s_\n\n", reportTitle(rep), rep.Timezone)
+
+	b.WriteString("## Reviews\n\n")
+	fmt.Fprintf(&b, "- Reviews done: %d (%s)\n", rep.Reviews.Done, formatDuration(rep.Reviews.TotalTimeSec))
+	if rep.Reviews.Due != nil {
+		fmt.Fprintf(&b, "- Problems reviewed: %d of %d due (%s)\n", rep.Reviews.ProblemsReviewed, *rep.Reviews.Due, formatPercent(rep.Reviews.CompletionRate))
+		fmt.Fprintf(&b, "- Still due: %d\n\n", *rep.Reviews.StillDue)
+	} else {
+		fmt.Fprintf(&b, "- Problems reviewed: %d\n\n", rep.Reviews.ProblemsReviewed)
+	}
+
+	b.WriteString("## New problems\n\n")
+	if rep.NewProblems.Count == 0 {
+		b.WriteString("No problems added.\n\n")
+	} else {
+		fmt.Fprintf(&b, "%d added.\n\n", rep.NewProblems.Count)
+		for _, p := range rep.NewProblems.Problems {
+			fmt.Fprintf(&b, "- %s (%s)\n", mdCell(p.Title), p.Difficulty)
+		}
+		if more := rep.NewProblems.Count - len(rep.NewProblems.Problems); more > 0 {
+			fmt.Fprintf(&b, "- and %d more\n", more)
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("## Topics\n\n")
+	if len(rep.Topics.Improved) == 0 && len(rep.Topics.Regressed) == 0 {
+		fmt.Fprintf(&b, "No topic moved by %.0f points or more.\n\n", reportTopicDelta)
+	}
+	for _, group := range []struct {
+		name   string
+		topics []TopicChange
+	}{{"Improved", rep.Topics.Improved}, {"Regressed", rep.Topics.Regressed}} {
+		if len(group.topics) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "**%s**\n\n| Topic | Problems | Start | End | Change |\n| --- | --- | --- | --- | --- |\n", group.name)
+		for _, t := range group.topics {
+			fmt.Fprintf(&b, "| %s | %d | %.1f | %.1f | %s |\n", mdCell(t.Topic), t.Problems, t.Start, t.End, formatSigned(t.Delta))
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("## Contests\n\n")
+	c := rep.Contests
+	if c.ContestsFinished == 0 && c.ProblemsRecorded == 0 {
+		b.WriteString("No contests.\n\n")
+	} else {
+		fmt.Fprintf(&b, "- Contests finished: %d\n", c.ContestsFinished)
+		fmt.Fprintf(&b, "- Problems: %d recorded, %d solved (%s)\n", c.ProblemsRecorded, c.SolvedCount, formatDuration(c.TotalTimeSec))
+		if c.AvgGrade != nil {
+			fmt.Fprintf(&b, "- Average grade: %.1f\n", *c.AvgGrade)
+		}
+		if s := formatRating(c); s != "" {
+			fmt.Fprintf(&b, "- Rating: %s\n", s)
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("## Streak\n\n")
+	fmt.Fprintf(&b, "- Current streak: %d days (longest %d)\n", rep.Streak.CurrentDays, rep.Streak.LongestDays)
+	fmt.Fprintf(&b, "- Goal met on %d of %d days", rep.Streak.GoalDays, rep.Streak.Days)
+	if rep.Streak.FrozenDays > 0 {
+		fmt.Fprintf(&b, ", %d frozen", rep.Streak.FrozenDays)
+	}
+	b.WriteString("\n\n")
+
+	b.WriteString("## Hardest problems\n\n")
+	if len(rep.Hardest) == 0 {
+		b.WriteString("No failed reviews.\n")
+	} else {
+		b.WriteString("| Problem | Difficulty | Failures | Reviews |\n| --- | --- | --- | --- |\n")
+		for _, p := range rep.Hardest {
+			fmt.Fprintf(&b, "| %s | %s | %d | %d |\n", mdCell(p.Title), p.Difficulty, p.Failures, p.Reviews)
+		}
+	}
+	return b.String()
+}
+
+var reportHTML = template.Must(template.New("report").Funcs(template.FuncMap{
+	"duration": formatDuration,
+	"signed":   formatSigned,
+	"percent":  formatPercent,
+	"rating":   formatRating,
+	"grade":    func(v *float64) string { return fmt.Sprintf("%.1f", *v) },
+}).Parse(`<!doctype html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p><em>Time zone: {{.Timezone}}</em></p>
+
+<h2>Reviews</h2>
+<ul>
+<li>Reviews done: {{.Reviews.Done}} ({{duration .Reviews.TotalTimeSec}})</li>
+{{if .Reviews.Due}}<li>Problems reviewed: {{.Reviews.ProblemsReviewed}} of {{.Reviews.Due}} due ({{percent .Reviews.CompletionRate}})</li>
+<li>Still due: {{.Reviews.StillDue}}</li>{{else}}<li>Problems reviewed: {{.Reviews.ProblemsReviewed}}</li>{{end}}
+</ul>
+
+<h2>New problems</h2>
+{{if eq .NewProblems.Count 0}}<p>No problems added.</p>{{else}}<p>{{.NewProblems.Count}} added.</p>
+<ul>
+{{range .NewProblems.Problems}}<li>{{.Title}} ({{.Difficulty}})</li>
+{{end}}{{if gt .MoreNewProblems 0}}<li>and {{.MoreNewProblems}} more</li>
+{{end}}</ul>{{end}}
+
+<h2>Topics</h2>
+{{if and (not .Topics.Improved) (not .Topics.Regressed)}}<p>No topic moved by {{.TopicDelta}} points or more.</p>{{end}}
+{{range .TopicGroups}}{{if .Topics}}<h3>{{.Name}}</h3>
+<table>
+<tr><th>Topic</th><th>Problems</th><th>Start</th><th>End</th><th>Change</th></tr>
+{{range .Topics}}<tr><td>{{.Topic}}</td><td>{{.Problems}}</td><td>{{printf "%.1f" .Start}}</td><td>{{printf "%.1f" .End}}</td><td>{{signed .Delta}}</td></tr>
+{{end}}</table>
+{{end}}{{end}}
+<h2>Contests</h2>
+{{with .Contests}}{{if and (eq .ContestsFinished 0) (eq .ProblemsRecorded 0)}}<p>No contests.</p>{{else}}<ul>
+<li>Contests finished: {{.ContestsFinished}}</li>
+<li>Problems: {{.ProblemsRecorded}} recorded, {{.SolvedCount}} solved ({{duration .TotalTimeSec}})</li>
+{{if .AvgGrade}}<li>Average grade: {{grade .AvgGrade}}</li>
+{{end}}{{with rating .}}<li>Rating: {{.}}</li>
+{{end}}</ul>{{end}}{{end}}
+
+<h2>Streak</h2>
+<ul>
+<li>Current streak: {{.Streak.CurrentDays}} days (longest {{.Streak.LongestDays}})</li>
+<li>Goal met on {{.Streak.GoalDays}} of {{.Streak.Days}} days{{if .Streak.FrozenDays}}, {{.Streak.FrozenDays}} frozen{{end}}</li>
+</ul>
+
+<h2>Hardest problems</h2>
+{{if not .Hardest}}<p>No failed reviews.</p>{{else}}<table>
+<tr><th>Problem</th><th>Difficulty</th><th>Failures</th><th>Reviews</th></tr>
+{{range .Hardest}}<tr><td>{{.Title}}</td><td>{{.Difficulty}}</td><td>{{.Failures}}</td><td>{{.Reviews}}</td></tr>
+{{end}}</table>{{end}}
+</body>
+</html>
+`))
+
+// renderReportHTML renders the report as a standalone HTML page. Titles and
+// topics are escaped by html/template.
+func renderReportHTML(rep Report) (string, error) {
+	type topicGroup struct {
+		Name   string
+		Topics []TopicChange
+	}
+	view := struct {
+		Report
+		Title           string
+		TopicDelta      string
+		TopicGroups     []topicGroup
+		MoreNewProblems int
+	}{
+		Report:          rep,
+		Title:           reportTitle(rep),
+		TopicDelta:      fmt.Sprintf("%.0f", reportTopicDelta),
+		TopicGroups:     []topicGroup{{"Improved", rep.Topics.Improved}, {"Regressed", rep.Topics.Regressed}},
+		MoreNewProblems: rep.NewProblems.Count - len(rep.NewProblems.Problems),
+	}
+	var buf bytes.Buffer
+	if err := reportHTML.Execute(&buf, view); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
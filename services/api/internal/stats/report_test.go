@@ -0,0 +1,132 @@
+package stats
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/mastery"
+)
+
+func TestReplayState(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 12, 0, 0, 0, time.UTC) }
+	reviews := []gradedReview{{day(1), 3}, {day(2), 4}, {day(9), 1}}
+
+	st, grades := replayState(reviews, day(5))
+	if st.Reps != 2 || st.IntervalDays != 6 || st.LastReviewAt == nil || !st.LastReviewAt.Equal(day(2)) {
+		t.Fatalf("state before the lapse = %+v", st)
+	}
+	if len(grades) != 2 || grades[0] != 4 || grades[1] != 3 {
+		t.Fatalf("grades should be newest first, got %v", grades)
+	}
+
+	st, grades = replayState(reviews, day(10))
+	if st.Reps != 0 || st.IntervalDays != 1 || *st.LastGrade != 1 || len(grades) != 3 {
+		t.Fatalf("state after the lapse = %+v, grades %v", st, grades)
+	}
+
+	st, grades = replayState(nil, day(1))
+	if st.Reps != 0 || st.LastReviewAt != nil || len(grades) != 0 {
+		t.Fatalf("no reviews should give an unreviewed state, got %+v", st)
+	}
+	if e := mastery.Problem(st, grades, day(1)); e.Score != 0 {
+		t.Fatalf("unreviewed problem should have no mastery, got %+v", e)
+	}
+}
+
+func TestReplayStateUsesLastHistoryLenReviews(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 12, 0, 0, 0, time.UTC) }
+	// An early lapse followed by HistoryLen good reviews: the lapse falls out of
+	// the window, so the replay matches one over the good reviews alone.
+	reviews := []gradedReview{{day(1), 0}}
+	for i := 0; i < mastery.HistoryLen; i++ {
+		reviews = append(reviews, gradedReview{day(2 + i), 4})
+	}
+	st, grades := replayState(reviews, day(28))
+	want, wantGrades := replayState(reviews[1:], day(28))
+	if st.Reps != want.Reps || st.Ease != want.Ease || len(grades) != mastery.HistoryLen || len(wantGrades) != mastery.HistoryLen {
+		t.Fatalf("replay = %+v %v, want %+v %v", st, grades, want, wantGrades)
+	}
+	for _, g := range grades {
+		if g != 4 {
+			t.Fatalf("lapse should be outside the window, got %v", grades)
+		}
+	}
+}
+
+func TestTopicChanges(t *testing.T) {
+	est := func(topic string, score float64) mastery.TopicEstimate {
+		return mastery.TopicEstimate{Topic: topic, Problems: 2, Estimate: mastery.Estimate{Score: score}}
+	}
+	start := []mastery.TopicEstimate{est("graphs", 40), est("dp", 60), est("arrays", 50), est("trees", 30)}
+	end := []mastery.TopicEstimate{est("graphs", 55), est("dp", 52), est("arrays", 52), est("trees", 45), est("heaps", 70)}
+
+	got := topicChanges(start, end)
+	if len(got.Improved) != 2 || got.Improved[0].Topic != "graphs" || got.Improved[1].Topic != "trees" {
+		t.Fatalf("improved = %+v", got.Improved)
+	}
+	if got.Improved[0].Delta != 15 || got.Improved[0].Start != 40 || got.Improved[0].End != 55 {
+		t.Fatalf("graphs change = %+v", got.Improved[0])
+	}
+	if len(got.Regressed) != 1 || got.Regressed[0].Topic != "dp" || got.Regressed[0].Delta != -8 {
+		t.Fatalf("regressed = %+v", got.Regressed)
+	}
+}
+
+func TestRenderReport(t *testing.T) {
+	grade := 2.5
+	stillDue, due := 2, 8
+	rep := Report{
+		Period: "week", From: "2026-03-02", To: "2026-03-08", Timezone: "America/New_York",
+		Reviews:     ReportReviews{Done: 12, ProblemsReviewed: 6, StillDue: &stillDue, Due: &due, TotalTimeSec: 4800},
+		NewProblems: ReportNewProblems{Count: 1, Problems: []ReportProblem{{Title: "A | B <script>", Difficulty: "easy"}}},
+		Topics:      ReportTopics{Improved: []TopicChange{{Topic: "graphs", Problems: 3, Start: 40, End: 55, Delta: 15}}},
+		Contests:    ReportContests{ContestStatsTotals: ContestStatsTotals{ContestsFinished: 1, ProblemsRecorded: 3, SolvedCount: 2, AvgGrade: &grade}},
+		Streak:      ReportStreak{CurrentDays: 4, LongestDays: 9, GoalDays: 5, FrozenDays: 1, Days: 7},
+		Hardest:     []HardProblem{{Title: "Hard | One", Difficulty: "hard", Failures: 2, Reviews: 3}},
+	}
+
+	md := renderReportMarkdown(rep)
+	for _, want := range []string{
+		"# Weekly progress report: 2026-03-02 to 2026-03-08",
+		"- Reviews done: 12 (1h 20m)",
+		"- Problems reviewed: 6 of 8 due (n/a)",
+		"| graphs | 3 | 40.0 | 55.0 | +15.0 |",
+		"- Average grade: 2.5",
+		"- Goal met on 5 of 7 days, 1 frozen",
+		`| Hard \| One | hard | 2 | 3 |`,
+	} {
+		if !strings.Contains(md, want) {
+			t.Fatalf("markdown missing %q:\n%s", want, md)
+		}
+	}
+	if strings.Contains(md, "Regressed") {
+		t.Fatalf("empty groups should be omitted:\n%s", md)
+	}
+
+	html, err := renderReportHTML(rep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"<h1>Weekly progress report: 2026-03-02 to 2026-03-08</h1>",
+		"A | B &lt;script&gt; (easy)",
+		"<td>graphs</td><td>3</td><td>40.0</td><td>55.0</td><td>&#43;15.0</td>",
+		"<li>Average grade: 2.5</li>",
+		"<li>Problems reviewed: 6 of 8 due (n/a)</li>",
+	} {
+		if !strings.Contains(html, want) {
+			t.Fatalf("html missing %q:\n%s", want, html)
+		}
+	}
+
+	// A past window has no due counts.
+	rep.Reviews.StillDue, rep.Reviews.Due = nil, nil
+	md = renderReportMarkdown(rep)
+	if !strings.Contains(md, "- Problems reviewed: 6\n") || strings.Contains(md, "Still due") {
+		t.Fatalf("past window should only list problems reviewed:\n%s", md)
+	}
+	if html, err = renderReportHTML(rep); err != nil || !strings.Contains(html, "<li>Problems reviewed: 6</li>") || strings.Contains(html, "Still due") {
+		t.Fatalf("past window html (%v):\n%s", err, html)
+	}
+}
@@ -0,0 +1,126 @@
+package stats
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/activity"
+)
+
+// window is an inclusive range of the user's local calendar days. From and To
+// are civil dates (UTC midnights, as activity uses); Start and End are the UTC
+// instants where From begins and the day after To begins in the user's zone,
+// so [Start, End) selects exactly the window's timestamps even when a day in it
+// is 23 or 25 hours long.
+type window struct {
+	From  time.Time
+	To    time.Time
+	Start time.Time
+	End   time.Time
+	Days  int
+}
+
+// WindowInfo is echoed in responses so clients see the window they got.
+type WindowInfo struct {
+	From       string `json:"from"`
+	To         string `json:"to"`
+	WindowDays int    `json:"window_days"`
+}
+
+func (w window) info() WindowInfo {
+	return WindowInfo{From: w.From.Format("2006-01-02"), To: w.To.Format("2006-01-02"), WindowDays: w.Days}
+}
+
+// startOfDay returns the first instant of the civil date day in loc. That is
+// local midnight, except where a DST change skips midnight (for example
+// America/Santiago or America/Havana), where it is the instant of the change.
+func startOfDay(day time.Time, loc *time.Location) time.Time {
+	y, m, d := day.Date()
+	t := time.Date(y, m, d, 0, 0, 0, 0, loc)
+	if ly, lm, ld := t.In(loc).Date(); ly == y && lm == m && ld == d {
+		return t
+	}
+	// time.Date resolved the missing midnight into the previous day; search (in
+	// whole seconds, which zone changes fall on) for the change between then and
+	// noon, which always exists.
+	lo, hi := t.Unix(), time.Date(y, m, d, 12, 0, 0, 0, loc).Unix()
+	for hi-lo > 1 {
+		mid := lo + (hi-lo)/2
+		if activity.LocalDate(time.Unix(mid, 0), loc).Equal(civilDay(day)) {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	return time.Unix(hi, 0).In(loc)
+}
+
+func newWindow(from time.Time, to time.Time, loc *time.Location) window {
+	from, to = civilDay(from), civilDay(to)
+	return window{
+		From:  from,
+		To:    to,
+		Start: startOfDay(from, loc).UTC(),
+		End:   startOfDay(to.AddDate(0, 0, 1), loc).UTC(),
+		Days:  int(to.Sub(from).Hours()/24) + 1,
+	}
+}
+
+// parseWindow reads the window from the query: from and to as YYYY-MM-DD local
+// dates, or window_days ending at to. to defaults to today and the window to
+// def days; it may not end after today or span more than max days. Errors are
+// meant for a 400 response.
+func parseWindow(q url.Values, loc *time.Location, now time.Time, def int, max int) (window, error) {
+	today := activity.LocalDate(now, loc)
+	parseDate := func(name string) (time.Time, bool, error) {
+		v := strings.TrimSpace(q.Get(name))
+		if v == "" {
+			return time.Time{}, false, nil
+		}
+		d, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("%s must be a date (YYYY-MM-DD)", name)
+		}
+		return d, true, nil
+	}
+	from, hasFrom, err := parseDate("from")
+	if err != nil {
+		return window{}, err
+	}
+	to, hasTo, err := parseDate("to")
+	if err != nil {
+		return window{}, err
+	}
+	if !hasTo {
+		to = today
+	}
+	if to.After(today) {
+		return window{}, fmt.Errorf("to cannot be after today (%s)", today.Format("2006-01-02"))
+	}
+
+	days := def
+	if v := strings.TrimSpace(q.Get("window_days")); v != "" {
+		if hasFrom {
+			return window{}, fmt.Errorf("use from or window_days, not both")
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > max {
+			return window{}, fmt.Errorf("window_days must be 1..%d", max)
+		}
+		days = n
+	}
+	if !hasFrom {
+		from = to.AddDate(0, 0, -(days - 1))
+	}
+	if from.After(to) {
+		return window{}, fmt.Errorf("from must be on or before to")
+	}
+	w := newWindow(from, to, loc)
+	if w.Days > max {
+		return window{}, fmt.Errorf("window must be at most %d days", max)
+	}
+	return w, nil
+}
@@ -0,0 +1,131 @@
+package stats
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/md-rashed-zaman/PrepTracker/services/api/internal/activity"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("time zone %s not available: %v", name, err)
+	}
+	return loc
+}
+
+func date(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+
+func TestWindowBoundsAcrossNewYorkDST(t *testing.T) {
+	ny := mustLoad(t, "America/New_York")
+
+	// Spring forward on 2026-03-08: that local day is 23 hours long.
+	w := newWindow(date(2026, 3, 8), date(2026, 3, 8), ny)
+	if want := time.Date(2026, 3, 8, 5, 0, 0, 0, time.UTC); !w.Start.Equal(want) {
+		t.Fatalf("start = %v, want %v", w.Start, want)
+	}
+	if want := time.Date(2026, 3, 9, 4, 0, 0, 0, time.UTC); !w.End.Equal(want) {
+		t.Fatalf("end = %v, want %v", w.End, want)
+	}
+	if w.Days != 1 || w.End.Sub(w.Start) != 23*time.Hour {
+		t.Fatalf("expected one 23h day, got %d days over %v", w.Days, w.End.Sub(w.Start))
+	}
+
+	// Fall back on 2026-11-01: 25 hours.
+	w = newWindow(date(2026, 10, 31), date(2026, 11, 2), ny)
+	if w.Days != 3 || w.End.Sub(w.Start) != 73*time.Hour {
+		t.Fatalf("expected 3 days over 73h, got %d days over %v", w.Days, w.End.Sub(w.Start))
+	}
+
+	// Timestamps bucket into the local day whichever offset applies.
+	cases := []struct {
+		at   time.Time
+		want string
+	}{
+		{time.Date(2026, 3, 8, 4, 59, 0, 0, time.UTC), "2026-03-07"},  // 23:59 EST
+		{time.Date(2026, 3, 8, 5, 0, 0, 0, time.UTC), "2026-03-08"},   // 00:00 EST
+		{time.Date(2026, 3, 9, 3, 59, 0, 0, time.UTC), "2026-03-08"},  // 23:59 EDT
+		{time.Date(2026, 3, 9, 4, 0, 0, 0, time.UTC), "2026-03-09"},   // 00:00 EDT
+		{time.Date(2026, 11, 1, 5, 30, 0, 0, time.UTC), "2026-11-01"}, // 01:30 EDT
+		{time.Date(2026, 11, 1, 6, 30, 0, 0, time.UTC), "2026-11-01"}, // 01:30 EST, the repeated hour
+		{time.Date(2026, 11, 2, 4, 59, 0, 0, time.UTC), "2026-11-01"}, // 23:59 EST
+		{time.Date(2026, 11, 2, 5, 0, 0, 0, time.UTC), "2026-11-02"},
+	}
+	for _, c := range cases {
+		if got := activity.LocalDate(c.at, ny).Format("2006-01-02"); got != c.want {
+			t.Fatalf("LocalDate(%v) = %s, want %s", c.at, got, c.want)
+		}
+		day, _ := time.Parse("2006-01-02", c.want)
+		w := newWindow(day, day, ny)
+		if c.at.Before(w.Start) || !c.at.Before(w.End) {
+			t.Fatalf("%v should fall in [%v, %v)", c.at, w.Start, w.End)
+		}
+	}
+}
+
+func TestStartOfDayWhenMidnightIsSkipped(t *testing.T) {
+	// These zones move clocks forward at midnight, so the day starts at 01:00.
+	cases := []struct {
+		zone string
+		day  time.Time
+		want time.Time
+	}{
+		{"America/Santiago", date(2026, 9, 6), time.Date(2026, 9, 6, 4, 0, 0, 0, time.UTC)},
+		{"America/Havana", date(2026, 3, 8), time.Date(2026, 3, 8, 5, 0, 0, 0, time.UTC)},
+		{"Asia/Beirut", date(2026, 3, 29), time.Date(2026, 3, 28, 22, 0, 0, 0, time.UTC)},
+	}
+	for _, c := range cases {
+		loc := mustLoad(t, c.zone)
+		got := startOfDay(c.day, loc)
+		if !got.Equal(c.want) {
+			t.Fatalf("%s: startOfDay(%s) = %v, want %v", c.zone, c.day.Format("2006-01-02"), got.UTC(), c.want)
+		}
+		if d := activity.LocalDate(got, loc); !d.Equal(c.day) {
+			t.Fatalf("%s: start falls on %v", c.zone, d)
+		}
+		if d := activity.LocalDate(got.Add(-time.Second), loc); !d.Equal(c.day.AddDate(0, 0, -1)) {
+			t.Fatalf("%s: the second before the start should be the previous day, got %v", c.zone, d)
+		}
+	}
+}
+
+func TestParseWindow(t *testing.T) {
+	ny := mustLoad(t, "America/New_York")
+	now := time.Date(2026, 3, 10, 3, 0, 0, 0, time.UTC) // 23:00 on 03-09 in New York
+	parse := func(raw string) (window, error) {
+		q, _ := url.ParseQuery(raw)
+		return parseWindow(q, ny, now, 7, 90)
+	}
+
+	w, err := parse("")
+	if err != nil || w.From != date(2026, 3, 3) || w.To != date(2026, 3, 9) || w.Days != 7 {
+		t.Fatalf("default window = %+v, %v", w, err)
+	}
+	w, err = parse("from=2026-03-01&to=2026-03-08")
+	if err != nil || w.Days != 8 || w.info() != (WindowInfo{From: "2026-03-01", To: "2026-03-08", WindowDays: 8}) {
+		t.Fatalf("explicit window = %+v, %v", w, err)
+	}
+	w, err = parse("window_days=2&to=2026-03-05")
+	if err != nil || w.From != date(2026, 3, 4) || w.To != date(2026, 3, 5) {
+		t.Fatalf("window_days ending at to = %+v, %v", w, err)
+	}
+
+	for _, raw := range []string{
+		"from=03/01/2026",
+		"to=2026-02-30",
+		"to=2026-03-10", // tomorrow in New York, though already today in UTC
+		"from=2026-03-09&to=2026-03-08",
+		"from=2025-01-01",
+		"window_days=0",
+		"window_days=91",
+		"window_days=abc",
+		"window_days=3&from=2026-03-01",
+	} {
+		if _, err := parse(raw); err == nil {
+			t.Fatalf("expected %q to be rejected", raw)
+		}
+	}
+}
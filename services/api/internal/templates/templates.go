@@ -42,3 +42,31 @@ func Load(key, version string) ([]Item, error) {
 	return items, nil
 }
 
+// Catalog returns the items of every embedded template, deduplicated by URL,
+// in file name then file order.
+func Catalog() ([]Item, error) {
+	entries, err := dataFS.ReadDir("data")
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	out := make([]Item, 0)
+	for _, e := range entries {
+		b, err := dataFS.ReadFile("data/" + e.Name())
+		if err != nil {
+			return nil, err
+		}
+		var items []Item
+		if err := json.Unmarshal(b, &items); err != nil {
+			return nil, err
+		}
+		for _, it := range items {
+			if seen[it.URL] {
+				continue
+			}
+			seen[it.URL] = true
+			out = append(out, it)
+		}
+	}
+	return out, nil
+}
@@ -60,10 +60,20 @@ func ResetDB(t *testing.T, pool *pgxpool.Pool) {
 	// Keep this aligned with migrations.
 	_, err := pool.Exec(ctx, `
 		TRUNCATE TABLE
+		  daily_user_activity,
+		  mock_interview_rubrics,
+		  mock_interviews,
+		  contest_rating_history,
+		  contest_ratings,
 		  calendar_ics_tokens,
+		  contest_presets,
+		  contest_participants,
+		  contest_events,
 		  contest_results,
 		  contest_items,
 		  contests,
+		  study_plan_items,
+		  study_plans,
 		  list_items,
 		  lists,
 		  review_logs,
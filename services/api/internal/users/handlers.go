@@ -22,6 +22,12 @@ type patchSettingsRequest struct {
 	MinIntervalDays *int    `json:"min_interval_days"`
 	DueHourLocal    *int    `json:"due_hour_local"`
 	DueMinuteLocal  *int    `json:"due_minute_local"`
+
+	ContestCooldownContests *int `json:"contest_cooldown_contests"`
+	ContestCooldownHours    *int `json:"contest_cooldown_hours"`
+
+	DailyGoalReviews *int `json:"daily_goal_reviews"`
+	DailyGoalMinutes *int `json:"daily_goal_minutes"`
 }
 
 func (h *Handler) PatchMeSettings(w http.ResponseWriter, r *http.Request) {
@@ -60,7 +66,33 @@ func (h *Handler) PatchMeSettings(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	settings, err := h.repo.UpdateSettings(r.Context(), userID, req.Timezone, req.MinIntervalDays, req.DueHourLocal, req.DueMinuteLocal)
+	if req.ContestCooldownContests != nil && (*req.ContestCooldownContests < 0 || *req.ContestCooldownContests > 50) {
+		httpx.WriteError(w, http.StatusBadRequest, "contest_cooldown_contests must be 0..50")
+		return
+	}
+	if req.ContestCooldownHours != nil && (*req.ContestCooldownHours < 0 || *req.ContestCooldownHours > 720) {
+		httpx.WriteError(w, http.StatusBadRequest, "contest_cooldown_hours must be 0..720")
+		return
+	}
+	if req.DailyGoalReviews != nil && (*req.DailyGoalReviews < 1 || *req.DailyGoalReviews > 500) {
+		httpx.WriteError(w, http.StatusBadRequest, "daily_goal_reviews must be 1..500")
+		return
+	}
+	if req.DailyGoalMinutes != nil && (*req.DailyGoalMinutes < 0 || *req.DailyGoalMinutes > 1440) {
+		httpx.WriteError(w, http.StatusBadRequest, "daily_goal_minutes must be 0..1440")
+		return
+	}
+
+	settings, err := h.repo.PatchSettings(r.Context(), userID, SettingsPatch{
+		Timezone:                req.Timezone,
+		MinIntervalDays:         req.MinIntervalDays,
+		DueHourLocal:            req.DueHourLocal,
+		DueMinuteLocal:          req.DueMinuteLocal,
+		ContestCooldownContests: req.ContestCooldownContests,
+		ContestCooldownHours:    req.ContestCooldownHours,
+		DailyGoalReviews:        req.DailyGoalReviews,
+		DailyGoalMinutes:        req.DailyGoalMinutes,
+	})
 	if err != nil {
 		httpx.WriteError(w, http.StatusInternalServerError, "failed to update settings")
 		return
@@ -70,5 +102,11 @@ func (h *Handler) PatchMeSettings(w http.ResponseWriter, r *http.Request) {
 		"min_interval_days": settings.MinIntervalDays,
 		"due_hour_local":    settings.DueHourLocal,
 		"due_minute_local":  settings.DueMinuteLocal,
+
+		"contest_cooldown_contests": settings.ContestCooldownContests,
+		"contest_cooldown_hours":    settings.ContestCooldownHours,
+
+		"daily_goal_reviews": settings.DailyGoalReviews,
+		"daily_goal_minutes": settings.DailyGoalMinutes,
 	})
 }
@@ -22,6 +22,14 @@ type Settings struct {
 	MinIntervalDays int
 	DueHourLocal    int
 	DueMinuteLocal  int
+	// Contest generation cooldown: problems from the last ContestCooldownContests
+	// contests or reviewed within ContestCooldownHours are penalized.
+	ContestCooldownContests int
+	ContestCooldownHours    int
+	// Daily goal: a local day counts toward the streak once it has at least
+	// DailyGoalReviews reviews and DailyGoalMinutes minutes of logged time.
+	DailyGoalReviews int
+	DailyGoalMinutes int
 }
 
 type Repository struct {
@@ -89,10 +97,14 @@ func (r *Repository) GetByEmail(ctx context.Context, email string) (User, error)
 func (r *Repository) GetSettings(ctx context.Context, userID string) (Settings, error) {
 	var s Settings
 	err := r.pool.QueryRow(ctx, `
-		SELECT user_id::text, timezone, min_interval_days, due_hour_local, due_minute_local
+		SELECT user_id::text, timezone, min_interval_days, due_hour_local, due_minute_local,
+		       contest_cooldown_contests, contest_cooldown_hours,
+		       daily_goal_reviews, daily_goal_minutes
 		FROM user_settings
 		WHERE user_id = $1
-	`, userID).Scan(&s.UserID, &s.Timezone, &s.MinIntervalDays, &s.DueHourLocal, &s.DueMinuteLocal)
+	`, userID).Scan(&s.UserID, &s.Timezone, &s.MinIntervalDays, &s.DueHourLocal, &s.DueMinuteLocal,
+		&s.ContestCooldownContests, &s.ContestCooldownHours,
+		&s.DailyGoalReviews, &s.DailyGoalMinutes)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return Settings{}, db.ErrNotFound
@@ -102,7 +114,28 @@ func (r *Repository) GetSettings(ctx context.Context, userID string) (Settings,
 	return s, nil
 }
 
+// SettingsPatch holds the optional fields of a settings update; nil leaves a field unchanged.
+type SettingsPatch struct {
+	Timezone                *string
+	MinIntervalDays         *int
+	DueHourLocal            *int
+	DueMinuteLocal          *int
+	ContestCooldownContests *int
+	ContestCooldownHours    *int
+	DailyGoalReviews        *int
+	DailyGoalMinutes        *int
+}
+
 func (r *Repository) UpdateSettings(ctx context.Context, userID string, timezone *string, minIntervalDays *int, dueHourLocal *int, dueMinuteLocal *int) (Settings, error) {
+	return r.PatchSettings(ctx, userID, SettingsPatch{
+		Timezone:        timezone,
+		MinIntervalDays: minIntervalDays,
+		DueHourLocal:    dueHourLocal,
+		DueMinuteLocal:  dueMinuteLocal,
+	})
+}
+
+func (r *Repository) PatchSettings(ctx context.Context, userID string, p SettingsPatch) (Settings, error) {
 	// Apply updates with validation in SQL layer (simple bounds).
 	_, err := r.pool.Exec(ctx, `
 		UPDATE user_settings
@@ -110,9 +143,14 @@ func (r *Repository) UpdateSettings(ctx context.Context, userID string, timezone
 		    min_interval_days = COALESCE($3, min_interval_days),
 		    due_hour_local = COALESCE($4, due_hour_local),
 		    due_minute_local = COALESCE($5, due_minute_local),
+		    contest_cooldown_contests = COALESCE($6, contest_cooldown_contests),
+		    contest_cooldown_hours = COALESCE($7, contest_cooldown_hours),
+		    daily_goal_reviews = COALESCE($8, daily_goal_reviews),
+		    daily_goal_minutes = COALESCE($9, daily_goal_minutes),
 		    updated_at = now()
 		WHERE user_id = $1
-	`, userID, timezone, minIntervalDays, dueHourLocal, dueMinuteLocal)
+	`, userID, p.Timezone, p.MinIntervalDays, p.DueHourLocal, p.DueMinuteLocal, p.ContestCooldownContests, p.ContestCooldownHours,
+		p.DailyGoalReviews, p.DailyGoalMinutes)
 	if err != nil {
 		return Settings{}, err
 	}
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS study_plans (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    list_id UUID NOT NULL REFERENCES lists(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    start_date DATE NOT NULL,
+    target_date DATE NOT NULL,
+    study_weekdays INT[] NOT NULL DEFAULT '{1,2,3,4,5}', -- 0=Sunday .. 6=Saturday
+    max_new_per_day INT NOT NULL DEFAULT 4,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_study_plans_user ON study_plans(user_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS study_plan_items (
+    plan_id UUID NOT NULL REFERENCES study_plans(id) ON DELETE CASCADE,
+    problem_id UUID NOT NULL REFERENCES problems(id) ON DELETE CASCADE,
+    order_index INT NOT NULL,
+    scheduled_date DATE, -- NULL when the problem was already reviewed before the plan was created
+    PRIMARY KEY (plan_id, problem_id)
+);
+CREATE INDEX IF NOT EXISTS idx_study_plan_items_order ON study_plan_items(plan_id, order_index);
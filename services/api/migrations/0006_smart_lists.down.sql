@@ -0,0 +1,3 @@
+DELETE FROM lists WHERE source_type = 'smart';
+ALTER TABLE lists
+    DROP COLUMN IF EXISTS filter;
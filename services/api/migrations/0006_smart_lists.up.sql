@@ -0,0 +1,4 @@
+-- Smart lists (source_type = 'smart') have no list_items; their items are the
+-- result of evaluating this filter against the owner's library on read.
+ALTER TABLE lists
+    ADD COLUMN IF NOT EXISTS filter JSONB;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_contests_open_started;
+ALTER TABLE contests
+    DROP COLUMN IF EXISTS expired_at;
@@ -0,0 +1,7 @@
+ALTER TABLE contests
+    ADD COLUMN IF NOT EXISTS expired_at TIMESTAMPTZ;
+
+-- Supports the background sweep for started-but-never-completed contests.
+CREATE INDEX IF NOT EXISTS idx_contests_open_started
+    ON contests(started_at)
+    WHERE completed_at IS NULL AND expired_at IS NULL;
@@ -0,0 +1,3 @@
+ALTER TABLE contest_results
+    DROP COLUMN IF EXISTS time_computed,
+    DROP COLUMN IF EXISTS is_late;
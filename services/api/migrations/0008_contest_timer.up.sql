@@ -0,0 +1,3 @@
+ALTER TABLE contest_results
+    ADD COLUMN IF NOT EXISTS is_late BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS time_computed BOOLEAN NOT NULL DEFAULT false;
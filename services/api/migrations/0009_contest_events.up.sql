@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS contest_events (
+    id BIGSERIAL PRIMARY KEY,
+    contest_id UUID NOT NULL REFERENCES contests(id) ON DELETE CASCADE,
+    problem_id UUID NOT NULL REFERENCES problems(id) ON DELETE CASCADE,
+    type TEXT NOT NULL CHECK (type IN ('opened', 'wrong_answer', 'hint', 'solved', 'gave_up')),
+    occurred_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    offset_sec INT NOT NULL,
+    note TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_contest_events_contest ON contest_events(contest_id, occurred_at, id);
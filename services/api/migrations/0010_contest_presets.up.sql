@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS contest_presets (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    strategy TEXT NOT NULL DEFAULT 'balanced',
+    duration_minutes INT NOT NULL,
+    easy_count INT NOT NULL DEFAULT 0,
+    medium_count INT NOT NULL DEFAULT 0,
+    hard_count INT NOT NULL DEFAULT 0,
+    easy_minutes INT NOT NULL DEFAULT 0,
+    medium_minutes INT NOT NULL DEFAULT 0,
+    hard_minutes INT NOT NULL DEFAULT 0,
+    include_topics TEXT[] NOT NULL DEFAULT '{}',
+    exclude_topics TEXT[] NOT NULL DEFAULT '{}',
+    platforms TEXT[] NOT NULL DEFAULT '{}',
+    never_attempted_only BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (user_id, name)
+);
@@ -0,0 +1,5 @@
+ALTER TABLE contest_items
+    DROP COLUMN IF EXISTS score_breakdown;
+
+ALTER TABLE contests
+    DROP COLUMN IF EXISTS seed;
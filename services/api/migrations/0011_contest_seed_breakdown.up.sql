@@ -0,0 +1,5 @@
+ALTER TABLE contests
+    ADD COLUMN IF NOT EXISTS seed BIGINT;
+
+ALTER TABLE contest_items
+    ADD COLUMN IF NOT EXISTS score_breakdown JSONB;
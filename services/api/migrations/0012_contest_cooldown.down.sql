@@ -0,0 +1,3 @@
+ALTER TABLE user_settings
+    DROP COLUMN IF EXISTS contest_cooldown_hours,
+    DROP COLUMN IF EXISTS contest_cooldown_contests;
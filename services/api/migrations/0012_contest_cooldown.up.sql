@@ -0,0 +1,4 @@
+-- Generation penalizes problems from the last N contests or reviewed within the last H hours.
+ALTER TABLE user_settings
+    ADD COLUMN IF NOT EXISTS contest_cooldown_contests INT NOT NULL DEFAULT 3,
+    ADD COLUMN IF NOT EXISTS contest_cooldown_hours INT NOT NULL DEFAULT 12;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS contest_rating_history;
+DROP TABLE IF EXISTS contest_ratings;
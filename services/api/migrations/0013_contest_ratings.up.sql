@@ -0,0 +1,23 @@
+-- Elo-style contest ratings, updated when a contest completes. scope is 'overall',
+-- 'difficulty:<easy|medium|hard>' or 'topic:<name>'.
+CREATE TABLE IF NOT EXISTS contest_ratings (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    scope TEXT NOT NULL,
+    rating DOUBLE PRECISION NOT NULL,
+    contests INT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, scope)
+);
+
+CREATE TABLE IF NOT EXISTS contest_rating_history (
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    contest_id UUID NOT NULL REFERENCES contests(id) ON DELETE CASCADE,
+    scope TEXT NOT NULL,
+    rating_before DOUBLE PRECISION NOT NULL,
+    rating_after DOUBLE PRECISION NOT NULL,
+    recorded_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (contest_id, scope)
+);
+
+CREATE INDEX IF NOT EXISTS idx_contest_rating_history_user ON contest_rating_history(user_id, scope, recorded_at);
@@ -0,0 +1,16 @@
+ALTER TABLE contest_rating_history DROP CONSTRAINT IF EXISTS contest_rating_history_contest_user_scope_key;
+DELETE FROM contest_rating_history h USING contests c WHERE c.id = h.contest_id AND h.user_id <> c.user_id;
+ALTER TABLE contest_rating_history ADD CONSTRAINT contest_rating_history_contest_id_scope_key UNIQUE (contest_id, scope);
+
+DROP INDEX IF EXISTS idx_contest_events_contest_user;
+DELETE FROM contest_events e USING contests c WHERE c.id = e.contest_id AND e.user_id <> c.user_id;
+ALTER TABLE contest_events DROP COLUMN IF EXISTS user_id;
+CREATE INDEX IF NOT EXISTS idx_contest_events_contest ON contest_events(contest_id, occurred_at, id);
+
+ALTER TABLE contest_results DROP CONSTRAINT IF EXISTS contest_results_pkey;
+DELETE FROM contest_results r USING contests c WHERE c.id = r.contest_id AND r.user_id <> c.user_id;
+ALTER TABLE contest_results DROP COLUMN IF EXISTS user_id;
+ALTER TABLE contest_results ADD PRIMARY KEY (contest_id, problem_id);
+
+DROP TABLE IF EXISTS contest_participants;
+ALTER TABLE contests DROP COLUMN IF EXISTS invite_code;
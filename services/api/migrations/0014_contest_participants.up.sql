@@ -0,0 +1,30 @@
+-- Shared contests: the creator (contests.user_id) hosts and controls the lifecycle;
+-- other users join with the invite code. Results and events become per participant.
+ALTER TABLE contests ADD COLUMN IF NOT EXISTS invite_code TEXT UNIQUE;
+
+CREATE TABLE IF NOT EXISTS contest_participants (
+    contest_id UUID NOT NULL REFERENCES contests(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    joined_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (contest_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS idx_contest_participants_user ON contest_participants(user_id, contest_id);
+
+INSERT INTO contest_participants (contest_id, user_id, joined_at)
+SELECT id, user_id, created_at FROM contests
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE contest_results ADD COLUMN IF NOT EXISTS user_id UUID REFERENCES users(id) ON DELETE CASCADE;
+UPDATE contest_results cr SET user_id = c.user_id FROM contests c WHERE c.id = cr.contest_id AND cr.user_id IS NULL;
+ALTER TABLE contest_results ALTER COLUMN user_id SET NOT NULL;
+ALTER TABLE contest_results DROP CONSTRAINT IF EXISTS contest_results_pkey;
+ALTER TABLE contest_results ADD PRIMARY KEY (contest_id, user_id, problem_id);
+
+ALTER TABLE contest_events ADD COLUMN IF NOT EXISTS user_id UUID REFERENCES users(id) ON DELETE CASCADE;
+UPDATE contest_events ce SET user_id = c.user_id FROM contests c WHERE c.id = ce.contest_id AND ce.user_id IS NULL;
+ALTER TABLE contest_events ALTER COLUMN user_id SET NOT NULL;
+DROP INDEX IF EXISTS idx_contest_events_contest;
+CREATE INDEX IF NOT EXISTS idx_contest_events_contest_user ON contest_events(contest_id, user_id, occurred_at, id);
+
+ALTER TABLE contest_rating_history DROP CONSTRAINT IF EXISTS contest_rating_history_contest_id_scope_key;
+ALTER TABLE contest_rating_history ADD CONSTRAINT contest_rating_history_contest_user_scope_key UNIQUE (contest_id, user_id, scope);
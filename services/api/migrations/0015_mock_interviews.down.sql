@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS mock_interview_rubrics;
+DROP TABLE IF EXISTS mock_interviews;
@@ -0,0 +1,30 @@
+-- Pair practice: an interviewer runs one problem from the candidate's library and
+-- scores it with a rubric. The rubric feeds the candidate's review_logs (source
+-- 'mock_interview') and schedule.
+CREATE TABLE IF NOT EXISTS mock_interviews (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    interviewer_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    candidate_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    problem_id UUID NOT NULL REFERENCES problems(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    started_at TIMESTAMPTZ,
+    completed_at TIMESTAMPTZ,
+    canceled_at TIMESTAMPTZ,
+    CHECK (interviewer_id <> candidate_id)
+);
+CREATE INDEX IF NOT EXISTS idx_mock_interviews_interviewer ON mock_interviews(interviewer_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_mock_interviews_candidate ON mock_interviews(candidate_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS mock_interview_rubrics (
+    interview_id UUID PRIMARY KEY REFERENCES mock_interviews(id) ON DELETE CASCADE,
+    candidate_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    communication INT NOT NULL CHECK (communication BETWEEN 1 AND 4),
+    problem_solving INT NOT NULL CHECK (problem_solving BETWEEN 1 AND 4),
+    code_quality INT NOT NULL CHECK (code_quality BETWEEN 1 AND 4),
+    testing INT NOT NULL CHECK (testing BETWEEN 1 AND 4),
+    grade INT NOT NULL CHECK (grade BETWEEN 0 AND 4),
+    time_spent_sec INT,
+    notes TEXT NOT NULL DEFAULT '',
+    submitted_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_mock_interview_rubrics_candidate ON mock_interview_rubrics(candidate_id, submitted_at DESC);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS daily_user_activity;
+ALTER TABLE user_settings
+    DROP COLUMN IF EXISTS daily_goal_minutes,
+    DROP COLUMN IF EXISTS daily_goal_reviews;
@@ -0,0 +1,40 @@
+-- Daily goals: a local day meets the goal when it has at least daily_goal_reviews
+-- reviews and daily_goal_minutes minutes of logged time.
+ALTER TABLE user_settings
+    ADD COLUMN IF NOT EXISTS daily_goal_reviews INT NOT NULL DEFAULT 1,
+    ADD COLUMN IF NOT EXISTS daily_goal_minutes INT NOT NULL DEFAULT 0;
+
+-- Materialized per-day activity keyed by the user's local date at write time.
+-- Review writers update it in the same transaction as review_logs; streaks and
+-- goals read it instead of scanning logs. goal_met_at is stamped the first time a
+-- day reaches the goal in effect then, so later goal changes do not rewrite
+-- history. frozen_at marks a missed day covered by a streak freeze.
+CREATE TABLE IF NOT EXISTS daily_user_activity (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    local_date DATE NOT NULL,
+    reviews INT NOT NULL DEFAULT 0,
+    time_spent_sec INT NOT NULL DEFAULT 0,
+    goal_met_at TIMESTAMPTZ,
+    frozen_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, local_date)
+);
+
+-- Backfill from existing logs with the default goal (one review). Unknown time
+-- zones fall back to UTC, matching the API.
+INSERT INTO daily_user_activity (user_id, local_date, reviews, time_spent_sec, goal_met_at)
+SELECT rl.user_id,
+       (rl.reviewed_at AT TIME ZONE tz.name)::date AS local_date,
+       COUNT(*),
+       COALESCE(SUM(rl.time_spent_sec), 0),
+       MAX(rl.reviewed_at)
+FROM review_logs rl
+JOIN LATERAL (
+    SELECT COALESCE(
+        (SELECT us.timezone FROM user_settings us
+         WHERE us.user_id = rl.user_id
+           AND us.timezone IN (SELECT name FROM pg_timezone_names)),
+        'UTC') AS name
+) tz ON true
+GROUP BY rl.user_id, local_date
+ON CONFLICT (user_id, local_date) DO NOTHING;
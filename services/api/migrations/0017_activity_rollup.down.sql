@@ -0,0 +1,9 @@
+ALTER TABLE user_problem_state
+    DROP COLUMN IF EXISTS recent_grades;
+ALTER TABLE daily_user_activity
+    DROP COLUMN IF EXISTS contest_time_sec,
+    DROP COLUMN IF EXISTS contest_grade_sum,
+    DROP COLUMN IF EXISTS contest_graded,
+    DROP COLUMN IF EXISTS contest_solved,
+    DROP COLUMN IF EXISTS contest_problems,
+    DROP COLUMN IF EXISTS contests_finished;
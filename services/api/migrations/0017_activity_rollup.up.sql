@@ -0,0 +1,69 @@
+-- Contest activity joins the daily rollup so contest stats read one row per day
+-- instead of aggregating contest_results and contests on every request.
+ALTER TABLE daily_user_activity
+    ADD COLUMN IF NOT EXISTS contests_finished INT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS contest_problems INT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS contest_solved INT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS contest_graded INT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS contest_grade_sum INT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS contest_time_sec INT NOT NULL DEFAULT 0;
+
+-- The last few grades per problem, newest first, so mastery does not scan
+-- review_logs. Review writers prepend to it in the same transaction.
+ALTER TABLE user_problem_state
+    ADD COLUMN IF NOT EXISTS recent_grades INT[] NOT NULL DEFAULT '{}';
+
+UPDATE user_problem_state s
+SET recent_grades = g.grades
+FROM (
+    SELECT user_id, problem_id, array_agg(grade ORDER BY rn) AS grades
+    FROM (
+        SELECT user_id, problem_id, grade,
+               row_number() OVER (PARTITION BY user_id, problem_id ORDER BY reviewed_at DESC, created_at DESC) AS rn
+        FROM review_logs
+    ) r
+    WHERE rn <= 10
+    GROUP BY user_id, problem_id
+) g
+WHERE s.user_id = g.user_id AND s.problem_id = g.problem_id;
+
+-- Backfill contest columns with the same time zone rule as 0016.
+CREATE TEMPORARY TABLE activity_tz AS
+SELECT u.id AS user_id,
+       COALESCE(
+           (SELECT us.timezone FROM user_settings us
+            WHERE us.user_id = u.id AND us.timezone IN (SELECT name FROM pg_timezone_names)),
+           'UTC') AS tz
+FROM users u;
+
+INSERT INTO daily_user_activity (user_id, local_date, contest_problems, contest_solved, contest_graded, contest_grade_sum, contest_time_sec)
+SELECT cr.user_id,
+       (cr.recorded_at AT TIME ZONE t.tz)::date AS local_date,
+       COUNT(*),
+       COUNT(*) FILTER (WHERE cr.solved_flag IS TRUE),
+       COUNT(cr.grade),
+       COALESCE(SUM(cr.grade), 0),
+       COALESCE(SUM(cr.time_spent_sec), 0)
+FROM contest_results cr
+JOIN activity_tz t ON t.user_id = cr.user_id
+GROUP BY cr.user_id, local_date
+ON CONFLICT (user_id, local_date) DO UPDATE
+SET contest_problems = EXCLUDED.contest_problems,
+    contest_solved = EXCLUDED.contest_solved,
+    contest_graded = EXCLUDED.contest_graded,
+    contest_grade_sum = EXCLUDED.contest_grade_sum,
+    contest_time_sec = EXCLUDED.contest_time_sec;
+
+INSERT INTO daily_user_activity (user_id, local_date, contests_finished)
+SELECT cp.user_id,
+       (c.completed_at AT TIME ZONE t.tz)::date AS local_date,
+       COUNT(*)
+FROM contests c
+JOIN contest_participants cp ON cp.contest_id = c.id
+JOIN activity_tz t ON t.user_id = cp.user_id
+WHERE c.completed_at IS NOT NULL
+GROUP BY cp.user_id, local_date
+ON CONFLICT (user_id, local_date) DO UPDATE
+SET contests_finished = EXCLUDED.contests_finished;
+
+DROP TABLE activity_tz;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_user_problem_state_added;
+ALTER TABLE user_problem_state
+    DROP COLUMN IF EXISTS added_at;
@@ -0,0 +1,19 @@
+-- When the problem entered the user's library, so reports can count new
+-- problems per user (problems.created_at is shared across users).
+ALTER TABLE user_problem_state
+    ADD COLUMN IF NOT EXISTS added_at TIMESTAMPTZ;
+
+-- Best effort for existing rows: the first review, else when the problem was created.
+UPDATE user_problem_state s
+SET added_at = COALESCE(
+    (SELECT MIN(rl.reviewed_at) FROM review_logs rl WHERE rl.user_id = s.user_id AND rl.problem_id = s.problem_id),
+    (SELECT p.created_at FROM problems p WHERE p.id = s.problem_id)
+)
+WHERE s.added_at IS NULL;
+
+ALTER TABLE user_problem_state
+    ALTER COLUMN added_at SET DEFAULT now(),
+    ALTER COLUMN added_at SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_user_problem_state_added
+    ON user_problem_state(user_id, added_at);
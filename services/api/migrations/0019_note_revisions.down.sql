@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS problem_note_revisions;
+ALTER TABLE problem_notes
+    DROP COLUMN IF EXISTS rev;
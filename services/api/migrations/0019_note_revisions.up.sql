@@ -0,0 +1,23 @@
+-- Every save of a note appends a revision; problem_notes keeps the current
+-- content and rev, the number of its latest revision. Revision numbers only
+-- grow, so pruning old revisions never reuses one.
+ALTER TABLE problem_notes
+    ADD COLUMN IF NOT EXISTS rev INT NOT NULL DEFAULT 1;
+
+CREATE TABLE IF NOT EXISTS problem_note_revisions (
+    user_id UUID NOT NULL,
+    problem_id UUID NOT NULL,
+    rev INT NOT NULL,
+    content_md TEXT NOT NULL,
+    content_json JSONB NOT NULL,
+    restored_from INT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, problem_id, rev),
+    FOREIGN KEY (user_id, problem_id) REFERENCES problem_notes(user_id, problem_id) ON DELETE CASCADE
+);
+
+-- Existing notes start at revision 1 with their current content.
+INSERT INTO problem_note_revisions (user_id, problem_id, rev, content_md, content_json, created_at)
+SELECT user_id, problem_id, rev, content_md, content_json, updated_at
+FROM problem_notes
+ON CONFLICT DO NOTHING;
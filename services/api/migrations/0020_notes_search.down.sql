@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_problem_notes_search;
+ALTER TABLE problem_notes
+    DROP COLUMN IF EXISTS search_tsv;
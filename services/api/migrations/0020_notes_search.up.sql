@@ -0,0 +1,8 @@
+-- Full-text search over note markdown. The English configuration stems words
+-- ("sorting" finds "sorted"); the column is maintained by Postgres.
+ALTER TABLE problem_notes
+    ADD COLUMN IF NOT EXISTS search_tsv TSVECTOR
+    GENERATED ALWAYS AS (to_tsvector('english', content_md)) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_problem_notes_search
+    ON problem_notes USING GIN (search_tsv);
@@ -0,0 +1,20 @@
+-- Solutions a user wrote for a problem, kept apart from problem_notes so
+-- attempts can be listed and compared over time. written_on is the user's
+-- local date; complexities and approach are free text ("O(n log n)", "two
+-- pointers").
+CREATE TABLE IF NOT EXISTS problem_solutions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    problem_id UUID NOT NULL REFERENCES problems(id) ON DELETE CASCADE,
+    language TEXT NOT NULL,
+    code TEXT NOT NULL,
+    time_complexity TEXT NOT NULL DEFAULT '',
+    space_complexity TEXT NOT NULL DEFAULT '',
+    approach TEXT NOT NULL DEFAULT '',
+    written_on DATE NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_problem_solutions_user_problem
+    ON problem_solutions(user_id, problem_id, written_on);